@@ -1,10 +1,13 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"luma-ai-backend/models"
 	"luma-ai-backend/services"
+	"luma-ai-backend/storage"
 	"luma-ai-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -79,6 +82,10 @@ func UpdateBucket(c *gin.Context) {
 	}
 
 	response, err := bucketService.UpdateBucket(id, &req)
+	if errors.Is(err, services.ErrBucketSyncRunning) {
+		utils.ResponseErr(c, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
 		return
@@ -112,7 +119,59 @@ func ListObjects(c *gin.Context) {
 		return
 	}
 
-	response, err := bucketService.ListObjects(req.BucketID, req.Prefix, req.Page, req.PageSize)
+	response, err := bucketService.ListObjects(req)
+	if errors.Is(err, services.ErrBucketNotIndexed) {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// ResyncBucket 立即在后台同步存储桶的对象索引
+func ResyncBucket(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以同步存储桶", http.StatusForbidden)
+		return
+	}
+
+	id, err := utils.ParseInt64(c.Param("id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的存储桶ID", http.StatusBadRequest)
+		return
+	}
+
+	err = bucketService.ResyncBucket(id)
+	if errors.Is(err, services.ErrBucketSyncRunning) {
+		utils.ResponseErr(c, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseSuccess(c)
+}
+
+// GetBucketSync 获取存储桶的同步状态
+func GetBucketSync(c *gin.Context) {
+	id, err := utils.ParseInt64(c.Param("id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的存储桶ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := bucketService.GetBucketSync(id)
 	if err != nil {
 		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
 		return
@@ -120,3 +179,67 @@ func ListObjects(c *gin.Context) {
 
 	utils.ResponseOk(c, response)
 }
+
+// ListMissingObjects 列出已从存储中删除但仍被包引用的对象
+func ListMissingObjects(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以查看已删除的对象", http.StatusForbidden)
+		return
+	}
+
+	id, err := utils.ParseInt64(c.Param("id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的存储桶ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := bucketService.MissingObjects(id)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// GetLocalObject 返回本地存储中的文件，地址由 PresignGet 生成，校验签名后无需登录
+func GetLocalObject(c *gin.Context) {
+	bucketID, err := utils.ParseInt64(c.Param("bucket_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的存储桶ID", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := bucketService.Provider(bucketID)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusNotFound)
+		return
+	}
+	local, ok := provider.(*storage.Local)
+	if !ok {
+		utils.ResponseErr(c, "存储桶不是本地存储", http.StatusBadRequest)
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := local.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	file, err := local.Path(key)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := local.Head(c.Request.Context(), key); err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusNotFound)
+		return
+	}
+	c.File(file)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"luma-ai-backend/models"
+	"luma-ai-backend/services"
+	"luma-ai-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 声明全局服务常量
+var consensusService = services.NewConsensusService()
+
+// ComputeConsensus 管理员重新计算包的重叠标注合并结果
+func ComputeConsensus(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以计算合并结果", http.StatusForbidden)
+		return
+	}
+
+	packageID, err := utils.ParseInt64(c.Param("package_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的包ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := consensusService.ComputePackage(packageID)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// GetConsensusList 获取包的合并结果，flagged=true 时只返回需要裁决的条目
+func GetConsensusList(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin && userRole != models.RoleReviewer {
+		utils.ResponseErr(c, "只有管理员和审核员可以查看合并结果", http.StatusForbidden)
+		return
+	}
+
+	packageID, err := utils.ParseInt64(c.Param("package_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的包ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ConsensusListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := consensusService.ListConsensus(packageID, req)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// AdjudicateConsensus 审核员裁决一致性不足的条目
+func AdjudicateConsensus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleReviewer && userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有审核员可以裁决", http.StatusForbidden)
+		return
+	}
+
+	id, err := utils.ParseInt64(c.Param("id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AdjudicateConsensusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := consensusService.Adjudicate(id, req, userID.(int64))
+	if err != nil {
+		var validationErr *services.AnnotationValidationError
+		if errors.As(err, &validationErr) {
+			utils.ResponseErrWithData(c, err.Error(), http.StatusBadRequest, validationErr.Errors)
+			return
+		}
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"luma-ai-backend/models"
+	"luma-ai-backend/services"
+	"luma-ai-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 声明全局服务常量
+var exportService = services.NewExportService()
+
+// ExportTask 导出任务标注
+func ExportTask(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以导出标注", http.StatusForbidden)
+		return
+	}
+
+	taskID, err := utils.ParseInt64(c.Param("task_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的任务ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Archive != "" && req.Archive != models.ArchiveFormatZip && req.Archive != models.ArchiveFormatTar {
+		utils.ResponseErr(c, "不支持的归档格式", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Format {
+	case "", models.ExportFormatCOCO:
+		dataset, err := exportService.ExportTaskCOCO(taskID)
+		if err != nil {
+			utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAttachmentJSON(c, fmt.Sprintf("task_%d_coco.json", taskID), dataset)
+	case models.ExportFormatYOLO:
+		writeAttachment(c, fmt.Sprintf("task_%d_yolo.zip", taskID), "application/zip", func(w io.Writer) error {
+			return exportService.ExportTaskYOLO(taskID, w)
+		})
+	case models.ExportFormatVOC:
+		writeAttachment(c, archiveFilename(fmt.Sprintf("task_%d_voc", taskID), req.Archive), archiveContentType(req.Archive), func(w io.Writer) error {
+			return exportService.ExportTaskVOC(taskID, req.Archive, w)
+		})
+	case models.ExportFormatVTT, models.ExportFormatSRT:
+		if req.Key != "" {
+			filename, data, err := exportService.ExportTaskSubtitle(taskID, req.Format, req.Key)
+			if err != nil {
+				utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeSubtitleFile(c, filename, req.Format, data)
+			return
+		}
+		writeAttachment(c, archiveFilename(fmt.Sprintf("task_%d_%s", taskID, req.Format), req.Archive), archiveContentType(req.Archive), func(w io.Writer) error {
+			return exportService.ExportTaskSubtitles(taskID, req.Format, req.Archive, w)
+		})
+	default:
+		utils.ResponseErr(c, "不支持的导出格式", http.StatusBadRequest)
+	}
+}
+
+// ExportPackage 导出包标注
+func ExportPackage(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以导出标注", http.StatusForbidden)
+		return
+	}
+
+	packageID, err := utils.ParseInt64(c.Param("package_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的包ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Archive != "" && req.Archive != models.ArchiveFormatZip && req.Archive != models.ArchiveFormatTar {
+		utils.ResponseErr(c, "不支持的归档格式", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Format {
+	case "", models.ExportFormatCOCO:
+		dataset, err := exportService.ExportPackageCOCO(packageID)
+		if err != nil {
+			utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAttachmentJSON(c, fmt.Sprintf("package_%d_coco.json", packageID), dataset)
+	case models.ExportFormatYOLO:
+		writeAttachment(c, fmt.Sprintf("package_%d_yolo.zip", packageID), "application/zip", func(w io.Writer) error {
+			return exportService.ExportPackageYOLO(packageID, w)
+		})
+	case models.ExportFormatVOC:
+		writeAttachment(c, archiveFilename(fmt.Sprintf("package_%d_voc", packageID), req.Archive), archiveContentType(req.Archive), func(w io.Writer) error {
+			return exportService.ExportPackageVOC(packageID, req.Archive, w)
+		})
+	case models.ExportFormatVTT, models.ExportFormatSRT:
+		if req.Key != "" {
+			filename, data, err := exportService.ExportPackageSubtitle(packageID, req.Format, req.Key)
+			if err != nil {
+				utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeSubtitleFile(c, filename, req.Format, data)
+			return
+		}
+		writeAttachment(c, archiveFilename(fmt.Sprintf("package_%d_%s", packageID, req.Format), req.Archive), archiveContentType(req.Archive), func(w io.Writer) error {
+			return exportService.ExportPackageSubtitles(packageID, req.Format, req.Archive, w)
+		})
+	default:
+		utils.ResponseErr(c, "不支持的导出格式", http.StatusBadRequest)
+	}
+}
+
+// writeAttachmentJSON 以附件形式返回 JSON 文件
+func writeAttachmentJSON(c *gin.Context, filename string, data interface{}) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.JSON(http.StatusOK, data)
+}
+
+// writeAttachment 以附件形式流式返回文件
+// 写出内容之前发生的错误按普通错误响应返回，写出过程中的错误只能记录日志并中断
+func writeAttachment(c *gin.Context, filename, contentType string, write func(w io.Writer) error) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Content-Type", contentType)
+
+	if err := write(c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("failed to write export %s: %v", filename, err)
+		c.Abort()
+	}
+}
+
+// archiveFilename 归档文件名
+func archiveFilename(name string, archive models.ArchiveFormat) string {
+	if archive == models.ArchiveFormatTar {
+		return name + ".tar"
+	}
+	return name + ".zip"
+}
+
+// archiveContentType 归档文件的 Content-Type
+func archiveContentType(archive models.ArchiveFormat) string {
+	if archive == models.ArchiveFormatTar {
+		return "application/x-tar"
+	}
+	return "application/zip"
+}
+
+// writeSubtitleFile 以附件形式返回单个字幕文件
+func writeSubtitleFile(c *gin.Context, filename string, format models.ExportFormat, data []byte) {
+	contentType := "application/x-subrip; charset=utf-8"
+	if format == models.ExportFormatVTT {
+		contentType = "text/vtt; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, data)
+}
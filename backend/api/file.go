@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -15,7 +16,7 @@ import (
 	"github.com/google/uuid"
 )
 
-// UploadFile 上传文件到阿里云OSS
+// UploadFile 上传文件
 func UploadFile(c *gin.Context) {
 	// 从表单中获取文件
 	file, err := c.FormFile("file")
@@ -45,11 +46,11 @@ func UploadFile(c *gin.Context) {
 		return
 	}
 
-	// 上传到阿里云OSS
-	err = uploadToAliyunOSS(c, bytes.NewReader(fileBytes), filename)
+	// 上传到存储
+	err = uploadToStorage(c, bytes.NewReader(fileBytes), filename, file.Header.Get("Content-Type"))
 	if err != nil {
-		log.Printf("failed to upload file to aliyun oss: %v", err)
-		utils.ResponseErr(c, "faild to upload file to aliyun oss", 500)
+		log.Printf("failed to upload file: %v", err)
+		utils.ResponseErr(c, "faild to upload file", 500)
 		return
 	}
 }
@@ -61,54 +62,50 @@ func DeleteFile(c *gin.Context) {
 		return
 	}
 
-	// 删除阿里云OSS文件
-	err := deleteFromAliyunOSS(key)
+	// 删除存储中的文件
+	err := deleteFromStorage(key)
 	if err != nil {
-		log.Printf("failed to delete file from aliyun oss: %v", err)
-		utils.ResponseErr(c, "failed to delete file from aliyun oss", 500)
+		log.Printf("failed to delete file: %v", err)
+		utils.ResponseErr(c, "failed to delete file", 500)
 		return
 	}
 
 	utils.ResponseSuccess(c)
 }
 
-// uploadToAliyunOSS 上传到阿里云OSS
-func uploadToAliyunOSS(c *gin.Context, src io.Reader, filename string) error {
-	// 获取存储空间
-	bucket, err := config.GetOSSBucket()
+// uploadToStorage 上传到存储，配置了阿里云OSS时上传到OSS，否则保存到本地目录
+func uploadToStorage(c *gin.Context, src io.Reader, filename, contentType string) error {
+	// 获取存储
+	provider, domain, err := config.GetUploadStorage()
 	if err != nil {
 		return err
 	}
 
 	// 上传文件
-	err = bucket.PutObject(filename, src)
+	err = provider.Put(c.Request.Context(), filename, src, contentType)
 	if err != nil {
 		return err
 	}
 
 	// 构建完整的文件URL
-	fileURL := fmt.Sprintf("%s/%s", config.Aliyun.Domain, filename)
-	if !strings.Contains(fileURL, "http") {
-		fileURL = "http://" + fileURL
-	}
+	fileURL := fmt.Sprintf("%s/%s", domain, filename)
 
 	response := map[string]interface{}{
 		"url":  fileURL,
 		"key":  filename,
-		"hash": "", // 阿里云OSS没有直接返回hash值
+		"hash": "", // 存储没有直接返回hash值
 	}
 	utils.ResponseOk(c, response)
 	return nil
 }
 
-// deleteFromAliyunOSS 从阿里云OSS删除文件
-func deleteFromAliyunOSS(key string) error {
-	bucket, err := config.GetOSSBucket()
+// deleteFromStorage 从存储删除文件
+func deleteFromStorage(key string) error {
+	provider, domain, err := config.GetUploadStorage()
 	if err != nil {
 		return err
 	}
 
-	key = strings.TrimPrefix(key, config.Aliyun.Domain+"/")
-	err = bucket.DeleteObject(key)
-	return err
+	key = strings.TrimPrefix(key, domain+"/")
+	return provider.Delete(context.Background(), key)
 }
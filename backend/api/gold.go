@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"luma-ai-backend/models"
+	"luma-ai-backend/services"
+	"luma-ai-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 声明全局服务常量
+var goldService = services.NewGoldService()
+
+// SetGoldItems 管理员设置包的金标准条目和准确率阈值
+func SetGoldItems(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以设置金标准条目", http.StatusForbidden)
+		return
+	}
+
+	packageID, err := utils.ParseInt64(c.Param("package_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的包ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SetGoldItemsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := goldService.SetGoldItems(packageID, req, userID.(int64))
+	if err != nil {
+		var validationErr *services.AnnotationValidationError
+		if errors.As(err, &validationErr) {
+			utils.ResponseErrWithData(c, err.Error(), http.StatusBadRequest, validationErr.Errors)
+			return
+		}
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// GetGoldItems 管理员查看包的金标准设置，金标准条目不对标注员和审核员公开
+func GetGoldItems(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以查看金标准条目", http.StatusForbidden)
+		return
+	}
+
+	packageID, err := utils.ParseInt64(c.Param("package_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的包ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := goldService.GetGoldItems(packageID)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// GetGoldScores 获取标注员的金标准准确率历史，标注员只能查看自己的
+func GetGoldScores(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	annotatorID, err := utils.ParseInt64(c.Param("user_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的用户ID", http.StatusBadRequest)
+		return
+	}
+	if userRole != models.RoleAdmin && userRole != models.RoleReviewer && annotatorID != userID.(int64) {
+		utils.ResponseErr(c, "没有权限查看该用户的准确率", http.StatusForbidden)
+		return
+	}
+
+	var req models.GoldScoreListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := goldService.ListGoldScores(annotatorID, req)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
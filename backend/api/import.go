@@ -0,0 +1,66 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"luma-ai-backend/models"
+	"luma-ai-backend/services"
+	"luma-ai-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 声明全局服务常量
+var importService = services.NewImportService()
+
+// ImportAnnotations 导入预标注（COCO JSON 或 YOLO zip）
+func ImportAnnotations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以导入预标注", http.StatusForbidden)
+		return
+	}
+
+	taskID, err := utils.ParseInt64(c.Param("task_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的任务ID", http.StatusBadRequest)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		utils.ResponseErr(c, "failed to open file", http.StatusInternalServerError)
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		utils.ResponseErr(c, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	// format 可选，未指定时按文件扩展名判断
+	response, err := importService.ImportAnnotations(taskID, c.PostForm("format"), file.Filename, data, userID.(int64), userRole.(string))
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
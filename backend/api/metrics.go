@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+
+	"luma-ai-backend/models"
+	"luma-ai-backend/services"
+	"luma-ai-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 声明全局服务常量
+var metricsService = services.NewMetricsService()
+
+// GetTaskMetrics 获取任务标注与对照任务或合并结果比较的质量指标
+func GetTaskMetrics(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin && userRole != models.RoleReviewer {
+		utils.ResponseErr(c, "只有管理员和审核员可以查看质量指标", http.StatusForbidden)
+		return
+	}
+
+	taskID, err := utils.ParseInt64(c.Param("task_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的任务ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.TaskMetricsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := metricsService.TaskMetrics(taskID, req)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// GetAnnotatorMetrics 获取标注员的质量指标，标注员只能查看自己的
+func GetAnnotatorMetrics(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	annotatorID, err := utils.ParseInt64(c.Param("user_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的用户ID", http.StatusBadRequest)
+		return
+	}
+	if userRole != models.RoleAdmin && userRole != models.RoleReviewer && annotatorID != userID.(int64) {
+		utils.ResponseErr(c, "没有权限查看该用户的质量指标", http.StatusForbidden)
+		return
+	}
+
+	var req models.AnnotatorMetricsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := metricsService.AnnotatorMetrics(annotatorID, req)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// GetPackageMetrics 获取包内重叠标注副本之间的一致性指标
+func GetPackageMetrics(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin && userRole != models.RoleReviewer {
+		utils.ResponseErr(c, "只有管理员和审核员可以查看质量指标", http.StatusForbidden)
+		return
+	}
+
+	packageID, err := utils.ParseInt64(c.Param("package_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的包ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := metricsService.PackageMetrics(packageID)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
@@ -38,7 +38,16 @@ func PublishPackage(c *gin.Context) {
 		return
 	}
 
-	response, err := packageService.PublishPackage(id)
+	// 请求体可选，不传时整个包作为一个任务
+	var req models.PublishPackageReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	response, err := packageService.PublishPackage(id, req)
 	if err != nil {
 		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
 		return
@@ -64,6 +73,33 @@ func GetPackageList(c *gin.Context) {
 	utils.ResponseOk(c, response)
 }
 
+// GetPackageSLA 管理员查看各包的 SLA 达成率
+func GetPackageSLA(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以查看 SLA 统计", http.StatusForbidden)
+		return
+	}
+
+	var req models.PackageSLARequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.PackageSLA(req.PackageID)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
 // GetPackageDetail 获取包详情
 func GetPackageDetail(c *gin.Context) {
 	id, err := utils.ParseInt64(c.Param("package_id"))
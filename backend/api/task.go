@@ -1,7 +1,9 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"luma-ai-backend/models"
 	"luma-ai-backend/services"
@@ -38,7 +40,7 @@ func GetTaskList(c *gin.Context) {
 		return
 	}
 
-	response, err := taskService.GetTaskList(req.UserID, req.Status, req.Page, req.PageSize)
+	response, err := taskService.GetTaskList(req)
 	if err != nil {
 		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
 		return
@@ -77,6 +79,61 @@ func ClaimTask(c *gin.Context) {
 	utils.ResponseOk(c, response)
 }
 
+// NextTask 领取队列中优先级最高的可领取任务
+func NextTask(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	response, err := taskService.NextTask(userID.(int64), userRole.(string))
+	if err != nil {
+		if errors.Is(err, services.ErrNoTaskAvailable) {
+			utils.ResponseErr(c, err.Error(), http.StatusNotFound)
+			return
+		}
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// ReworkTask 标注员对被驳回的任务返工
+func ReworkTask(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.TaskReworkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.StartRework(req.TaskID, userID.(int64), userRole.(string))
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
 // UpdateTaskStatus 更新任务状态
 func UpdateTaskStatus(c *gin.Context) {
 	// 获取当前用户信息
@@ -114,13 +171,19 @@ func UpdateTaskWipIdx(c *gin.Context) {
 		return
 	}
 
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
 	var req models.TaskWipUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response, err := taskService.UpdateTaskWipIdx(req.TaskID, userID.(int64), req.WipIdx)
+	response, err := taskService.UpdateTaskWipIdx(req.TaskID, userID.(int64), userRole.(string), req.WipIdx)
 	if err != nil {
 		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
 		return
@@ -174,20 +237,50 @@ func SaveAnnotation(c *gin.Context) {
 		return
 	}
 
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
 	var req models.SavedAnnotationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// If-Match 携带读取时的版本号，新建标注时可以不带
+	var ifMatch *int
+	if header := c.GetHeader("If-Match"); header != "" {
+		version, err := utils.ParseIfMatch(header)
+		if err != nil {
+			utils.ResponseErr(c, "无效的 If-Match", http.StatusBadRequest)
+			return
+		}
+		ifMatch = &version
+	}
+
 	// 验证用户是否有权限保存该任务的标注
 	// 只有任务的标注员可以保存标注
-	response, err := taskService.SaveAnnotation(req, userID.(int64))
+	response, err := taskService.SaveAnnotation(req, userID.(int64), userRole.(string), ifMatch)
 	if err != nil {
-		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
-		return
-	}
-
+		var validationErr *services.AnnotationValidationError
+		var conflictErr *services.AnnotationConflictError
+		switch {
+		case errors.As(err, &validationErr):
+			utils.ResponseErrWithData(c, err.Error(), http.StatusBadRequest, validationErr.Errors)
+		case errors.As(err, &conflictErr):
+			c.Header("ETag", utils.FormatETag(conflictErr.Current.Version))
+			utils.ResponseErrWithData(c, err.Error(), http.StatusConflict, conflictErr.Current)
+		case errors.Is(err, services.ErrAnnotationVersionRequired):
+			utils.ResponseErr(c, err.Error(), http.StatusPreconditionRequired)
+		default:
+			utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	c.Header("ETag", utils.FormatETag(response.Version))
 	utils.ResponseOk(c, response)
 }
 
@@ -219,12 +312,19 @@ func ReviewAnnotation(c *gin.Context) {
 		return
 	}
 
-	response, err := taskService.ReviewAnnotation(req, userID.(int64))
+	response, err := taskService.ReviewAnnotation(req, userID.(int64), userRole.(string))
 	if err != nil {
+		var conflictErr *services.AnnotationConflictError
+		if errors.As(err, &conflictErr) {
+			c.Header("ETag", utils.FormatETag(conflictErr.Current.Version))
+			utils.ResponseErrWithData(c, err.Error(), http.StatusConflict, conflictErr.Current)
+			return
+		}
 		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	c.Header("ETag", utils.FormatETag(response.Version))
 	utils.ResponseOk(c, response)
 }
 
@@ -262,5 +362,253 @@ func GetAnnotation(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", utils.FormatETag(response.Version))
+	utils.ResponseOk(c, response)
+}
+
+// ListAnnotationRevisions 获取条目的标注修订记录
+func ListAnnotationRevisions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := utils.ParseInt64(c.Query("task_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的任务ID", http.StatusBadRequest)
+		return
+	}
+
+	key := c.Query("key")
+	if key == "" {
+		utils.ResponseErr(c, "key参数不能为空", http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.ListAnnotationRevisions(taskID, key, userID.(int64), userRole.(string))
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// DiffAnnotationRevisions 比较两个标注修订版本
+func DiffAnnotationRevisions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.AnnotationRevisionDiffReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.DiffAnnotationRevisions(req, userID.(int64), userRole.(string))
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// RestoreAnnotationRevision 将标注恢复到历史版本
+func RestoreAnnotationRevision(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RestoreAnnotationRevisionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 与保存标注相同，恢复必须基于读取时的版本
+	var ifMatch *int
+	if header := c.GetHeader("If-Match"); header != "" {
+		version, err := utils.ParseIfMatch(header)
+		if err != nil {
+			utils.ResponseErr(c, "无效的 If-Match", http.StatusBadRequest)
+			return
+		}
+		ifMatch = &version
+	}
+
+	response, err := taskService.RestoreAnnotationRevision(req, userID.(int64), userRole.(string), ifMatch)
+	if err != nil {
+		var validationErr *services.AnnotationValidationError
+		var conflictErr *services.AnnotationConflictError
+		switch {
+		case errors.As(err, &validationErr):
+			utils.ResponseErrWithData(c, err.Error(), http.StatusBadRequest, validationErr.Errors)
+		case errors.As(err, &conflictErr):
+			c.Header("ETag", utils.FormatETag(conflictErr.Current.Version))
+			utils.ResponseErrWithData(c, err.Error(), http.StatusConflict, conflictErr.Current)
+		case errors.Is(err, services.ErrAnnotationVersionRequired):
+			utils.ResponseErr(c, err.Error(), http.StatusPreconditionRequired)
+		default:
+			utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	c.Header("ETag", utils.FormatETag(response.Version))
+	utils.ResponseOk(c, response)
+}
+
+// GetTaskTimeline 获取任务的事件时间线
+func GetTaskTimeline(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := utils.ParseInt64(c.Param("task_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的任务ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.GetTaskTimeline(taskID, userID.(int64), userRole.(string))
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// GetTaskItemURL 获取任务条目的限时下载地址，存储凭证不再下发给前端
+func GetTaskItemURL(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := utils.ParseInt64(c.Param("task_id"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的任务ID", http.StatusBadRequest)
+		return
+	}
+	idx, err := strconv.Atoi(c.Param("idx"))
+	if err != nil {
+		utils.ResponseErr(c, "无效的条目下标", http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.GetTaskItemURL(taskID, idx, userID.(int64), userRole.(string))
+	if err != nil {
+		if errors.Is(err, services.ErrTaskItemForbidden) {
+			utils.ResponseErr(c, err.Error(), http.StatusForbidden)
+			return
+		}
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// SetTaskPriority 管理员设置任务的优先级和截止时间
+func SetTaskPriority(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以设置任务优先级", http.StatusForbidden)
+		return
+	}
+
+	var req models.TaskPriorityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.SetTaskPriority(req, adminID.(int64))
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseOk(c, response)
+}
+
+// BatchUpdateTasks 管理员批量分配、取消分配、修改状态或优先级
+func BatchUpdateTasks(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		utils.ResponseErr(c, "用户未登录", http.StatusUnauthorized)
+		return
+	}
+
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "用户角色未找到", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "只有管理员可以批量操作任务", http.StatusForbidden)
+		return
+	}
+
+	var req models.TaskBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseErr(c, "参数错误: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := taskService.BatchUpdateTasks(req, adminID.(int64))
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// 部分任务失败时整批回滚，由 committed 和各任务的结果说明原因
 	utils.ResponseOk(c, response)
 }
@@ -231,3 +231,35 @@ func GetUserList(c *gin.Context) {
 
 	utils.ResponseOk(c, response)
 }
+
+// UpdateUserSkills 管理员设置用户的技能标签和同时进行的任务上限
+func UpdateUserSkills(c *gin.Context) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		utils.ResponseErr(c, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if userRole != models.RoleAdmin {
+		utils.ResponseErr(c, "only admin can update user skills", http.StatusForbidden)
+		return
+	}
+
+	targetUserID, err := utils.ParseInt64(c.Param("user_id"))
+	if err != nil {
+		utils.ResponseErr(c, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UserSkillsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := userService.UpdateUserSkills(targetUserID, &req)
+	if err != nil {
+		utils.ResponseErr(c, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	utils.ResponseOk(c, response)
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// BucketSyncInterval 定期全量扫描存储桶更新对象索引的间隔，为 0 时只在手动触发时同步
+var BucketSyncInterval = 6 * time.Hour
+
+// InitBucketSync 读取存储桶同步配置
+func InitBucketSync() {
+	if interval := os.Getenv("BUCKET_SYNC_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d < 0 {
+			log.Printf("BUCKET_SYNC_INTERVAL 配置无效: %s，使用默认值 %s", interval, BucketSyncInterval)
+		} else {
+			BucketSyncInterval = d
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"luma-ai-backend/models"
+)
+
+// InitCredentialKeys 读取存储桶凭证加密的主密钥
+// BUCKET_MASTER_KEYS 格式为 "版本:base64密钥"，多个用逗号分隔；BUCKET_MASTER_KEY_FILE 为密钥文件，每行一个，# 开头为注释
+// 默认使用版本号最大的密钥加密，可以通过 BUCKET_MASTER_KEY_VERSION 指定
+func InitCredentialKeys() {
+	var entries []string
+	if env := os.Getenv("BUCKET_MASTER_KEYS"); env != "" {
+		entries = append(entries, strings.Split(env, ",")...)
+	}
+	if file := os.Getenv("BUCKET_MASTER_KEY_FILE"); file != "" {
+		lines, err := readKeyFile(file)
+		if err != nil {
+			log.Fatalf("读取主密钥文件失败: %v", err)
+		}
+		entries = append(entries, lines...)
+	}
+
+	keys := map[int][]byte{}
+	current := 0
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		version, key, err := parseMasterKey(entry)
+		if err != nil {
+			log.Fatalf("主密钥配置无效: %v", err)
+		}
+		keys[version] = key
+		if version > current {
+			current = version
+		}
+	}
+
+	if v := os.Getenv("BUCKET_MASTER_KEY_VERSION"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("BUCKET_MASTER_KEY_VERSION 配置无效: %s", v)
+		}
+		current = version
+	}
+
+	if err := models.SetCredentialKeys(keys, current); err != nil {
+		log.Fatalf("主密钥配置无效: %v", err)
+	}
+	if current == 0 {
+		log.Println("未配置主密钥，存储桶凭证将以明文保存")
+	}
+}
+
+// parseMasterKey 解析 "版本:base64密钥"
+func parseMasterKey(entry string) (int, []byte, error) {
+	parts := strings.SplitN(entry, ":", 2)
+	if len(parts) != 2 {
+		return 0, nil, fmt.Errorf("格式应为 版本:base64密钥")
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, nil, fmt.Errorf("无效的版本号 %s", parts[0])
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, nil, fmt.Errorf("主密钥 v%d 不是有效的 base64", version)
+	}
+	return version, key, nil
+}
+
+// readKeyFile 读取密钥文件中的非空、非注释行
+func readKeyFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"luma-ai-backend/models"
 
@@ -80,11 +81,19 @@ func InitRedis() {
 func syncDatabase(engine *xorm.Engine) {
 	tables := []interface{}{
 		new(models.User),
-		new(models.SysMsg),          // 添加系统消息表
-		new(models.Bucket),          // 添加存储桶表
-		new(models.Package),         // 添加包表
-		new(models.Task),            // 添加任务表
-		new(models.SavedAnnotation), // 添加标注
+		new(models.SysMsg),              // 添加系统消息表
+		new(models.Bucket),              // 添加存储桶表
+		new(models.Package),             // 添加包表
+		new(models.Task),                // 添加任务表
+		new(models.SavedAnnotation),     // 添加标注
+		new(models.AnnotationRevision),  // 添加标注修订记录
+		new(models.ConsensusAnnotation), // 添加重叠标注合并结果
+		new(models.GoldItem),            // 添加金标准条目
+		new(models.GoldScore),           // 添加金标准评分记录
+		new(models.TaskEvent),           // 添加任务事件
+		new(models.TaskSlaAlert),        // 添加截止时间提醒记录
+		new(models.BucketObject),        // 添加存储桶对象索引
+		new(models.BucketSync),          // 添加存储桶同步进度
 	}
 
 	tableNames := []string{
@@ -94,6 +103,19 @@ func syncDatabase(engine *xorm.Engine) {
 		"包",
 		"任务",
 		"标注",
+		"标注修订记录",
+		"重叠标注合并结果",
+		"金标准条目",
+		"金标准评分记录",
+		"任务事件",
+		"截止时间提醒记录",
+		"存储桶对象索引",
+		"存储桶同步进度",
+	}
+
+	// 标注表新增 (task_id, key) 唯一索引，同步前需要清理历史重复数据
+	if err := dedupSavedAnnotations(engine); err != nil {
+		log.Fatalf("清理重复标注失败: %v", err)
 	}
 
 	for i, table := range tables {
@@ -105,3 +127,67 @@ func syncDatabase(engine *xorm.Engine) {
 
 	log.Println("数据库表结构同步完成")
 }
+
+// 重复标注：同一任务同一条目 id 较小（较早写入）的标注，有三条以上副本时同一 id 只返回一次
+const duplicateAnnotationsSQL = "SELECT DISTINCT a.id FROM saved_annotation a JOIN saved_annotation b " +
+	"ON a.task_id = b.task_id AND a.`key` = b.`key` AND a.id < b.id"
+
+// dedupSavedAnnotations 一次性迁移：清理同一任务同一条目的重复标注，保留 id 最大（最后写入）的一条
+// 只有设置 DEDUP_SAVED_ANNOTATIONS=true 时执行，删除前把标注及其修订记录备份到带时间戳的表中，
+// 并在同一事务中删除被清理标注的修订记录
+func dedupSavedAnnotations(engine *xorm.Engine) error {
+	exist, err := engine.IsTableExist(new(models.SavedAnnotation))
+	if err != nil || !exist {
+		return err
+	}
+
+	var ids []int64
+	if err = engine.SQL(duplicateAnnotationsSQL).Find(&ids); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if os.Getenv("DEDUP_SAVED_ANNOTATIONS") != "true" {
+		return fmt.Errorf("发现 %d 条重复标注，无法创建 (task_id, key) 唯一索引，请备份数据库后设置 DEDUP_SAVED_ANNOTATIONS=true 重新启动", len(ids))
+	}
+
+	revisionExist, err := engine.IsTableExist(new(models.AnnotationRevision))
+	if err != nil {
+		return err
+	}
+
+	// 建表语句会隐式提交，备份在事务之外进行
+	suffix := time.Now().Format("20060102150405")
+	if _, err = engine.Exec("CREATE TABLE saved_annotation_dedup_" + suffix + " AS SELECT * FROM saved_annotation WHERE id IN (" + duplicateAnnotationsSQL + ")"); err != nil {
+		return err
+	}
+	if revisionExist {
+		if _, err = engine.Exec("CREATE TABLE annotation_revision_dedup_" + suffix + " AS SELECT * FROM annotation_revision WHERE annotation_id IN (" + duplicateAnnotationsSQL + ")"); err != nil {
+			return err
+		}
+	}
+
+	session := engine.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return err
+	}
+	if revisionExist {
+		if _, err = session.Where("annotation_id IN (SELECT id FROM saved_annotation_dedup_" + suffix + ")").Delete(new(models.AnnotationRevision)); err != nil {
+			session.Rollback()
+			return err
+		}
+	}
+	deleted, err := session.Where("id IN (SELECT id FROM saved_annotation_dedup_" + suffix + ")").Delete(new(models.SavedAnnotation))
+	if err != nil {
+		session.Rollback()
+		return err
+	}
+	if err = session.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("已清理 %d 条重复标注，备份表 saved_annotation_dedup_%s", deleted, suffix)
+	return nil
+}
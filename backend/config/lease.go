@@ -0,0 +1,33 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// TaskLeaseTTL 领取任务后的租约时长，标注员或审核员在此期间没有操作则任务被释放，为 0 时不启用租约
+var TaskLeaseTTL = 24 * time.Hour
+
+// TaskLeaseScanInterval 检查过期租约的间隔
+var TaskLeaseScanInterval = 5 * time.Minute
+
+// InitLease 读取任务租约配置，格式为 Go duration，例如 2h、30m
+func InitLease() {
+	if ttl := os.Getenv("TASK_LEASE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d < 0 {
+			log.Printf("TASK_LEASE_TTL 配置无效: %s，使用默认值 %s", ttl, TaskLeaseTTL)
+		} else {
+			TaskLeaseTTL = d
+		}
+	}
+	if interval := os.Getenv("TASK_LEASE_SCAN_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			log.Printf("TASK_LEASE_SCAN_INTERVAL 配置无效: %s，使用默认值 %s", interval, TaskLeaseScanInterval)
+		} else {
+			TaskLeaseScanInterval = d
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// TaskMaxConcurrent 用户同时进行的任务上限默认值，用户设置了 MaxTasks 时以用户为准，为 0 时不限制
+var TaskMaxConcurrent = 0
+
+// InitQueue 读取任务队列配置
+func InitQueue() {
+	if limit := os.Getenv("TASK_MAX_CONCURRENT"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			log.Printf("TASK_MAX_CONCURRENT 配置无效: %s，使用默认值 %d", limit, TaskMaxConcurrent)
+		} else {
+			TaskMaxConcurrent = n
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskSLAThresholds 相对截止时间发送提醒的时间点，负数为截止前，0 和正数为逾期后，按从早到晚排序
+var TaskSLAThresholds = []time.Duration{-24 * time.Hour, 0}
+
+// TaskSLAScanInterval 检查截止时间的间隔
+var TaskSLAScanInterval = 10 * time.Minute
+
+// InitSLA 读取截止时间提醒配置，TASK_SLA_THRESHOLDS 为逗号分隔的 Go duration，例如 -24h,-2h,0,24h，为空字符串时不提醒
+func InitSLA() {
+	if thresholds, ok := os.LookupEnv("TASK_SLA_THRESHOLDS"); ok {
+		parsed := make([]time.Duration, 0)
+		valid := true
+		for _, s := range strings.Split(thresholds, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				valid = false
+				break
+			}
+			parsed = append(parsed, d)
+		}
+		if valid {
+			sort.Slice(parsed, func(i, j int) bool { return parsed[i] < parsed[j] })
+			TaskSLAThresholds = parsed
+		} else {
+			log.Printf("TASK_SLA_THRESHOLDS 配置无效: %s，使用默认值 %v", thresholds, TaskSLAThresholds)
+		}
+	}
+	if interval := os.Getenv("TASK_SLA_SCAN_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			log.Printf("TASK_SLA_SCAN_INTERVAL 配置无效: %s，使用默认值 %s", interval, TaskSLAScanInterval)
+		} else {
+			TaskSLAScanInterval = d
+		}
+	}
+}
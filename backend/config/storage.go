@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"luma-ai-backend/storage"
+)
+
+// StorageConfig 本地存储配置
+type StorageConfig struct {
+	PublicURL string // 后端对外访问地址，用于生成本地存储的下载地址
+	SignKey   string // 本地存储下载地址的签名密钥
+	UploadDir string // 未配置阿里云 OSS 时上传文件保存的目录
+	LocalRoot string // 存储桶使用本地存储时根目录必须位于此目录下，未配置时不允许创建本地存储桶
+}
+
+// StoragePresignTTL 后端生成的对象下载地址的有效期
+var StoragePresignTTL = 15 * time.Minute
+
+var Storage StorageConfig
+
+// InitStorage 读取本地存储配置
+func InitStorage() {
+	Storage = StorageConfig{
+		PublicURL: strings.TrimRight(os.Getenv("STORAGE_PUBLIC_URL"), "/"),
+		SignKey:   os.Getenv("STORAGE_SIGN_KEY"),
+		UploadDir: os.Getenv("UPLOAD_LOCAL_DIR"),
+		LocalRoot: os.Getenv("STORAGE_LOCAL_ROOT"),
+	}
+	if Storage.PublicURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3001"
+		}
+		Storage.PublicURL = "http://localhost:" + port
+	}
+	if Storage.SignKey == "" {
+		Storage.SignKey = os.Getenv("JWT_SECRET")
+	}
+	if Storage.UploadDir == "" {
+		Storage.UploadDir = "./uploads"
+	}
+	if ttl := os.Getenv("STORAGE_PRESIGN_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			log.Printf("STORAGE_PRESIGN_TTL 配置无效: %s，使用默认值 %s", ttl, StoragePresignTTL)
+		} else {
+			StoragePresignTTL = d
+		}
+	}
+}
+
+// UseOSSUpload 是否把上传文件保存到阿里云 OSS
+func UseOSSUpload() bool {
+	return Aliyun.BucketName != ""
+}
+
+// GetUploadStorage 获取上传文件使用的存储和文件访问地址前缀，配置了阿里云 OSS 时使用 OSS，否则保存到本地目录
+func GetUploadStorage() (storage.Provider, string, error) {
+	if UseOSSUpload() {
+		provider, err := storage.NewOSS(storage.Config{
+			Bucket:    Aliyun.BucketName,
+			Endpoint:  Aliyun.Endpoint,
+			AccessKey: Aliyun.AccessID,
+			SecretKey: Aliyun.AccessKey,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		domain := Aliyun.Domain
+		if !strings.Contains(domain, "http") {
+			domain = "http://" + domain
+		}
+		return provider, domain, nil
+	}
+
+	if err := os.MkdirAll(Storage.UploadDir, 0o755); err != nil {
+		return nil, "", fmt.Errorf("无法创建上传目录: %v", err)
+	}
+	provider, err := storage.NewLocal(storage.Config{Endpoint: Storage.UploadDir})
+	if err != nil {
+		return nil, "", err
+	}
+	return provider, Storage.PublicURL + "/uploads", nil
+}
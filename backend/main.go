@@ -1,12 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 
 	"luma-ai-backend/config"
 	"luma-ai-backend/middleware"
+	"luma-ai-backend/models"
 	"luma-ai-backend/routes"
+	"luma-ai-backend/services"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -38,18 +41,49 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// 存储桶凭证加密的主密钥
+	config.InitCredentialKeys()
+
 	// 初始化数据库
 	config.InitDB()
 	defer config.DB.Close()
 
+	// 管理命令：go run main.go rotate-bucket-keys，使用当前主密钥重新加密所有存储桶凭证
+	if len(os.Args) > 1 && os.Args[1] == "rotate-bucket-keys" {
+		n, err := services.NewBucketService().RotateCredentialKeys()
+		if err != nil {
+			fmt.Println("重新加密存储桶凭证失败:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已使用主密钥 v%d 重新加密 %d 个存储桶的凭证\n", models.CredentialKeyVersion(), n)
+		return
+	}
+
 	// 初始化Redis
 	config.InitRedis()
 
 	// 初始化阿里云
 	config.InitAliyun()
+	// 本地存储配置
+	config.InitStorage()
 	// 初始化邮件服务
 	config.InitBrevo()
 
+	// 任务队列配置
+	config.InitQueue()
+
+	// 释放租约过期的任务
+	config.InitLease()
+	services.StartLeaseReaper()
+
+	// 截止时间提醒
+	config.InitSLA()
+	services.StartSLAScheduler()
+
+	// 存储桶对象索引定期同步
+	config.InitBucketSync()
+	services.StartBucketSyncScheduler()
+
 	// 创建Gin引擎
 	r := gin.Default()
 
@@ -71,8 +105,8 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"https://api.test.co", "http://localhost:3000", "http://106.13.121.206"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 	}))
 
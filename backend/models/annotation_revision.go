@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// 标注修订的来源操作
+const (
+	RevisionActionSave    = "save"    // 标注员保存
+	RevisionActionReview  = "review"  // 审核员审核
+	RevisionActionImport  = "import"  // 导入预标注
+	RevisionActionRestore = "restore" // 恢复到历史版本
+)
+
+// AnnotationRevision 标注修订记录，只追加不修改，每次保存或审核后记录标注的完整快照
+type AnnotationRevision struct {
+	ID           int64       `xorm:"pk autoincr 'id'" json:"id"`
+	AnnotationID int64       `xorm:"unique(annotation_revision) not null 'annotation_id'" json:"annotationId"`
+	Revision     int         `xorm:"unique(annotation_revision) not null 'revision'" json:"revision"` // 同一标注内从 1 递增
+	TaskID       int64       `xorm:"index(task_key) not null 'task_id'" json:"taskId"`
+	Key          string      `xorm:"varchar(500) index(task_key) not null 'key'" json:"key"`
+	Action       string      `xorm:"varchar(20) not null 'action'" json:"action"`
+	UserID       int64       `xorm:"'user_id'" json:"userId"` // 操作人
+	Marks        []MarkData  `xorm:"json 'marks'" json:"marks"`
+	Review       *ReviewInfo `xorm:"json 'review'" json:"review,omitempty"`
+	RestoredFrom int         `xorm:"'restored_from'" json:"restoredFrom,omitempty"` // 恢复操作对应的历史版本号
+	CreatedAt    time.Time   `xorm:"created 'created_at'" json:"created_at"`
+}
+
+// AnnotationRevisionListResponse 标注修订列表响应
+type AnnotationRevisionListResponse struct {
+	List  []AnnotationRevision `json:"list"`
+	Total int64                `json:"total"`
+}
+
+// AnnotationRevisionDiffReq 比较两个修订版本的请求
+type AnnotationRevisionDiffReq struct {
+	TaskID int64  `form:"task_id" binding:"required"`
+	Key    string `form:"key" binding:"required"`
+	From   int    `form:"from" binding:"required"`
+	To     int    `form:"to" binding:"required"`
+}
+
+// MarkChange 两个版本间位置或形状发生变化的标记
+type MarkChange struct {
+	From MarkData `json:"from"`
+	To   MarkData `json:"to"`
+	IoU  float64  `json:"iou"` // 新旧形状的交并比，没有面积的标记为 0
+}
+
+// AnnotationRevisionDiff 两个修订版本之间的标记差异
+type AnnotationRevisionDiff struct {
+	From      int          `json:"from"`
+	To        int          `json:"to"`
+	Added     []MarkData   `json:"added"`
+	Removed   []MarkData   `json:"removed"`
+	Moved     []MarkChange `json:"moved"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// RestoreAnnotationRevisionReq 恢复历史版本请求
+type RestoreAnnotationRevisionReq struct {
+	TaskID   int64  `json:"taskId" binding:"required"`
+	Key      string `json:"key" binding:"required"`
+	Revision int    `json:"revision" binding:"required"`
+}
@@ -1,19 +1,70 @@
 package models
 
 import (
+	"fmt"
+	"log"
 	"time"
 )
 
-// Bucket S3存储桶模型
+// Bucket 存储桶模型
 type Bucket struct {
 	ID        int64     `xorm:"pk autoincr 'id'" json:"id"`
 	Name      string    `xorm:"varchar(100) not null 'name'" json:"name"`
+	Provider  string    `xorm:"varchar(20) not null default 's3' 'provider'" json:"provider"` // s3、oss 或 local
 	Region    string    `xorm:"varchar(50) not null 'region'" json:"region"`
+	Endpoint  string    `xorm:"varchar(500) 'endpoint'" json:"endpoint"` // S3 兼容服务或 OSS 的访问地址，本地存储为根目录
 	PathMode  bool      `xorm:"bool default false 'path_mode'" json:"path_mode"`
-	AccessKey string    `xorm:"varchar(255) not null 'access_key'" json:"-"`
-	SecretKey string    `xorm:"varchar(255) not null 'secret_key'" json:"-"`
 	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
 	UpdatedAt time.Time `xorm:"updated 'updated_at'" json:"updated_at"`
+
+	// 访问凭证只在内存中为明文，读取时自动解密，写入凭证列前需要调用 SealCredentials 加密
+	AccessKey       string `xorm:"-" json:"-"`
+	SecretKey       string `xorm:"-" json:"-"`
+	AccessKeyCipher string `xorm:"varchar(512) not null 'access_key'" json:"-"`
+	SecretKeyCipher string `xorm:"varchar(512) not null 'secret_key'" json:"-"`
+	DataKey         string `xorm:"varchar(255) 'data_key'" json:"-"` // 用主密钥加密的数据密钥
+	KeyVersion      int    `xorm:"default 0 'key_version'" json:"-"` // 主密钥版本，0 表示凭证为明文
+
+	credentialErr error // 解密凭证失败的原因
+}
+
+// AfterLoad 读取后解密凭证
+func (b *Bucket) AfterLoad() {
+	plain, err := openCredentials(b.DataKey, b.KeyVersion, b.AccessKeyCipher, b.SecretKeyCipher)
+	if err != nil {
+		log.Printf("存储桶 %d 凭证解密失败: %v", b.ID, err)
+		b.credentialErr = err
+		return
+	}
+	b.AccessKey, b.SecretKey = plain[0], plain[1]
+	b.credentialErr = nil
+}
+
+// CredentialError 凭证解密失败时返回原因
+func (b *Bucket) CredentialError() error {
+	return b.credentialErr
+}
+
+// SealCredentials 使用当前主密钥重新生成数据密钥并加密凭证，失败时保留原有密文不变
+func (b *Bucket) SealCredentials() error {
+	dataKey, sealed, version, err := sealCredentials(b.AccessKey, b.SecretKey)
+	if err != nil {
+		return fmt.Errorf("存储桶凭证加密失败: %v", err)
+	}
+	b.AccessKeyCipher, b.SecretKeyCipher = sealed[0], sealed[1]
+	b.DataKey, b.KeyVersion = dataKey, version
+	return nil
+}
+
+// MaskCredential 只保留凭证首尾几位用于辨认
+func MaskCredential(credential string) string {
+	if credential == "" {
+		return ""
+	}
+	if len(credential) <= 8 {
+		return "****"
+	}
+	return credential[:4] + "****" + credential[len(credential)-4:]
 }
 
 // BucketAccess S3访问凭证
@@ -22,22 +73,28 @@ type BucketAccess struct {
 	Secret string `json:"secret"`
 }
 
-// BucketReq 创建/更新存储桶请求
+// BucketReq 创建/更新存储桶请求，Region 和 Access 按存储类型校验
 type BucketReq struct {
 	ID       int64        `json:"id"`
 	Name     string       `json:"name" binding:"required"`
-	Region   string       `json:"region" binding:"required"`
+	Provider string       `json:"provider" binding:"omitempty,oneof=s3 oss local"`
+	Region   string       `json:"region"`
+	Endpoint string       `json:"endpoint"`
 	PathMode bool         `json:"path_mode"`
-	Access   BucketAccess `json:"access" binding:"required"`
+	Access   BucketAccess `json:"access"`
 }
 
-// BucketResponse 存储桶detail
+// BucketResponse 存储桶detail，不返回凭证，只返回用于辨认的 access key 提示
 type BucketResponse struct {
-	ID        int64        `json:"id"`
-	Name      string       `json:"name"`
-	Region    string       `json:"region"`
-	Access    BucketAccess `json:"access"`
-	CreatedAt time.Time    `json:"created_at"`
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Provider      string    `json:"provider"`
+	Region        string    `json:"region"`
+	Endpoint      string    `json:"endpoint"`
+	PathMode      bool      `json:"path_mode"`
+	AccessKeyHint string    `json:"accessKeyHint,omitempty"`
+	KeyVersion    int       `json:"keyVersion"` // 主密钥版本，0 表示凭证未加密
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 // ListBucketRequest 存储桶列表请求
@@ -59,20 +116,29 @@ type ObjectInfo struct {
 	Type         string    `json:"type"` // "image" or "video"
 	Size         int64     `json:"size"`
 	LastModified time.Time `json:"last_modified"`
+	Width        int       `json:"width,omitempty"` // 仅索引中的图片有宽高
+	Height       int       `json:"height,omitempty"`
 }
 
-// ListObjectsRequest 对象列表请求
+// ListObjectsRequest 对象列表请求，按目录列出，翻页时传入上一页返回的 continuation_token
+// 搜索、筛选和排序需要存储桶已完成同步
 type ListObjectsRequest struct {
-	BucketID int64  `form:"bucket_id" binding:"required"`
-	Prefix   string `form:"prefix"`
-	Page     int    `form:"page" binding:"required,min=1"`
-	PageSize int    `form:"page_size" binding:"required,min=1,max=100"`
+	BucketID          int64  `form:"bucket_id" binding:"required"`
+	Prefix            string `form:"prefix"` // 目录前缀，以 "/" 结尾
+	ContinuationToken string `form:"continuation_token"`
+	PageSize          int    `form:"page_size" binding:"required,min=1,max=1000"`
+	Search            string `form:"search"`                                                        // 按文件名搜索，包含前缀下所有子目录
+	MediaType         string `form:"media_type" binding:"omitempty,oneof=image video"`              // 按类型筛选
+	SortBy            string `form:"sort_by" binding:"omitempty,oneof=key name size last_modified"` // 默认按 key
+	Order             string `form:"order" binding:"omitempty,oneof=asc desc"`
 }
 
 // ListObjectsResponse 对象列表响应
 type ListObjectsResponse struct {
-	Objects  []ObjectInfo `json:"objects"`
-	Total    int64        `json:"total"`
-	Page     int          `json:"page"`
-	PageSize int          `json:"page_size"`
+	List                  []ObjectInfo `json:"list"`
+	Total                 int64        `json:"total"` // 本页返回的对象数
+	NextContinuationToken string       `json:"nextContinuationToken,omitempty"`
+	HasMore               bool         `json:"hasMore"`
+	SubDirectories        []string     `json:"subDirectories"` // 当前目录下的子目录前缀
+	Indexed               bool         `json:"indexed"`        // 是否来自对象索引，否则为实时列出
 }
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// BucketObject 存储桶对象索引，由后台同步任务从存储中增量更新，列表、搜索直接查询该表
+type BucketObject struct {
+	ID           int64      `xorm:"pk autoincr 'id'" json:"id"`
+	BucketID     int64      `xorm:"unique(bucket_key) index(bucket_dir) not null 'bucket_id'" json:"bucketId"`
+	KeyHash      string     `xorm:"varchar(64) unique(bucket_key) not null 'key_hash'" json:"-"` // key 的 sha256，key 过长无法直接建唯一索引
+	Key          string     `xorm:"varchar(1024) not null 'key'" json:"key"`
+	Dir          string     `xorm:"varchar(700) index(bucket_dir) not null 'dir'" json:"dir"` // key 所在目录，以 "/" 结尾，根目录为空
+	Name         string     `xorm:"varchar(255) not null 'name'" json:"name"`
+	Size         int64      `xorm:"'size'" json:"size"`
+	ETag         string     `xorm:"varchar(100) 'etag'" json:"etag"`
+	LastModified time.Time  `xorm:"'last_modified'" json:"lastModified"`
+	MediaType    string     `xorm:"varchar(20) index 'media_type'" json:"mediaType"` // image 或 video
+	Width        int        `xorm:"'width'" json:"width"`
+	Height       int        `xorm:"'height'" json:"height"`
+	Deleted      bool       `xorm:"index 'deleted' default(0)" json:"deleted"` // 同步时在存储中已不存在
+	DeletedAt    *time.Time `xorm:"'deleted_at'" json:"deletedAt,omitempty"`
+	SyncedAt     time.Time  `xorm:"index 'synced_at'" json:"syncedAt"` // 最后一次同步时看到该对象的时间
+	CreatedAt    time.Time  `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt    time.Time  `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+// 存储桶同步状态
+const (
+	BucketSyncIdle    = "idle"
+	BucketSyncRunning = "running"
+	BucketSyncFailed  = "failed"
+)
+
+// BucketSync 存储桶的同步进度，全量扫描中断后从 Cursor 继续
+type BucketSync struct {
+	ID            int64      `xorm:"pk autoincr 'id'" json:"id"`
+	BucketID      int64      `xorm:"unique not null 'bucket_id'" json:"bucketId"`
+	Status        string     `xorm:"varchar(20) not null 'status'" json:"status"`
+	Cursor        string     `xorm:"varchar(1024) 'cursor'" json:"-"`                  // 本轮扫描的续传标记
+	ScanStartedAt *time.Time `xorm:"'scan_started_at'" json:"scanStartedAt,omitempty"` // 本轮扫描开始的时间，早于该时间未被看到的对象视为已删除
+	LastSyncedAt  *time.Time `xorm:"'last_synced_at'" json:"lastSyncedAt,omitempty"`   // 最近一次完成全量扫描的时间
+	Objects       int64      `xorm:"'objects'" json:"objects"`                         // 索引中未删除的对象数
+	Added         int        `xorm:"'added'" json:"added"`                             // 最近一次扫描新增的对象数
+	Updated       int        `xorm:"'updated'" json:"updated"`                         // 最近一次扫描内容变化的对象数
+	Removed       int        `xorm:"'removed'" json:"removed"`                         // 最近一次扫描删除的对象数
+	Error         string     `xorm:"text 'error'" json:"error,omitempty"`
+	UpdatedAt     time.Time  `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+// MissingObject 已从存储中删除但仍被包引用的对象
+type MissingObject struct {
+	PackageID   int64      `json:"packageId"`
+	PackageName string     `json:"packageName"`
+	Key         string     `json:"key"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+}
+
+// MissingObjectResponse 存储桶中被包引用的已删除对象
+type MissingObjectResponse struct {
+	List  []MissingObject `json:"list"`
+	Total int             `json:"total"`
+}
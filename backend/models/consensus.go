@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// ConsensusAnnotation 重叠标注合并后的结果，每个包内条目一条
+type ConsensusAnnotation struct {
+	ID                int64      `xorm:"pk autoincr 'id'" json:"id"`
+	PackageID         int64      `xorm:"unique(package_key) not null 'package_id'" json:"packageId"`
+	Key               string     `xorm:"varchar(500) unique(package_key) not null 'key'" json:"key"`
+	ChunkIdx          int        `xorm:"'chunk_idx' default(0)" json:"chunkIdx"`
+	Marks             []MarkData `xorm:"json 'marks'" json:"marks"`                           // 合并后的标记，裁决后为审核员确认的标记
+	Agreement         float64    `xorm:"'agreement'" json:"agreement"`                        // 标注员之间的一致性，0-1
+	AnnotatorCount    int        `xorm:"'annotator_count'" json:"annotatorCount"`             // 参与合并的标注员数
+	NeedsAdjudication bool       `xorm:"index 'needs_adjudication'" json:"needsAdjudication"` // 一致性低于阈值，等待审核员裁决
+	AdjudicatedBy     int64      `xorm:"'adjudicated_by'" json:"adjudicatedBy,omitempty"`
+	AdjudicatedAt     *time.Time `xorm:"'adjudicated_at'" json:"adjudicatedAt,omitempty"`
+	CreatedAt         time.Time  `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt         time.Time  `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+// ConsensusListRequest 合并结果列表请求
+type ConsensusListRequest struct {
+	Flagged  bool `form:"flagged"` // 只看需要裁决的条目
+	Page     int  `form:"page" binding:"required,min=1"`
+	PageSize int  `form:"page_size" binding:"required,min=1,max=100"`
+}
+
+// ConsensusListResponse 合并结果列表响应
+type ConsensusListResponse struct {
+	List  []ConsensusAnnotation `json:"list"`
+	Total int64                 `json:"total"`
+}
+
+// ConsensusComputeResult 计算合并结果的统计
+type ConsensusComputeResult struct {
+	Chunks  int `json:"chunks"`  // 已完成合并的分片数
+	Pending int `json:"pending"` // 仍有副本未提交的分片数
+	Items   int `json:"items"`   // 合并的条目数
+	Flagged int `json:"flagged"` // 需要裁决的条目数
+}
+
+// AdjudicateConsensusReq 审核员裁决请求
+type AdjudicateConsensusReq struct {
+	Marks []MarkData `json:"marks" binding:"required"`
+}
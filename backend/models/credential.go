@@ -0,0 +1,136 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 凭证加密的主密钥，按版本保存，轮换后旧版本仍用于解密历史数据
+var (
+	credentialKeys       = map[int][]byte{}
+	credentialKeyVersion = 0 // 加密使用的版本，为 0 时凭证以明文保存
+)
+
+// SetCredentialKeys 设置主密钥（32 字节），current 为加密使用的版本
+func SetCredentialKeys(keys map[int][]byte, current int) error {
+	for version, key := range keys {
+		if version <= 0 {
+			return fmt.Errorf("主密钥版本必须大于 0: %d", version)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("主密钥 v%d 长度必须为 32 字节", version)
+		}
+	}
+	if current != 0 && keys[current] == nil {
+		return fmt.Errorf("未找到主密钥 v%d", current)
+	}
+	credentialKeys = keys
+	credentialKeyVersion = current
+	return nil
+}
+
+// CredentialKeyVersion 当前加密使用的主密钥版本
+func CredentialKeyVersion() int {
+	return credentialKeyVersion
+}
+
+// sealCredentials 信封加密：为每条记录生成数据密钥加密凭证，数据密钥再用当前主密钥加密
+// 返回加密后的数据密钥、密文和主密钥版本，未配置主密钥时原样返回明文，版本为 0
+func sealCredentials(plain ...string) (string, []string, int, error) {
+	version := credentialKeyVersion
+	if version == 0 {
+		return "", plain, 0, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", nil, 0, err
+	}
+	wrapped, err := gcmSeal(credentialKeys[version], dataKey)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	sealed := make([]string, len(plain))
+	for i, text := range plain {
+		ciphertext, err := gcmSeal(dataKey, []byte(text))
+		if err != nil {
+			return "", nil, 0, err
+		}
+		sealed[i] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+	return base64.StdEncoding.EncodeToString(wrapped), sealed, version, nil
+}
+
+// openCredentials 解密 sealCredentials 加密的凭证，版本为 0 时为明文
+func openCredentials(dataKey string, version int, sealed ...string) ([]string, error) {
+	if version == 0 {
+		return sealed, nil
+	}
+	masterKey := credentialKeys[version]
+	if masterKey == nil {
+		return nil, fmt.Errorf("未找到主密钥 v%d", version)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := gcmOpen(masterKey, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]string, len(sealed))
+	for i, text := range sealed {
+		ciphertext, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, err
+		}
+		data, err := gcmOpen(key, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		plain[i] = string(data)
+	}
+	return plain, nil
+}
+
+// gcmSeal AES-GCM 加密，随机 nonce 放在密文前面
+func gcmSeal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// gcmOpen 解密 gcmSeal 的结果
+func gcmOpen(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("密文长度无效")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
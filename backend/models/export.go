@@ -0,0 +1,116 @@
+package models
+
+import "encoding/xml"
+
+// ExportFormat 导出格式
+type ExportFormat string
+
+const (
+	ExportFormatCOCO ExportFormat = "coco"
+	ExportFormatYOLO ExportFormat = "yolo"
+	ExportFormatVOC  ExportFormat = "voc"
+	ExportFormatVTT  ExportFormat = "vtt"
+	ExportFormatSRT  ExportFormat = "srt"
+)
+
+// ArchiveFormat 导出归档格式
+type ArchiveFormat string
+
+const (
+	ArchiveFormatZip ArchiveFormat = "zip"
+	ArchiveFormatTar ArchiveFormat = "tar"
+)
+
+// ExportRequest 导出请求
+type ExportRequest struct {
+	Format  ExportFormat  `form:"format"`
+	Archive ArchiveFormat `form:"archive"` // zip（默认）或 tar，仅对归档类格式有效
+	Key     string        `form:"key"`     // 字幕格式下指定视频 key 时导出单个文件，否则导出归档
+}
+
+// CocoInfo COCO 数据集信息
+type CocoInfo struct {
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	DateCreated string `json:"date_created"`
+}
+
+// CocoImage COCO 图片条目，review 相关字段为扩展字段
+type CocoImage struct {
+	ID            int64  `json:"id"`
+	FileName      string `json:"file_name"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	TaskID        int64  `json:"task_id,omitempty"`
+	ReviewScore   *int   `json:"review_score,omitempty"`
+	ReviewComment string `json:"review_comment,omitempty"`
+	ReviewerID    int64  `json:"reviewer_id,omitempty"`
+	ReviewedAt    string `json:"reviewed_at,omitempty"`
+}
+
+// CocoCategory COCO 类别
+type CocoCategory struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Supercategory string `json:"supercategory"`
+}
+
+// CocoAnnotation COCO 实例标注
+type CocoAnnotation struct {
+	ID           int64       `json:"id"`
+	ImageID      int64       `json:"image_id"`
+	CategoryID   int         `json:"category_id"`
+	Segmentation [][]float64 `json:"segmentation"`
+	Area         float64     `json:"area"`
+	BBox         []float64   `json:"bbox"` // [x, y, width, height]
+	IsCrowd      int         `json:"iscrowd"`
+}
+
+// CocoDataset COCO instances 文件
+type CocoDataset struct {
+	Info        CocoInfo         `json:"info"`
+	Images      []CocoImage      `json:"images"`
+	Annotations []CocoAnnotation `json:"annotations"`
+	Categories  []CocoCategory   `json:"categories"`
+}
+
+// VocAnnotation Pascal VOC 标注文件
+type VocAnnotation struct {
+	XMLName   xml.Name    `xml:"annotation"`
+	Folder    string      `xml:"folder"`
+	Filename  string      `xml:"filename"`
+	Path      string      `xml:"path"`
+	Source    VocSource   `xml:"source"`
+	Size      VocSize     `xml:"size"`
+	Segmented int         `xml:"segmented"`
+	Objects   []VocObject `xml:"object"`
+}
+
+// VocSource VOC 数据来源
+type VocSource struct {
+	Database string `xml:"database"`
+}
+
+// VocSize VOC 图片尺寸
+type VocSize struct {
+	Width  int `xml:"width"`
+	Height int `xml:"height"`
+	Depth  int `xml:"depth"`
+}
+
+// VocObject VOC 目标
+type VocObject struct {
+	Name      string    `xml:"name"`
+	Pose      string    `xml:"pose"`
+	Truncated int       `xml:"truncated"`
+	Difficult int       `xml:"difficult"`
+	BndBox    VocBndBox `xml:"bndbox"`
+}
+
+// VocBndBox VOC 边界框（像素坐标）
+type VocBndBox struct {
+	XMin int `xml:"xmin"`
+	YMin int `xml:"ymin"`
+	XMax int `xml:"xmax"`
+	YMax int `xml:"ymax"`
+}
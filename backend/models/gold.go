@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// 金标准准确率低于阈值时对任务的处理方式
+const (
+	GoldActionFlag   = "flag"   // 标记任务，提醒管理员和审核员重点检查
+	GoldActionReject = "reject" // 直接驳回任务
+)
+
+// GoldItem 金标准条目，包中带有可信参考标注的条目，会混入每个任务中且不对标注员可见
+type GoldItem struct {
+	ID        int64      `xorm:"pk autoincr 'id'" json:"id"`
+	PackageID int64      `xorm:"unique(package_key) not null 'package_id'" json:"packageId"`
+	Key       string     `xorm:"varchar(500) unique(package_key) not null 'key'" json:"key"`
+	Marks     []MarkData `xorm:"json 'marks'" json:"marks"` // 参考标注
+	CreatedBy int64      `xorm:"'created_by'" json:"createdBy"`
+	CreatedAt time.Time  `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt time.Time  `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+// GoldScore 标注员提交任务时金标准条目的评分记录，按时间累积为准确率历史
+type GoldScore struct {
+	ID        int64     `xorm:"pk autoincr 'id'" json:"id"`
+	TaskID    int64     `xorm:"index not null 'task_id'" json:"taskId"`
+	PackageID int64     `xorm:"not null 'package_id'" json:"packageId"`
+	UserID    int64     `xorm:"index not null 'user_id'" json:"userId"`
+	GoldItems int       `xorm:"'gold_items'" json:"goldItems"` // 参与评分的金标准条目数
+	Precision float64   `xorm:"'precision'" json:"precision"`
+	Recall    float64   `xorm:"'recall'" json:"recall"`
+	Accuracy  float64   `xorm:"'accuracy'" json:"accuracy"` // 0-1，类别正确的匹配标记和视频片段时间交并比综合得分
+	Threshold float64   `xorm:"'threshold'" json:"threshold"`
+	Action    string    `xorm:"varchar(20) 'action'" json:"action,omitempty"` // 低于阈值时执行的处理，未触发为空
+	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
+}
+
+// GoldItemReq 金标准条目
+type GoldItemReq struct {
+	Key   string     `json:"key" binding:"required"`
+	Marks []MarkData `json:"marks" binding:"required"`
+}
+
+// SetGoldItemsReq 设置包的金标准条目及准确率阈值，会替换包原有的金标准条目
+type SetGoldItemsReq struct {
+	Threshold float64       `json:"threshold" binding:"omitempty,min=0,max=1"` // 0 表示只记录准确率不做处理
+	Action    string        `json:"action" binding:"omitempty,oneof=flag reject"`
+	Items     []GoldItemReq `json:"items" binding:"dive"`
+}
+
+// GoldItemsResponse 包的金标准设置
+type GoldItemsResponse struct {
+	Threshold float64    `json:"threshold"`
+	Action    string     `json:"action"`
+	Items     []GoldItem `json:"items"`
+}
+
+// GoldScoreListRequest 准确率历史请求
+type GoldScoreListRequest struct {
+	PackageID int64 `form:"package_id"`
+	Page      int   `form:"page" binding:"required,min=1"`
+	PageSize  int   `form:"page_size" binding:"required,min=1,max=100"`
+}
+
+// GoldScoreListResponse 准确率历史响应
+type GoldScoreListResponse struct {
+	List  []GoldScore `json:"list"`
+	Total int64       `json:"total"`
+	Mean  float64     `json:"mean"` // 所有记录的平均准确率
+}
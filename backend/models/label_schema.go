@@ -0,0 +1,54 @@
+package models
+
+// 标记类型
+const (
+	MarkTypeRect     = "rect"
+	MarkTypeCircle   = "circle"
+	MarkTypePolygon  = "polygon"
+	MarkTypePolyline = "polyline"
+	MarkTypePoint    = "point"
+	MarkTypeKeypoint = "keypoint"
+	MarkTypeVideo    = "video" // 视频片段，前端保存时不写 type
+)
+
+// LabelClass 标注类别
+type LabelClass struct {
+	Name               string   `json:"name" binding:"required"`
+	Color              string   `json:"color"`
+	RequiredAttributes []string `json:"requiredAttributes"` // 该类别必须填写的属性
+}
+
+// LabelSchema 包的标注规范
+type LabelSchema struct {
+	MarkTypes []string     `json:"markTypes"` // 允许的标记类型，为空时不限制
+	Classes   []LabelClass `json:"classes"`   // 允许的类别，为空时不限制
+}
+
+// AllowsMarkType 是否允许该标记类型
+func (s *LabelSchema) AllowsMarkType(markType string) bool {
+	if len(s.MarkTypes) == 0 {
+		return true
+	}
+	for _, t := range s.MarkTypes {
+		if t == markType {
+			return true
+		}
+	}
+	return false
+}
+
+// FindClass 按名称查找类别
+func (s *LabelSchema) FindClass(name string) (*LabelClass, bool) {
+	for i := range s.Classes {
+		if s.Classes[i].Name == name {
+			return &s.Classes[i], true
+		}
+	}
+	return nil, false
+}
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
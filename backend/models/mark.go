@@ -0,0 +1,601 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// circleSegments 圆形近似为多边形时使用的顶点数
+const circleSegments = 32
+
+// lineTolerance 折线、关键点命中判断的容差（像素）
+const lineTolerance = 1.0
+
+// Point 标记中的点，坐标相对于原图
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// BoundingBox 外接矩形
+type BoundingBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Area 面积
+func (b BoundingBox) Area() float64 {
+	return b.Width * b.Height
+}
+
+// Contains 点是否在矩形内（含边界）
+func (b BoundingBox) Contains(p Point) bool {
+	return p.X >= b.X && p.X <= b.X+b.Width && p.Y >= b.Y && p.Y <= b.Y+b.Height
+}
+
+// Intersect 与另一个矩形的交集，不相交时宽高为 0
+func (b BoundingBox) Intersect(o BoundingBox) BoundingBox {
+	x1, y1 := math.Max(b.X, o.X), math.Max(b.Y, o.Y)
+	x2, y2 := math.Min(b.X+b.Width, o.X+o.Width), math.Min(b.Y+b.Height, o.Y+o.Height)
+	if x2 <= x1 || y2 <= y1 {
+		return BoundingBox{}
+	}
+	return BoundingBox{X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1}
+}
+
+// IoU 与另一个矩形的交并比
+func (b BoundingBox) IoU(o BoundingBox) float64 {
+	inter := b.Intersect(o).Area()
+	union := b.Area() + o.Area() - inter
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
+
+// Polygon 矩形的四个顶点
+func (b BoundingBox) Polygon() []Point {
+	return []Point{{b.X, b.Y}, {b.X + b.Width, b.Y}, {b.X + b.Width, b.Y + b.Height}, {b.X, b.Y + b.Height}}
+}
+
+// MarkLabel 各类标记共有的显示和类别信息
+type MarkLabel struct {
+	Color      string         `json:"color"`
+	Text       string         `json:"text"`
+	Class      string         `json:"class,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Label 返回标记的类别信息，嵌入后各标记类型均可使用
+func (l *MarkLabel) Label() *MarkLabel {
+	return l
+}
+
+// ClassName 标记的类别：优先使用 class，未填写时使用标记文本
+func (l *MarkLabel) ClassName() string {
+	if class := strings.TrimSpace(l.Class); class != "" {
+		return class
+	}
+	return strings.TrimSpace(l.Text)
+}
+
+// Shape 带几何形状的标记
+type Shape interface {
+	BoundingBox() BoundingBox
+	Area() float64
+	Contains(p Point) bool
+	// Polygon 形状的轮廓，折线和关键点没有轮廓，返回 nil
+	Polygon() []Point
+	Label() *MarkLabel
+}
+
+// RectMark 矩形标记
+type RectMark struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	MarkLabel
+}
+
+// BoundingBox 外接矩形，反向拖拽时宽高可能为负，这里统一为正
+func (r *RectMark) BoundingBox() BoundingBox {
+	x, y, w, h := r.X, r.Y, r.Width, r.Height
+	if w < 0 {
+		x, w = x+w, -w
+	}
+	if h < 0 {
+		y, h = y+h, -h
+	}
+	return BoundingBox{X: x, Y: y, Width: w, Height: h}
+}
+
+// Area 面积
+func (r *RectMark) Area() float64 {
+	return r.BoundingBox().Area()
+}
+
+// Contains 点是否在矩形内
+func (r *RectMark) Contains(p Point) bool {
+	return r.BoundingBox().Contains(p)
+}
+
+// Polygon 矩形的四个顶点
+func (r *RectMark) Polygon() []Point {
+	return r.BoundingBox().Polygon()
+}
+
+// CircleMark 圆形标记，(X, Y) 为圆心
+type CircleMark struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+	MarkLabel
+}
+
+// BoundingBox 外接矩形
+func (c *CircleMark) BoundingBox() BoundingBox {
+	r := math.Abs(c.Radius)
+	return BoundingBox{X: c.X - r, Y: c.Y - r, Width: 2 * r, Height: 2 * r}
+}
+
+// Area 面积
+func (c *CircleMark) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+// Contains 点是否在圆内
+func (c *CircleMark) Contains(p Point) bool {
+	return math.Hypot(p.X-c.X, p.Y-c.Y) <= math.Abs(c.Radius)
+}
+
+// Polygon 圆的内接正多边形
+func (c *CircleMark) Polygon() []Point {
+	r := math.Abs(c.Radius)
+	if r == 0 {
+		return nil
+	}
+	points := make([]Point, circleSegments)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / circleSegments
+		points[i] = Point{c.X + r*math.Cos(angle), c.Y + r*math.Sin(angle)}
+	}
+	return points
+}
+
+// PolygonMark 多边形标记
+type PolygonMark struct {
+	Points []Point `json:"points"`
+	MarkLabel
+}
+
+// BoundingBox 外接矩形
+func (p *PolygonMark) BoundingBox() BoundingBox {
+	return pointsBoundingBox(p.Points)
+}
+
+// Area 面积（鞋带公式）
+func (p *PolygonMark) Area() float64 {
+	if len(p.Points) < 3 {
+		return 0
+	}
+	return math.Abs(signedArea(p.Points))
+}
+
+// Contains 点是否在多边形内（射线法）
+func (p *PolygonMark) Contains(pt Point) bool {
+	if len(p.Points) < 3 {
+		return false
+	}
+	inside := false
+	for i, j := 0, len(p.Points)-1; i < len(p.Points); j, i = i, i+1 {
+		a, b := p.Points[i], p.Points[j]
+		if (a.Y > pt.Y) != (b.Y > pt.Y) && pt.X < (b.X-a.X)*(pt.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Polygon 多边形顶点
+func (p *PolygonMark) Polygon() []Point {
+	if len(p.Points) < 3 {
+		return nil
+	}
+	return p.Points
+}
+
+// PolylineMark 折线标记，没有面积
+type PolylineMark struct {
+	Points []Point `json:"points"`
+	MarkLabel
+}
+
+// BoundingBox 外接矩形
+func (l *PolylineMark) BoundingBox() BoundingBox {
+	return pointsBoundingBox(l.Points)
+}
+
+// Area 折线没有面积
+func (l *PolylineMark) Area() float64 {
+	return 0
+}
+
+// Contains 点是否落在折线上（容差 lineTolerance）
+func (l *PolylineMark) Contains(p Point) bool {
+	for i := 1; i < len(l.Points); i++ {
+		if segmentDistance(p, l.Points[i-1], l.Points[i]) <= lineTolerance {
+			return true
+		}
+	}
+	return len(l.Points) == 1 && math.Hypot(p.X-l.Points[0].X, p.Y-l.Points[0].Y) <= lineTolerance
+}
+
+// Polygon 折线不是封闭区域
+func (l *PolylineMark) Polygon() []Point {
+	return nil
+}
+
+// PointMark 点 / 关键点标记
+type PointMark struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	MarkLabel
+}
+
+// BoundingBox 退化为一个点的外接矩形
+func (p *PointMark) BoundingBox() BoundingBox {
+	return BoundingBox{X: p.X, Y: p.Y}
+}
+
+// Area 点没有面积
+func (p *PointMark) Area() float64 {
+	return 0
+}
+
+// Contains 点是否与该点重合（容差 lineTolerance）
+func (p *PointMark) Contains(pt Point) bool {
+	return math.Hypot(pt.X-p.X, pt.Y-p.Y) <= lineTolerance
+}
+
+// Polygon 点不是封闭区域
+func (p *PointMark) Polygon() []Point {
+	return nil
+}
+
+// IoU 计算两个形状的交并比
+// 至少一方为凸多边形（矩形、圆形、凸多边形）时直接按多边形裁剪计算，
+// 两个凹多边形时将其中一个剖分为三角形后逐个裁剪累加交集面积。
+// 自相交的多边形无法剖分，此时退化为外接矩形的交并比；没有面积的形状交并比为 0
+func IoU(a, b Shape) float64 {
+	pa, pb := a.Polygon(), b.Polygon()
+	if len(pa) < 3 || len(pb) < 3 {
+		return 0
+	}
+
+	var inter float64
+	switch {
+	case isConvex(pb):
+		inter = clippedArea(pa, pb)
+	case isConvex(pa):
+		inter = clippedArea(pb, pa)
+	default:
+		triangles := triangulate(pb)
+		if triangles == nil {
+			return a.BoundingBox().IoU(b.BoundingBox())
+		}
+		for _, triangle := range triangles {
+			inter += clippedArea(pa, triangle)
+		}
+	}
+
+	areaA, areaB := math.Abs(signedArea(pa)), math.Abs(signedArea(pb))
+	union := areaA + areaB - inter
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
+
+// pointsBoundingBox 点集的外接矩形
+func pointsBoundingBox(points []Point) BoundingBox {
+	if len(points) == 0 {
+		return BoundingBox{}
+	}
+	minX, minY, maxX, maxY := points[0].X, points[0].Y, points[0].X, points[0].Y
+	for _, p := range points[1:] {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return BoundingBox{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
+
+// signedArea 多边形的有向面积，逆时针为正
+func signedArea(points []Point) float64 {
+	sum := 0.0
+	for i := range points {
+		j := (i + 1) % len(points)
+		sum += points[i].X*points[j].Y - points[j].X*points[i].Y
+	}
+	return sum / 2
+}
+
+// cross 向量 ab 与 ac 的叉积
+func cross(a, b, c Point) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// isConvex 判断多边形是否为凸多边形
+func isConvex(points []Point) bool {
+	sign := 0.0
+	for i := range points {
+		c := cross(points[i], points[(i+1)%len(points)], points[(i+2)%len(points)])
+		if c == 0 {
+			continue
+		}
+		if sign == 0 {
+			sign = c
+		} else if (c > 0) != (sign > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// clipPolygon 用凸多边形 clip 裁剪 subject（Sutherland–Hodgman），返回交集多边形
+func clipPolygon(subject, clip []Point) []Point {
+	orientation := 1.0
+	if signedArea(clip) < 0 {
+		orientation = -1
+	}
+	inside := func(p, a, b Point) bool {
+		return cross(a, b, p)*orientation >= 0
+	}
+	intersection := func(p, q, a, b Point) Point {
+		d1, d2 := cross(a, b, p), cross(a, b, q)
+		t := d1 / (d1 - d2)
+		return Point{p.X + t*(q.X-p.X), p.Y + t*(q.Y-p.Y)}
+	}
+
+	output := subject
+	for i := range clip {
+		a, b := clip[i], clip[(i+1)%len(clip)]
+		input := output
+		output = make([]Point, 0, len(input)+1)
+		for j := range input {
+			cur, prev := input[j], input[(j+len(input)-1)%len(input)]
+			curIn, prevIn := inside(cur, a, b), inside(prev, a, b)
+			if curIn {
+				if !prevIn {
+					output = append(output, intersection(prev, cur, a, b))
+				}
+				output = append(output, cur)
+			} else if prevIn {
+				output = append(output, intersection(prev, cur, a, b))
+			}
+		}
+		if len(output) == 0 {
+			return nil
+		}
+	}
+	return output
+}
+
+// clippedArea 多边形 subject 被凸多边形 clip 裁剪后的面积，subject 可以是凹多边形
+func clippedArea(subject, clip []Point) float64 {
+	clipped := clipPolygon(subject, clip)
+	if len(clipped) < 3 {
+		return 0
+	}
+	return math.Abs(signedArea(clipped))
+}
+
+// triangulate 用耳切法将简单多边形剖分为三角形，多边形自相交等无法剖分时返回 nil
+func triangulate(points []Point) [][]Point {
+	// 统一为逆时针并去掉共线的顶点
+	ring := make([]Point, 0, len(points))
+	for i := range points {
+		prev, next := points[(i+len(points)-1)%len(points)], points[(i+1)%len(points)]
+		if cross(prev, points[i], next) != 0 {
+			ring = append(ring, points[i])
+		}
+	}
+	if len(ring) < 3 {
+		return nil
+	}
+	if signedArea(ring) < 0 {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+
+	triangles := make([][]Point, 0, len(ring)-2)
+	for len(ring) > 3 {
+		ear := -1
+		for i := range ring {
+			prev, cur, next := ring[(i+len(ring)-1)%len(ring)], ring[i], ring[(i+1)%len(ring)]
+			if cross(prev, cur, next) <= 0 {
+				continue
+			}
+			isEar := true
+			for _, p := range ring {
+				if p != prev && p != cur && p != next && pointInTriangle(p, prev, cur, next) {
+					isEar = false
+					break
+				}
+			}
+			if isEar {
+				ear = i
+				break
+			}
+		}
+		if ear < 0 {
+			return nil
+		}
+		triangles = append(triangles, []Point{ring[(ear+len(ring)-1)%len(ring)], ring[ear], ring[(ear+1)%len(ring)]})
+		ring = append(ring[:ear], ring[ear+1:]...)
+	}
+	return append(triangles, ring)
+}
+
+// pointInTriangle 判断点是否在逆时针三角形 abc 内（含边界）
+func pointInTriangle(p, a, b, c Point) bool {
+	return cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0
+}
+
+// segmentDistance 点到线段的距离
+func segmentDistance(p, a, b Point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+	t := math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/(dx*dx+dy*dy)))
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
+
+// UnmarshalJSON 按 type 将 data 解析为具体的标记类型：
+// rect → *RectMark，circle → *CircleMark，polygon → *PolygonMark，polyline → *PolylineMark，
+// point / keypoint → *PointMark，视频片段（无 type，data 为数组）→ VideoMark
+func (m *MarkData) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	m.Type = raw.Type
+	m.Data = decodeMarkData(raw.Type, raw.Data)
+	m.raw = append([]byte(nil), b...)
+	return nil
+}
+
+// MarshalJSON 序列化 type 和 data，并保留原始 JSON 中前端或其他客户端附带的未知字段。
+// 标记类型声明的字段完全以当前值为准，被清空的字段不会从原始 JSON 中恢复
+func (m MarkData) MarshalJSON() ([]byte, error) {
+	type plain MarkData
+	b, err := json.Marshal(plain(m))
+	if err != nil || len(m.raw) == 0 {
+		return b, err
+	}
+	var base, out map[string]json.RawMessage
+	if json.Unmarshal(m.raw, &base) != nil || json.Unmarshal(b, &out) != nil || base == nil {
+		return b, nil
+	}
+
+	// data 中只保留具体标记类型没有声明的字段，点列表等声明的字段整体替换
+	if declared := jsonFieldNames(m.Data); declared != nil {
+		var baseData, outData map[string]json.RawMessage
+		if json.Unmarshal(base["data"], &baseData) == nil && json.Unmarshal(out["data"], &outData) == nil && outData != nil {
+			carryUnknownFields(outData, baseData, declared)
+			if data, err := json.Marshal(outData); err == nil {
+				out["data"] = data
+			}
+		}
+	}
+	carryUnknownFields(out, base, map[string]bool{"type": true, "data": true})
+	return json.Marshal(out)
+}
+
+// carryUnknownFields 将 src 中没有声明的字段复制到 dst
+func carryUnknownFields(dst, src map[string]json.RawMessage, declared map[string]bool) {
+	for k, v := range src {
+		if declared[k] {
+			continue
+		}
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
+
+// jsonFieldNames 结构体（或其指针）声明的 JSON 字段名，含嵌入结构体的字段；其他类型返回 nil
+func jsonFieldNames(v any) map[string]bool {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	names := make(map[string]bool)
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				collect(field.Type)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			names[name] = true
+		}
+	}
+	collect(t)
+	return names
+}
+
+// decodeMarkData 解析标记数据；未知类型或与类型不符的历史数据保留为原始结构，避免读取旧数据失败
+func decodeMarkData(markType string, raw json.RawMessage) any {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+
+	var target any
+	switch markType {
+	case MarkTypeRect:
+		target = &RectMark{}
+	case MarkTypeCircle:
+		target = &CircleMark{}
+	case MarkTypePolygon:
+		target = &PolygonMark{}
+	case MarkTypePolyline:
+		target = &PolylineMark{}
+	case MarkTypePoint, MarkTypeKeypoint:
+		target = &PointMark{}
+	case "", MarkTypeVideo:
+		if raw[0] == '[' {
+			var segments VideoMark
+			if json.Unmarshal(raw, &segments) == nil {
+				return segments
+			}
+		}
+	}
+	if target != nil && json.Unmarshal(raw, target) == nil {
+		return target
+	}
+
+	var generic any
+	_ = json.Unmarshal(raw, &generic)
+	return generic
+}
+
+// Shape 返回带几何形状的标记数据，视频片段或无法识别的数据返回 false
+func (m MarkData) Shape() (Shape, bool) {
+	shape, ok := m.Data.(Shape)
+	return shape, ok
+}
+
+// Label 返回标记的类别信息，视频片段或无法识别的数据返回 nil
+func (m MarkData) Label() *MarkLabel {
+	if shape, ok := m.Shape(); ok {
+		return shape.Label()
+	}
+	return nil
+}
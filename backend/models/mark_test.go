@@ -0,0 +1,203 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+// lShape 面积为 12 的 L 形凹多边形
+var lShape = []Point{{0, 0}, {4, 0}, {4, 2}, {2, 2}, {2, 4}, {0, 4}}
+
+func TestShapeGeometry(t *testing.T) {
+	tests := []struct {
+		name    string
+		shape   Shape
+		area    float64
+		inside  []Point
+		outside []Point
+	}{
+		{"矩形", &RectMark{X: 1, Y: 1, Width: 2, Height: 3}, 6, []Point{{2, 2}, {1, 1}}, []Point{{0, 0}, {3.5, 2}}},
+		{"反向拖拽的矩形", &RectMark{X: 3, Y: 4, Width: -2, Height: -3}, 6, []Point{{2, 2}}, []Point{{4, 4}}},
+		{"圆形", &CircleMark{X: 0, Y: 0, Radius: 2}, 4 * math.Pi, []Point{{1, 1}, {0, 2}}, []Point{{2, 2}}},
+		{"凸多边形", &PolygonMark{Points: []Point{{0, 0}, {4, 0}, {0, 4}}}, 8, []Point{{1, 1}}, []Point{{3, 3}}},
+		{"凹多边形", &PolygonMark{Points: lShape}, 12, []Point{{1, 3}, {3, 1}}, []Point{{3, 3}, {5, 1}}},
+		{"点数不足的多边形", &PolygonMark{Points: []Point{{0, 0}, {1, 1}}}, 0, nil, []Point{{0, 0}}},
+		{"折线", &PolylineMark{Points: []Point{{0, 0}, {4, 0}}}, 0, []Point{{2, 0}}, []Point{{2, 3}}},
+		{"点", &PointMark{X: 1, Y: 1}, 0, []Point{{1, 1}}, []Point{{5, 5}}},
+	}
+	for _, tt := range tests {
+		if got := tt.shape.Area(); math.Abs(got-tt.area) > epsilon {
+			t.Errorf("%s: 面积为 %v，期望 %v", tt.name, got, tt.area)
+		}
+		for _, p := range tt.inside {
+			if !tt.shape.Contains(p) {
+				t.Errorf("%s: 应包含点 %v", tt.name, p)
+			}
+		}
+		for _, p := range tt.outside {
+			if tt.shape.Contains(p) {
+				t.Errorf("%s: 不应包含点 %v", tt.name, p)
+			}
+		}
+	}
+}
+
+func TestIsConvex(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []Point
+		want   bool
+	}{
+		{"逆时针正方形", []Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, true},
+		{"顺时针正方形", []Point{{0, 0}, {0, 1}, {1, 1}, {1, 0}}, true},
+		{"含共线顶点", []Point{{0, 0}, {1, 0}, {2, 0}, {2, 2}, {0, 2}}, true},
+		{"L 形", lShape, false},
+	}
+	for _, tt := range tests {
+		if got := isConvex(tt.points); got != tt.want {
+			t.Errorf("%s: isConvex 为 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClipPolygon(t *testing.T) {
+	square := []Point{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	tests := []struct {
+		name    string
+		subject []Point
+		clip    []Point
+		area    float64
+	}{
+		{"部分重叠", []Point{{1, 1}, {3, 1}, {3, 3}, {1, 3}}, square, 1},
+		{"完全包含", []Point{{0.5, 0.5}, {1.5, 0.5}, {1.5, 1.5}, {0.5, 1.5}}, square, 1},
+		{"不相交", []Point{{5, 5}, {6, 5}, {6, 6}}, square, 0},
+		{"顺时针裁剪多边形", []Point{{1, 1}, {3, 1}, {3, 3}, {1, 3}}, []Point{{0, 0}, {0, 2}, {2, 2}, {2, 0}}, 1},
+		{"凹多边形被裁剪", lShape, []Point{{1, 1}, {3, 1}, {3, 3}, {1, 3}}, 3},
+	}
+	for _, tt := range tests {
+		if got := clippedArea(tt.subject, tt.clip); math.Abs(got-tt.area) > epsilon {
+			t.Errorf("%s: 裁剪后面积为 %v，期望 %v", tt.name, got, tt.area)
+		}
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	for _, points := range [][]Point{lShape, reversed(lShape), {{0, 0}, {2, 0}, {4, 0}, {4, 4}, {0, 4}}} {
+		triangles := triangulate(points)
+		if len(triangles) == 0 {
+			t.Fatalf("%v 应能剖分", points)
+		}
+		sum := 0.0
+		for _, triangle := range triangles {
+			sum += math.Abs(signedArea(triangle))
+		}
+		if want := math.Abs(signedArea(points)); math.Abs(sum-want) > epsilon {
+			t.Errorf("%v 剖分后面积为 %v，期望 %v", points, sum, want)
+		}
+	}
+}
+
+func TestIoU(t *testing.T) {
+	// L 形旋转 180° 后与原 L 形的交集为两个 2×2 的正方形，外接矩形完全重合
+	rotated := []Point{{4, 4}, {0, 4}, {0, 2}, {2, 2}, {2, 0}, {4, 0}}
+	tests := []struct {
+		name string
+		a, b Shape
+		want float64
+	}{
+		{"相同矩形", &RectMark{X: 0, Y: 0, Width: 2, Height: 2}, &RectMark{X: 0, Y: 0, Width: 2, Height: 2}, 1},
+		{"半重叠矩形", &RectMark{X: 0, Y: 0, Width: 2, Height: 2}, &RectMark{X: 1, Y: 0, Width: 2, Height: 2}, 1.0 / 3},
+		{"不相交矩形", &RectMark{X: 0, Y: 0, Width: 1, Height: 1}, &RectMark{X: 5, Y: 5, Width: 1, Height: 1}, 0},
+		{"相同圆形", &CircleMark{X: 1, Y: 1, Radius: 3}, &CircleMark{X: 1, Y: 1, Radius: 3}, 1},
+		{"不相交圆形", &CircleMark{X: 0, Y: 0, Radius: 1}, &CircleMark{X: 5, Y: 0, Radius: 1}, 0},
+		{"凸多边形与矩形", &PolygonMark{Points: []Point{{0, 0}, {2, 0}, {0, 2}}}, &RectMark{X: 0, Y: 0, Width: 2, Height: 2}, 0.5},
+		{"凹多边形与矩形", &PolygonMark{Points: lShape}, &RectMark{X: 0, Y: 0, Width: 2, Height: 2}, 1.0 / 3},
+		{"矩形与凹多边形", &RectMark{X: 0, Y: 0, Width: 2, Height: 2}, &PolygonMark{Points: lShape}, 1.0 / 3},
+		{"相同凹多边形", &PolygonMark{Points: lShape}, &PolygonMark{Points: reversed(lShape)}, 1},
+		{"两个凹多边形", &PolygonMark{Points: lShape}, &PolygonMark{Points: rotated}, 0.5},
+		{"不相交凹多边形", &PolygonMark{Points: lShape}, &PolygonMark{Points: translate(lShape, 10, 0)}, 0},
+		{"折线没有面积", &PolylineMark{Points: []Point{{0, 0}, {2, 2}}}, &RectMark{X: 0, Y: 0, Width: 2, Height: 2}, 0},
+	}
+	for _, tt := range tests {
+		if got := IoU(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: IoU 为 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkDataRoundTrip(t *testing.T) {
+	raw := `{"type":"rect","meta":{"source":"legacy"},"data":{"x":1,"y":2,"width":3,"height":4,"color":"red","text":"car","class":"car","extra":[1,2]}}`
+	var m MarkData
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatal(err)
+	}
+	rect, ok := m.Data.(*RectMark)
+	if !ok {
+		t.Fatalf("data 类型为 %T，期望 *RectMark", m.Data)
+	}
+	rect.Class = ""
+	rect.Width = 5
+
+	out := marshalMap(t, m)
+	data := out["data"].(map[string]any)
+	if _, ok := data["class"]; ok {
+		t.Error("已清空的 class 不应从原始数据中恢复")
+	}
+	if data["width"] != 5.0 {
+		t.Errorf("width 为 %v，期望 5", data["width"])
+	}
+	if _, ok := data["extra"]; !ok {
+		t.Error("data 中的未知字段应保留")
+	}
+	if _, ok := out["meta"]; !ok {
+		t.Error("顶层的未知字段应保留")
+	}
+
+	// 声明的字段整体替换，数组元素中的旧字段不会保留
+	raw = `{"type":"polygon","data":{"points":[{"x":0,"y":0,"z":9},{"x":1,"y":0,"z":9},{"x":1,"y":1,"z":9}],"color":"","text":""}}`
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatal(err)
+	}
+	m.Data.(*PolygonMark).Points = m.Data.(*PolygonMark).Points[:2]
+	points := marshalMap(t, m)["data"].(map[string]any)["points"].([]any)
+	if len(points) != 2 {
+		t.Fatalf("points 数量为 %d，期望 2", len(points))
+	}
+	for _, p := range points {
+		if _, ok := p.(map[string]any)["z"]; ok {
+			t.Error("点中的旧字段不应保留")
+		}
+	}
+}
+
+func marshalMap(t *testing.T, m MarkData) map[string]any {
+	t.Helper()
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func reversed(points []Point) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[len(points)-1-i] = p
+	}
+	return out
+}
+
+func translate(points []Point, dx, dy float64) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[i] = Point{p.X + dx, p.Y + dy}
+	}
+	return out
+}
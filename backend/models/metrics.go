@@ -0,0 +1,67 @@
+package models
+
+// 对照标注的来源
+const (
+	MetricsReferenceTask      = "task"      // 另一个任务的标注
+	MetricsReferenceConsensus = "consensus" // 重叠标注的合并结果
+)
+
+// DetectionMetrics 按交并比匹配有面积的标记后的检测指标
+type DetectionMetrics struct {
+	TruePositive  int     `json:"truePositive"`
+	FalsePositive int     `json:"falsePositive"` // 被评估方多标的标记
+	FalseNegative int     `json:"falseNegative"` // 被评估方漏标的标记
+	Precision     float64 `json:"precision"`
+	Recall        float64 `json:"recall"`
+	F1            float64 `json:"f1"`
+}
+
+// TemporalMetrics 视频片段的时间重叠指标
+type TemporalMetrics struct {
+	ReferenceSegments int     `json:"referenceSegments"`
+	CandidateSegments int     `json:"candidateSegments"`
+	Matched           int     `json:"matched"` // 时间交并比达到阈值的片段对
+	MeanIoU           float64 `json:"meanIoU"` // 一对一匹配的平均时间交并比，未匹配的片段计为 0
+}
+
+// QualityMetrics 两组标注的比较结果
+type QualityMetrics struct {
+	Keys        int              `json:"keys"` // 参与比较的条目数
+	Detection   DetectionMetrics `json:"detection"`
+	CohenKappa  *float64         `json:"cohenKappa"`            // 匹配上的标记的类别一致性，没有匹配时为空
+	FleissKappa *float64         `json:"fleissKappa,omitempty"` // 多名标注员的类别一致性，只在包的统计中给出
+	Temporal    TemporalMetrics  `json:"temporal"`
+}
+
+// TaskMetricsReq 任务指标请求
+type TaskMetricsReq struct {
+	ReferenceTaskID int64 `form:"reference_task_id"` // 对照任务，不指定时与包的合并结果比较
+}
+
+// TaskMetricsResponse 任务指标响应
+type TaskMetricsResponse struct {
+	TaskID          int64          `json:"taskId"`
+	Reference       string         `json:"reference"`
+	ReferenceTaskID int64          `json:"referenceTaskId,omitempty"`
+	Metrics         QualityMetrics `json:"metrics"`
+}
+
+// AnnotatorMetricsReq 标注员指标请求
+type AnnotatorMetricsReq struct {
+	PackageID int64 `form:"package_id"` // 只统计该包内的任务
+}
+
+// AnnotatorMetricsResponse 标注员指标响应，汇总其所有重叠标注任务与合并结果的比较
+type AnnotatorMetricsResponse struct {
+	UserID  int64          `json:"userId"`
+	Tasks   int            `json:"tasks"`
+	Metrics QualityMetrics `json:"metrics"`
+}
+
+// PackageMetricsResponse 包指标响应，汇总各分片副本之间的两两比较
+type PackageMetricsResponse struct {
+	PackageID int64          `json:"packageId"`
+	Chunks    int            `json:"chunks"` // 参与统计的分片数
+	Pairs     int            `json:"pairs"`  // 比较的副本对数
+	Metrics   QualityMetrics `json:"metrics"`
+}
@@ -14,40 +14,73 @@ const (
 
 // Package 包模型
 type Package struct {
-	ID        int64         `xorm:"pk autoincr 'id'" json:"id"`
-	BucketID  int64         `xorm:"'bucket_id' not null" json:"bucketId"`
-	Name      string        `xorm:"varchar(100) not null 'name'" json:"name"`
-	Items     string        `xorm:"text 'items'" json:"items"` // JSON 数组存储
-	Status    PackageStatus `xorm:"varchar(20) 'status'" json:"status"`
-	CreatedAt time.Time     `xorm:"created 'created_at'" json:"created_at"`
-	UpdatedAt time.Time     `xorm:"updated 'updated_at'" json:"updated_at"`
+	ID                 int64         `xorm:"pk autoincr 'id'" json:"id"`
+	BucketID           int64         `xorm:"'bucket_id' not null" json:"bucketId"`
+	Name               string        `xorm:"varchar(100) not null 'name'" json:"name"`
+	Items              string        `xorm:"text 'items'" json:"items"` // JSON 数组存储
+	Status             PackageStatus `xorm:"varchar(20) 'status'" json:"status"`
+	Schema             *LabelSchema  `xorm:"json 'label_schema'" json:"labelSchema,omitempty"`           // 标注规范，可选
+	Overlap            int           `xorm:"'overlap' default(1)" json:"overlap"`                        // 每个条目由几名标注员独立标注
+	AgreementThreshold float64       `xorm:"'agreement_threshold' default(0)" json:"agreementThreshold"` // 一致性低于该值的条目需要审核员裁决，0 表示使用默认值
+	GoldThreshold      float64       `xorm:"'gold_threshold' default(0)" json:"goldThreshold"`           // 金标准准确率低于该值时按 GoldAction 处理，0 表示不处理
+	GoldAction         string        `xorm:"varchar(20) 'gold_action'" json:"goldAction"`                // flag / reject
+	SkillTags          []string      `xorm:"json 'skill_tags'" json:"skillTags"`                         // 领取该包的任务需要具备的技能标签
+	CreatedAt          time.Time     `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt          time.Time     `xorm:"updated 'updated_at'" json:"updated_at"`
 }
 
 // PackageReq 创建/更新包请求
 type PackageReq struct {
-	ID       *int64   `json:"id,omitempty"`
-	BucketID int64    `json:"bucketId" binding:"required"`
-	Name     string   `json:"name" binding:"required"`
-	Items    []string `json:"items" binding:"required"`
+	ID                 *int64       `json:"id,omitempty"`
+	BucketID           int64        `json:"bucketId" binding:"required"`
+	Name               string       `json:"name" binding:"required"`
+	Items              []string     `json:"items" binding:"required"`
+	Schema             *LabelSchema `json:"labelSchema"`
+	Overlap            int          `json:"overlap" binding:"omitempty,min=1,max=10"`
+	AgreementThreshold float64      `json:"agreementThreshold" binding:"omitempty,min=0,max=1"`
+	SkillTags          []string     `json:"skillTags"`
 }
 
 // PackageResponse 包响应
 type PackageResponse struct {
-	ID        int64         `json:"id"`
-	BucketID  int64         `json:"bucketId"`
-	Name      string        `json:"name"`
-	Items     []string      `json:"items"`
-	Status    PackageStatus `json:"status"`
-	CreatedAt time.Time     `json:"created_at"`
+	ID                 int64            `json:"id"`
+	BucketID           int64            `json:"bucketId"`
+	Name               string           `json:"name"`
+	Items              []string         `json:"items"`
+	Status             PackageStatus    `json:"status"`
+	Schema             *LabelSchema     `json:"labelSchema,omitempty"`
+	Overlap            int              `json:"overlap"`
+	AgreementThreshold float64          `json:"agreementThreshold"`
+	SkillTags          []string         `json:"skillTags"`
+	Progress           *PackageProgress `json:"progress,omitempty"` // 发布后汇总各任务进度
+	CreatedAt          time.Time        `json:"created_at"`
 }
 
 // PackageItem 包列表项（不包含 items）
 type PackageItem struct {
-	ID        int64         `json:"id"`
-	BucketID  int64         `json:"bucketId"`
-	Name      string        `json:"name"`
-	Status    PackageStatus `json:"status"`
-	CreatedAt time.Time     `json:"created_at"`
+	ID        int64            `json:"id"`
+	BucketID  int64            `json:"bucketId"`
+	Name      string           `json:"name"`
+	Status    PackageStatus    `json:"status"`
+	Progress  *PackageProgress `json:"progress,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// PublishPackageReq 发布包请求，chunkSize 和 taskCount 都不填时整个包作为一个任务
+type PublishPackageReq struct {
+	ChunkSize int        `json:"chunkSize" binding:"omitempty,min=1"` // 每个任务的条目数
+	TaskCount int        `json:"taskCount" binding:"omitempty,min=1"` // 拆分的任务数
+	Priority  int        `json:"priority"`                            // 创建的任务的优先级
+	DueAt     *time.Time `json:"dueAt"`                               // 创建的任务的截止时间
+}
+
+// PackageProgress 包的整体进度，由各任务汇总
+type PackageProgress struct {
+	TaskCount         int                `json:"taskCount"`
+	TaskStatus        map[TaskStatus]int `json:"taskStatus"`        // 各状态的任务数
+	ItemCount         int                `json:"itemCount"`         // 包的条目总数
+	AnnotatedCount    int64              `json:"annotatedCount"`    // 已保存标注的条目数
+	ApprovedItemCount int                `json:"approvedItemCount"` // 审核通过任务中的条目数
 }
 
 // PackageListRequest 包列表请求
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// TaskSlaAlert 已发送的截止时间提醒，每个任务的每个阈值只提醒一次
+type TaskSlaAlert struct {
+	ID        int64     `xorm:"pk autoincr 'id'" json:"id"`
+	TaskID    int64     `xorm:"unique(task_threshold) 'task_id'" json:"taskId"`
+	Threshold int64     `xorm:"unique(task_threshold) 'threshold'" json:"threshold"` // 相对截止时间的秒数，负数为截止前
+	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
+}
+
+// TaskPriorityRequest 管理员设置任务的优先级和截止时间，DueAt 为空时清除截止时间
+type TaskPriorityRequest struct {
+	TaskID   int64      `json:"task_id" binding:"required"`
+	Priority int        `json:"priority"`
+	DueAt    *time.Time `json:"dueAt"`
+}
+
+// PackageSLARequest SLA 达成率请求，不指定包时统计所有设置了截止时间的包
+type PackageSLARequest struct {
+	PackageID int64 `form:"package_id"`
+}
+
+// PackageSLA 包的 SLA 达成情况，只统计设置了截止时间的任务
+type PackageSLA struct {
+	PackageID       int64   `json:"packageId"`
+	PackageName     string  `json:"packageName"`
+	Tasks           int     `json:"tasks"`           // 设置了截止时间的任务数
+	Completed       int     `json:"completed"`       // 已通过审核
+	CompletedOnTime int     `json:"completedOnTime"` // 在截止时间前通过审核
+	Overdue         int     `json:"overdue"`         // 已过截止时间仍未完成
+	Pending         int     `json:"pending"`         // 未到截止时间且未完成
+	Compliance      float64 `json:"compliance"`      // 按时完成数 / (已完成数 + 逾期未完成数)，没有可统计的任务时为 1
+}
+
+// PackageSLAResponse SLA 达成率响应
+type PackageSLAResponse struct {
+	List []PackageSLA `json:"list"`
+}
@@ -18,48 +18,99 @@ const (
 
 // Task 任务模型
 type Task struct {
-	ID        int64      `xorm:"pk autoincr 'id'" json:"id"`
-	Name      string     `xorm:"varchar(100) not null 'name'" json:"name"`
-	PackageID int64      `xorm:"'package_id' not null" json:"packageId"`
-	Annotator int64      `xorm:"'annotator'" json:"annotator"` // 标注员用户ID
-	Reviewer  int64      `xorm:"'reviewer'" json:"reviewer"`   // 审核员用户ID
-	Status    TaskStatus `xorm:"varchar(20) 'status'" json:"status"`
-	WipIdx    int        `xorm:"'wip_idx' default(0)" json:"wipIdx"`
-	CreatedAt time.Time  `xorm:"created 'created_at'" json:"created_at"`
-	UpdatedAt time.Time  `xorm:"updated 'updated_at'" json:"updated_at"`
+	ID             int64      `xorm:"pk autoincr 'id'" json:"id"`
+	Name           string     `xorm:"varchar(100) not null 'name'" json:"name"`
+	PackageID      int64      `xorm:"'package_id' not null" json:"packageId"`
+	Annotator      int64      `xorm:"'annotator'" json:"annotator"` // 标注员用户ID
+	Reviewer       int64      `xorm:"'reviewer'" json:"reviewer"`   // 审核员用户ID
+	Status         TaskStatus `xorm:"varchar(20) 'status'" json:"status"`
+	WipIdx         int        `xorm:"'wip_idx' default(0)" json:"wipIdx"`
+	ItemStart      int        `xorm:"'item_start' default(0)" json:"itemStart"`                 // 任务负责的包 items 区间 [ItemStart, ItemEnd)
+	ItemEnd        int        `xorm:"'item_end' default(0)" json:"itemEnd"`                     // 为 0 时表示整个包
+	ChunkIdx       int        `xorm:"'chunk_idx' default(0)" json:"chunkIdx"`                   // 拆分发布时的分片序号
+	Replica        int        `xorm:"'replica' default(0)" json:"replica"`                      // 重叠标注时同一分片的副本序号（从 1 开始），0 表示无重叠
+	Flagged        bool       `xorm:"index 'flagged' default(0)" json:"flagged"`                // 金标准准确率过低，需要重点审核
+	ReviewRound    int        `xorm:"'review_round' default(0)" json:"reviewRound"`             // 已完成的审核轮数
+	Priority       int        `xorm:"index 'priority' default(0)" json:"priority"`              // 数值越大越先被领取
+	DueAt          *time.Time `xorm:"index 'due_at'" json:"dueAt,omitempty"`                    // 截止时间，优先级相同时先到期的先被领取
+	CompletedAt    *time.Time `xorm:"'completed_at'" json:"completedAt,omitempty"`              // 通过审核的时间，用于统计 SLA
+	LeaseExpiresAt *time.Time `xorm:"index 'lease_expires_at'" json:"leaseExpiresAt,omitempty"` // 领取租约到期时间，标注或审核期间的操作会续期
+	CreatedAt      time.Time  `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt      time.Time  `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+// ItemRange 任务在包 items 中负责的区间，total 为包的条目数
+func (t *Task) ItemRange(total int) (int, int) {
+	if t.ItemEnd <= 0 {
+		return 0, total
+	}
+	start, end := t.ItemStart, t.ItemEnd
+	if end > total {
+		end = total
+	}
+	if start > end {
+		start = end
+	}
+	return start, end
 }
 
 // TaskResponse 任务响应
 type TaskResponse struct {
-	ID        int64      `json:"id"`
-	Name      string     `json:"name"`
-	PackageID int64      `json:"packageId"`
-	Annotator int64      `json:"annotator"`
-	Reviewer  int64      `json:"reviewer"`
-	Status    TaskStatus `json:"status"`
-	WipIdx    int        `json:"wipIdx"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID             int64      `json:"id"`
+	Name           string     `json:"name"`
+	PackageID      int64      `json:"packageId"`
+	Annotator      int64      `json:"annotator"`
+	Reviewer       int64      `json:"reviewer"`
+	Status         TaskStatus `json:"status"`
+	WipIdx         int        `json:"wipIdx"`
+	ItemStart      int        `json:"itemStart"`
+	ItemEnd        int        `json:"itemEnd"`
+	ChunkIdx       int        `json:"chunkIdx"`
+	Replica        int        `json:"replica"`
+	Flagged        bool       `json:"flagged"`
+	ReviewRound    int        `json:"reviewRound"`
+	Priority       int        `json:"priority"`
+	DueAt          *time.Time `json:"dueAt,omitempty"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 // TaskDetailResponse 任务详情响应（包含items）
 type TaskDetailResponse struct {
-	ID        int64      `json:"id"`
-	Name      string     `json:"name"`
-	PackageID int64      `json:"packageId"`
-	Annotator int64      `json:"annotator"`
-	Reviewer  int64      `json:"reviewer"`
-	WipIdx    int        `json:"wipIdx"`
-	Status    TaskStatus `json:"status"`
-	Items     []string   `json:"items"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID             int64        `json:"id"`
+	Name           string       `json:"name"`
+	PackageID      int64        `json:"packageId"`
+	Annotator      int64        `json:"annotator"`
+	Reviewer       int64        `json:"reviewer"`
+	WipIdx         int          `json:"wipIdx"`
+	Status         TaskStatus   `json:"status"`
+	Items          []string     `json:"items"`     // 仅包含该任务负责的条目
+	ItemStart      int          `json:"itemStart"` // items[0] 在包中的下标
+	ReviewRound    int          `json:"reviewRound"`
+	LeaseExpiresAt *time.Time   `json:"leaseExpiresAt,omitempty"` // 领取租约到期时间
+	ReworkItems    []ReworkItem `json:"reworkItems"`              // 返工时需要重新标注的条目
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// TaskItemURLResponse 任务条目的限时下载地址
+type TaskItemURLResponse struct {
+	Key       string    `json:"key"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 // TaskListRequest 任务列表请求
 type TaskListRequest struct {
-	UserID   int64      `form:"user_id"`
-	Status   TaskStatus `form:"status"`
-	Page     int        `form:"page" binding:"required,min=1"`
-	PageSize int        `form:"page_size" binding:"required,min=1,max=100"`
+	UserID      int64      `form:"user_id"`
+	Status      TaskStatus `form:"status"`
+	PackageID   int64      `form:"package_id"`
+	MinPriority *int       `form:"min_priority"`                                                    // 只返回优先级不低于该值的任务
+	Overdue     bool       `form:"overdue"`                                                         // 只返回已过截止时间且未完成的任务
+	SortBy      string     `form:"sort_by" binding:"omitempty,oneof=id priority due_at created_at"` // 默认按 id
+	Order       string     `form:"order" binding:"omitempty,oneof=asc desc"`                        // 默认升序
+	Page        int        `form:"page" binding:"required,min=1"`
+	PageSize    int        `form:"page_size" binding:"required,min=1,max=100"`
 }
 
 // TaskListResponse 任务列表响应
@@ -89,12 +140,33 @@ type TaskClaimRequest struct {
 	TaskID int64 `json:"task_id" binding:"required"`
 }
 
-// MarkData 标记数据
+// TaskReworkRequest 被驳回的任务返工请求
+type TaskReworkRequest struct {
+	TaskID int64 `json:"task_id" binding:"required"`
+}
+
+// ReworkItem 需要返工的条目及其审核意见
+type ReworkItem struct {
+	Key    string      `json:"key"`
+	Review *ReviewInfo `json:"review,omitempty"`
+}
+
+// TaskReworkResponse 返工响应
+type TaskReworkResponse struct {
+	Task  *TaskResponse `json:"task"`
+	Items []ReworkItem  `json:"items"`
+}
+
+// MarkData 标记数据，Data 按 Type 解析为具体类型，见 mark.go
 type MarkData struct {
-	Type string `json:"type" binding:"required"` // "rect", "circle", "polygon"
+	Type string `json:"type" binding:"required"` // rect / circle / polygon / polyline / point / keypoint，视频片段为空
 	Data any    `json:"data" binding:"required"`
+	raw  []byte // 解析前的原始 JSON，序列化时保留其中未知的字段
 }
 
+// ReviewPassScore 审核分数达到该值的条目视为通过，返工时无需重新标注
+const ReviewPassScore = 3
+
 // ReviewInfo 审核信息
 type ReviewInfo struct {
 	Score      int    `json:"score"`      // 0-5
@@ -103,18 +175,27 @@ type ReviewInfo struct {
 	ReviewedAt string `json:"reviewedAt"` // 审核时间
 }
 
+// 标注来源
+const (
+	AnnotationSourceManual = "manual" // 标注员保存
+	AnnotationSourceImport = "import" // 从 COCO / YOLO 导入的预标注
+)
+
 // SavedAnnotation 保存的标注数据
 type SavedAnnotation struct {
 	ID     int64  `xorm:"pk autoincr 'id'" json:"id,omitempty"`
-	TaskID int64  `xorm:"'task_id' not null" json:"taskId" binding:"required"`
-	Key    string `xorm:"varchar(500) 'key' not null" json:"key" binding:"required"` // S3 object key
+	TaskID int64  `xorm:"unique(task_key) 'task_id' not null" json:"taskId" binding:"required"`
+	Key    string `xorm:"varchar(500) unique(task_key) 'key' not null" json:"key" binding:"required"` // S3 object key
 	Meta   struct {
 		BucketID int64      `json:"bucketId" binding:"required"`
 		Marks    []MarkData `json:"marks" binding:"required"`
 	} `xorm:"json 'meta'" json:"meta" binding:"required"`
-	Review    *ReviewInfo `xorm:"json 'review'" json:"review,omitempty"` // 审核信息，可选
-	CreatedAt time.Time   `xorm:"created 'created_at'" json:"created_at"`
-	UpdatedAt time.Time   `xorm:"updated 'updated_at'" json:"updated_at"`
+	Review      *ReviewInfo `xorm:"json 'review'" json:"review,omitempty"`              // 审核信息，可选
+	Source      string      `xorm:"varchar(20) 'source'" json:"source,omitempty"`       // 标注来源：manual / import
+	NeedsRework bool        `xorm:"index 'needs_rework' default(0)" json:"needsRework"` // 任务返工时需要重新标注，保存后清除
+	Version     int         `xorm:"version 'version'" json:"version"`                   // 乐观锁版本号，每次更新加 1，对应 ETag
+	CreatedAt   time.Time   `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt   time.Time   `xorm:"updated 'updated_at'" json:"updated_at"`
 }
 
 // SavedAnnotationRequest 保存标注请求
@@ -133,3 +214,12 @@ type ReviewAnnotationReq struct {
 	Score        int    `json:"score" binding:"required,min=0,max=5"`
 	Comment      string `json:"comment"`
 }
+
+// AnnotationImportResult 导入预标注结果
+type AnnotationImportResult struct {
+	Imported  []string `json:"imported"`  // 成功导入的 key
+	Unmatched []string `json:"unmatched"` // 无法匹配到任务条目的文件
+	Conflicts []string `json:"conflicts"` // 已存在标注而未覆盖的 key
+	Invalid   []string `json:"invalid"`   // 无法解析的文件或行
+	Failed    []string `json:"failed"`    // 读取失败的文件及原因，不影响其他文件导入
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// 批量操作类型
+const (
+	TaskBatchAssign   = "assign"   // 分配给指定用户
+	TaskBatchUnassign = "unassign" // 取消分配，任务回到待领取
+	TaskBatchStatus   = "status"   // 修改状态
+	TaskBatchPriority = "priority" // 修改优先级和截止时间
+)
+
+// TaskBatchMaxTasks 一次批量操作最多涉及的任务数
+const TaskBatchMaxTasks = 500
+
+// TaskBatchFilter 批量操作的任务筛选条件，与任务ID列表同时指定时取交集
+type TaskBatchFilter struct {
+	PackageID int64      `json:"packageId"`
+	Status    TaskStatus `json:"status"`
+	Assignee  int64      `json:"assignee"` // 标注员或审核员
+}
+
+// TaskBatchRequest 批量操作请求
+type TaskBatchRequest struct {
+	Action     string           `json:"action" binding:"required,oneof=assign unassign status priority"`
+	TaskIDs    []int64          `json:"task_ids"`
+	Filter     *TaskBatchFilter `json:"filter"`
+	UserID     int64            `json:"user_id"`    // assign 的目标用户
+	Status     TaskStatus       `json:"status"`     // status 的目标状态
+	Priority   *int             `json:"priority"`   // priority 的优先级，为空时保留任务原有的优先级
+	DueAt      *time.Time       `json:"dueAt"`      // priority 的截止时间，为空时保留任务原有的截止时间
+	ClearDueAt bool             `json:"clearDueAt"` // priority 时清除截止时间，不能与 dueAt 同时指定
+}
+
+// TaskBatchResult 单个任务的操作结果
+type TaskBatchResult struct {
+	TaskID  int64         `json:"taskId"`
+	Success bool          `json:"success"`
+	Error   string        `json:"error,omitempty"`
+	Task    *TaskResponse `json:"task,omitempty"`
+}
+
+// TaskBatchResponse 批量操作响应，任一任务失败时整批回滚，Committed 为 false，
+// 此时 Success 为 true 的任务表示操作本身可以执行但已随整批回滚
+type TaskBatchResponse struct {
+	Committed bool              `json:"committed"`
+	Results   []TaskBatchResult `json:"results"`
+}
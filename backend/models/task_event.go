@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// 任务事件类型
+const (
+	TaskEventClaim      = "claim"      // 用户自行领取
+	TaskEventAssign     = "assign"     // 管理员分配
+	TaskEventRelease    = "release"    // 租约过期自动释放
+	TaskEventPriority   = "priority"   // 修改优先级或截止时间
+	TaskEventStatus     = "status"     // 状态变更
+	TaskEventWip        = "wip"        // 标注/审核进度变更
+	TaskEventAnnotation = "annotation" // 保存、导入或恢复标注
+	TaskEventReview     = "review"     // 审核标注
+)
+
+// TaskEvent 任务事件，只追加不修改，记录任务的每一次变更
+type TaskEvent struct {
+	ID        int64     `xorm:"pk autoincr 'id'" json:"id"`
+	TaskID    int64     `xorm:"index not null 'task_id'" json:"taskId"`
+	Type      string    `xorm:"varchar(20) not null 'type'" json:"type"`
+	Action    string    `xorm:"varchar(20) 'action'" json:"action,omitempty"` // 触发状态变更的操作，见状态机
+	ActorID   int64     `xorm:"'actor_id'" json:"actorId"`                    // 操作人，系统触发时为相关用户
+	ActorRole string    `xorm:"varchar(20) 'actor_role'" json:"actorRole"`
+	Key       string    `xorm:"varchar(500) 'key'" json:"key,omitempty"` // 标注和审核事件对应的条目
+	Field     string    `xorm:"varchar(20) 'field'" json:"field"`        // 变更的字段：annotator / reviewer / status / wip_idx / revision / score
+	OldValue  string    `xorm:"varchar(500) 'old_value'" json:"oldValue"`
+	NewValue  string    `xorm:"varchar(500) 'new_value'" json:"newValue"`
+	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
+}
+
+// TaskTimelineItem 时间线中的事件，附带操作人用户名
+type TaskTimelineItem struct {
+	TaskEvent
+	ActorName string `json:"actorName"`
+}
+
+// TaskTimelineResponse 任务时间线响应，按时间先后排列
+type TaskTimelineResponse struct {
+	List  []TaskTimelineItem `json:"list"`
+	Total int64              `json:"total"`
+}
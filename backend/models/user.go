@@ -18,6 +18,8 @@ type User struct {
 	Password  string    `xorm:"varchar(255) not null 'password'" json:"-"`
 	Avatar    string    `xorm:"varchar(255) 'avatar'" json:"avatar"`
 	Role      string    `xorm:"varchar(50) 'role'" json:"role"`
+	SkillTags []string  `xorm:"json 'skill_tags'" json:"skillTags"`     // 技能标签，只能领取所需标签都具备的任务
+	MaxTasks  int       `xorm:"'max_tasks' default(0)" json:"maxTasks"` // 同时进行的任务上限，0 表示使用默认值
 	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
 	UpdatedAt time.Time `xorm:"updated 'updated_at'" json:"updated_at"`
 }
@@ -52,6 +54,8 @@ type UserResponse struct {
 	Email     string    `json:"email"`
 	Avatar    string    `json:"avatar"`
 	Role      string    `json:"role"`
+	SkillTags []string  `json:"skillTags"`
+	MaxTasks  int       `json:"maxTasks"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -63,6 +67,12 @@ type UserUpdateReq struct {
 	Role     string `json:"role"`
 }
 
+// UserSkillsReq 管理员设置用户的技能标签和同时进行的任务上限
+type UserSkillsReq struct {
+	SkillTags []string `json:"skillTags"`
+	MaxTasks  int      `json:"maxTasks" binding:"min=0,max=100"`
+}
+
 // 重置密码请求结构体
 type ResetPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// VideoSegment 视频片段标注，前端保存为 VideoMarkData.data 数组中的元素
+type VideoSegment struct {
+	ID    string  `json:"id"`
+	Start float64 `json:"start"` // 秒
+	End   float64 `json:"end"`   // 秒
+	Text  string  `json:"text"`
+	Color string  `json:"color,omitempty"`
+}
+
+// IoU 两个片段的时间交并比
+func (s VideoSegment) IoU(o VideoSegment) float64 {
+	inter := math.Min(s.End, o.End) - math.Max(s.Start, o.Start)
+	if inter <= 0 {
+		return 0
+	}
+	union := math.Max(s.End, o.End) - math.Min(s.Start, o.Start)
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
+
+// VideoMark 视频片段标记的数据
+type VideoMark []VideoSegment
+
+// IsVideoMark 判断是否为视频片段标记：前端不写 type，data 为片段数组
+func (m MarkData) IsVideoMark() bool {
+	_, ok := m.Data.(VideoMark)
+	return ok
+}
+
+// VideoSegments 视频标记的片段列表，非视频标记返回 nil
+func (m MarkData) VideoSegments() []VideoSegment {
+	segments, _ := m.Data.(VideoMark)
+	return segments
+}
+
+// ParseVideoSegments 从标记列表中提取所有视频片段，按开始时间排序
+func ParseVideoSegments(marks []MarkData) []VideoSegment {
+	segments := make([]VideoSegment, 0)
+	for _, mark := range marks {
+		segments = append(segments, mark.VideoSegments()...)
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Start < segments[j].Start
+	})
+	return segments
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"luma-ai-backend/api"
+	"luma-ai-backend/config"
 	"luma-ai-backend/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -28,6 +29,14 @@ func SetupRoutes(r *gin.Engine) {
 		public.POST("/user/password/forget", api.RequestPasswordReset)
 		public.POST("/user/password/reset", api.VerifyCodeAndResetPassword)
 
+		// 本地存储的文件，通过签名校验访问权限
+		public.GET("/storage/:bucket_id/*key", api.GetLocalObject)
+
+	}
+
+	// 未配置阿里云OSS时上传的文件保存在本地目录
+	if !config.UseOSSUpload() {
+		r.Static("/uploads", config.Storage.UploadDir)
 	}
 
 	// 受保护的路由（需要认证）
@@ -38,6 +47,7 @@ func SetupRoutes(r *gin.Engine) {
 		protected.GET("/user/profile", api.GetProfile)
 		protected.PUT("/user/profile", api.UpdateProfile)
 		protected.GET("/user/list", api.GetUserList)
+		protected.PUT("/user/:user_id/skills", api.UpdateUserSkills)
 
 		// 存储桶相关
 		protected.GET("/bucket/list", api.ListBuckets)
@@ -46,24 +56,51 @@ func SetupRoutes(r *gin.Engine) {
 		protected.PUT("/bucket/:id", api.UpdateBucket)
 		protected.DELETE("/bucket/:id", api.DeleteBucket)
 		protected.GET("/bucket/objects", api.ListObjects)
+		protected.POST("/bucket/:id/sync", api.ResyncBucket)
+		protected.GET("/bucket/:id/sync", api.GetBucketSync)
+		protected.GET("/bucket/:id/missing", api.ListMissingObjects)
 
 		// 包相关
 		protected.POST("/package", api.SavePackage)
 		protected.POST("/package/publish/:package_id", api.PublishPackage)
 		protected.GET("/package/list", api.GetPackageList)
+		protected.GET("/package/sla", api.GetPackageSLA)
 		protected.GET("/package/:package_id", api.GetPackageDetail)
 		protected.DELETE("/package/:package_id", api.DeletePackage)
+		protected.GET("/package/:package_id/export", api.ExportPackage)
+		protected.POST("/package/:package_id/consensus", api.ComputeConsensus)
+		protected.GET("/package/:package_id/consensus", api.GetConsensusList)
+		protected.PUT("/consensus/:id/adjudicate", api.AdjudicateConsensus)
+		protected.PUT("/package/:package_id/gold", api.SetGoldItems)
+		protected.GET("/package/:package_id/gold", api.GetGoldItems)
+
+		// 质量指标
+		protected.GET("/metrics/task/:task_id", api.GetTaskMetrics)
+		protected.GET("/metrics/annotator/:user_id", api.GetAnnotatorMetrics)
+		protected.GET("/metrics/package/:package_id", api.GetPackageMetrics)
+		protected.GET("/metrics/annotator/:user_id/gold", api.GetGoldScores)
 
 		// 任务相关
 		protected.GET("/task/:task_id", api.GetTaskDetail)
+		protected.GET("/task/:task_id/timeline", api.GetTaskTimeline)
+		protected.GET("/task/:task_id/items/:idx/url", api.GetTaskItemURL)
 		protected.GET("/task/list", api.GetTaskList)
 		protected.POST("/task/claim", api.ClaimTask)
+		protected.POST("/task/next", api.NextTask)
+		protected.POST("/task/rework", api.ReworkTask)
 		protected.PUT("/task/status", api.UpdateTaskStatus)
 		protected.PUT("/task/wip", api.UpdateTaskWipIdx)
+		protected.PUT("/task/priority", api.SetTaskPriority)
 		protected.POST("/task/assign", api.AssignTask)
+		protected.POST("/task/batch", api.BatchUpdateTasks)
 		protected.POST("/task/annotation", api.SaveAnnotation)
 		protected.GET("/task/annotation", api.GetAnnotation)
 		protected.PUT("/task/annotation/review", api.ReviewAnnotation)
+		protected.GET("/task/annotation/revisions", api.ListAnnotationRevisions)
+		protected.GET("/task/annotation/revisions/diff", api.DiffAnnotationRevisions)
+		protected.POST("/task/annotation/restore", api.RestoreAnnotationRevision)
+		protected.GET("/task/:task_id/export", api.ExportTask)
+		protected.POST("/task/:task_id/import", api.ImportAnnotations)
 
 		// 系统消息相关
 		protected.GET("/sysmsg/list", api.GetSysMsgList)
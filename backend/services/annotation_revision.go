@@ -0,0 +1,388 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// minMatchScore 没有面积的标记按类别配对时使用的匹配分数
+const minMatchScore = 1e-6
+
+// recordAnnotationRevision 在事务中追加一条标注修订记录，并记录对应的任务事件
+// 先锁定标注行再取最大版本号，同一标注的并发保存、审核按顺序分配版本号
+func recordAnnotationRevision(session *xorm.Session, annotation *models.SavedAnnotation, action string, userID int64, userRole string, restoredFrom int) error {
+	var locked int64
+	if _, err := session.SQL("SELECT id FROM saved_annotation WHERE id = ? FOR UPDATE", annotation.ID).Get(&locked); err != nil {
+		return err
+	}
+	var latest int
+	if _, err := session.SQL("SELECT COALESCE(MAX(revision), 0) FROM annotation_revision WHERE annotation_id = ? FOR UPDATE", annotation.ID).Get(&latest); err != nil {
+		return err
+	}
+
+	revision := &models.AnnotationRevision{
+		AnnotationID: annotation.ID,
+		Revision:     latest + 1,
+		TaskID:       annotation.TaskID,
+		Key:          annotation.Key,
+		Action:       action,
+		UserID:       userID,
+		Marks:        annotation.Meta.Marks,
+		Review:       annotation.Review,
+		RestoredFrom: restoredFrom,
+	}
+	if _, err := session.Insert(revision); err != nil {
+		return err
+	}
+
+	// 同时记录任务事件：审核记录分数变化，其余记录修订版本号变化
+	event := &models.TaskEvent{
+		TaskID:    annotation.TaskID,
+		Type:      models.TaskEventAnnotation,
+		Action:    action,
+		ActorID:   userID,
+		ActorRole: userRole,
+		Key:       annotation.Key,
+		Field:     "revision",
+		NewValue:  strconv.Itoa(revision.Revision),
+	}
+	if latest > 0 {
+		event.OldValue = strconv.Itoa(latest)
+	}
+	if action == models.RevisionActionReview {
+		previous := &models.AnnotationRevision{}
+		has, err := session.Where("annotation_id = ? AND revision = ?", annotation.ID, latest).Get(previous)
+		if err != nil {
+			return err
+		}
+		event.Type = models.TaskEventReview
+		event.Field = "score"
+		event.OldValue = ""
+		if has && previous.Review != nil {
+			event.OldValue = strconv.Itoa(previous.Review.Score)
+		}
+		if annotation.Review != nil {
+			event.NewValue = strconv.Itoa(annotation.Review.Score)
+		}
+	}
+	return recordTaskEvent(session, event)
+}
+
+// canViewTaskAnnotations 管理员、任务的标注员和审核员可以查看任务的标注
+func canViewTaskAnnotations(task *models.Task, userID int64, userRole string) bool {
+	switch userRole {
+	case models.RoleAdmin:
+		return true
+	case models.RoleAnnotator:
+		return task.Annotator == userID
+	case models.RoleReviewer:
+		return task.Reviewer == userID
+	}
+	return false
+}
+
+// ListAnnotationRevisions 获取任务中某个条目的标注修订记录，最新的在前
+func (ts *TaskService) ListAnnotationRevisions(taskID int64, key string, userID int64, userRole string) (*models.AnnotationRevisionListResponse, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(taskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+	if !canViewTaskAnnotations(task, userID, userRole) {
+		return nil, errors.New("没有权限查看该任务的标注")
+	}
+
+	revisions := make([]models.AnnotationRevision, 0)
+	annotationID, err := annotationIDByKey(taskID, key)
+	if errors.Is(err, errAnnotationNotFound) {
+		return &models.AnnotationRevisionListResponse{List: revisions}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := config.DB.Where("annotation_id = ?", annotationID).
+		Desc("revision").FindAndCount(&revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AnnotationRevisionListResponse{
+		List:  revisions,
+		Total: total,
+	}, nil
+}
+
+// DiffAnnotationRevisions 比较同一条目的两个修订版本
+func (ts *TaskService) DiffAnnotationRevisions(req models.AnnotationRevisionDiffReq, userID int64, userRole string) (*models.AnnotationRevisionDiff, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(req.TaskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+	if !canViewTaskAnnotations(task, userID, userRole) {
+		return nil, errors.New("没有权限查看该任务的标注")
+	}
+
+	annotationID, err := annotationIDByKey(req.TaskID, req.Key)
+	if err != nil {
+		return nil, err
+	}
+	from, err := ts.getAnnotationRevision(annotationID, req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ts.getAnnotationRevision(annotationID, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffMarks(from.Marks, to.Marks)
+	diff.From = from.Revision
+	diff.To = to.Revision
+	return diff, nil
+}
+
+// RestoreAnnotationRevision 将标注恢复到历史版本，只允许任务的标注员在 processing 状态下操作。
+// ifMatch 为读取时的版本号，与保存标注一样缺少时返回 ErrAnnotationVersionRequired，不一致时返回 AnnotationConflictError
+func (ts *TaskService) RestoreAnnotationRevision(req models.RestoreAnnotationRevisionReq, userID int64, userRole string, ifMatch *int) (*models.SavedAnnotation, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(req.TaskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+	if task.Annotator != userID {
+		return nil, errors.New("只有任务的标注员可以恢复标注")
+	}
+	if task.Status != models.TaskStatusProcessing {
+		return nil, errors.New("只有 processing 状态的任务可以恢复标注")
+	}
+
+	annotationID, err := annotationIDByKey(req.TaskID, req.Key)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := ts.getAnnotationRevision(annotationID, req.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// 包的标注规范可能在该版本之后有调整，恢复前重新校验
+	pkg, _, err := ts.resolveTaskItems(task)
+	if err != nil {
+		return nil, err
+	}
+	if errs := validateMarks(pkg.Schema, revision.Marks); len(errs) > 0 {
+		return nil, &AnnotationValidationError{Errors: errs}
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+
+	// 锁定标注行，并发保存时按顺序执行
+	annotation := &models.SavedAnnotation{}
+	has, err = session.ID(revision.AnnotationID).ForUpdate().Get(annotation)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	if !has {
+		session.Rollback()
+		return nil, errors.New("标注不存在")
+	}
+	// 返工时审核已通过的条目保持不变，与保存标注一致
+	if task.ReviewRound > 0 && !annotation.NeedsRework &&
+		annotation.Review != nil && annotation.Review.Score >= models.ReviewPassScore {
+		session.Rollback()
+		return nil, errors.New("该条目审核已通过，无需返工")
+	}
+	if ifMatch == nil {
+		session.Rollback()
+		return nil, ErrAnnotationVersionRequired
+	}
+	if *ifMatch != annotation.Version {
+		session.Rollback()
+		return nil, &AnnotationConflictError{Current: annotation}
+	}
+
+	annotation.Meta.Marks = revision.Marks
+	affected, err := session.ID(annotation.ID).Cols("meta").Update(annotation)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	// 版本号已被其他保存修改，不记录没有写入的修订
+	if affected == 0 {
+		session.Rollback()
+		if conflict := ts.annotationConflict(req.TaskID, req.Key); conflict != nil {
+			return nil, conflict
+		}
+		return nil, errors.New("标注不存在")
+	}
+	if err = recordAnnotationRevision(session, annotation, models.RevisionActionRestore, userID, userRole, revision.Revision); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	// 恢复标注视为仍在处理，续期领取租约
+	if err = renewTaskLease(session, task); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+
+	fullAnnotation := &models.SavedAnnotation{}
+	has, err = config.DB.ID(annotation.ID).Get(fullAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("恢复标注后获取数据失败")
+	}
+	return fullAnnotation, nil
+}
+
+// errAnnotationNotFound 条目还没有标注
+var errAnnotationNotFound = errors.New("标注不存在")
+
+// annotationIDByKey 获取任务中条目当前标注的 ID，修订记录按标注 ID 查询
+func annotationIDByKey(taskID int64, key string) (int64, error) {
+	annotation := &models.SavedAnnotation{}
+	has, err := config.DB.Where("task_id = ? AND `key` = ?", taskID, key).Cols("id").Get(annotation)
+	if err != nil {
+		return 0, err
+	}
+	if !has {
+		return 0, errAnnotationNotFound
+	}
+	return annotation.ID, nil
+}
+
+// getAnnotationRevision 按版本号获取标注的修订记录
+func (ts *TaskService) getAnnotationRevision(annotationID int64, number int) (*models.AnnotationRevision, error) {
+	revision := &models.AnnotationRevision{}
+	has, err := config.DB.Where("annotation_id = ? AND revision = ?", annotationID, number).Get(revision)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("修订版本不存在")
+	}
+	return revision, nil
+}
+
+// diffMarks 比较两组标记：完全相同的计为未变化，其余按类型和形状重叠度配对为移动，剩下的为新增或删除
+func diffMarks(from, to []models.MarkData) *models.AnnotationRevisionDiff {
+	diff := &models.AnnotationRevisionDiff{
+		Added:   make([]models.MarkData, 0),
+		Removed: make([]models.MarkData, 0),
+		Moved:   make([]models.MarkChange, 0),
+	}
+
+	matchedFrom := make([]bool, len(from))
+	matchedTo := make([]bool, len(to))
+
+	// 完全相同的标记
+	fromJSON := make([]string, len(from))
+	for i, mark := range from {
+		raw, _ := json.Marshal(mark)
+		fromJSON[i] = string(raw)
+	}
+	for j, mark := range to {
+		raw, _ := json.Marshal(mark)
+		for i := range from {
+			if !matchedFrom[i] && fromJSON[i] == string(raw) {
+				matchedFrom[i], matchedTo[j] = true, true
+				diff.Unchanged++
+				break
+			}
+		}
+	}
+
+	// 剩余标记按匹配分数从高到低贪心配对
+	type candidate struct {
+		from, to int
+		score    float64
+	}
+	var candidates []candidate
+	for i := range from {
+		if matchedFrom[i] {
+			continue
+		}
+		for j := range to {
+			if matchedTo[j] {
+				continue
+			}
+			if score := markMatchScore(from[i], to[j]); score > 0 {
+				candidates = append(candidates, candidate{i, j, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].score > candidates[b].score
+	})
+	for _, c := range candidates {
+		if matchedFrom[c.from] || matchedTo[c.to] {
+			continue
+		}
+		matchedFrom[c.from], matchedTo[c.to] = true, true
+		iou := c.score
+		if iou == minMatchScore {
+			iou = 0
+		}
+		diff.Moved = append(diff.Moved, models.MarkChange{From: from[c.from], To: to[c.to], IoU: iou})
+	}
+
+	for i, mark := range from {
+		if !matchedFrom[i] {
+			diff.Removed = append(diff.Removed, mark)
+		}
+	}
+	for j, mark := range to {
+		if !matchedTo[j] {
+			diff.Added = append(diff.Added, mark)
+		}
+	}
+	return diff
+}
+
+// markMatchScore 两个标记是同一个标记的可能性：有面积的形状按交并比，
+// 折线、关键点、视频片段等按类别相同配对
+func markMatchScore(a, b models.MarkData) float64 {
+	if a.Type != b.Type {
+		return 0
+	}
+	shapeA, okA := a.Shape()
+	shapeB, okB := b.Shape()
+	if okA && okB && shapeA.Area() > 0 && shapeB.Area() > 0 {
+		if iou := models.IoU(shapeA, shapeB); iou > 0 {
+			return iou
+		}
+	}
+	if markCategory(a) == markCategory(b) {
+		return minMatchScore
+	}
+	return 0
+}
@@ -4,17 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"luma-ai-backend/config"
 	"luma-ai-backend/models"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"luma-ai-backend/storage"
 )
 
 // BucketService 存储桶服务
@@ -37,6 +39,10 @@ func (bs *BucketService) CreateBucket(bucketReq *models.BucketReq) (*models.Buck
 		return nil, errors.New("存储桶名称已存在")
 	}
 
+	if err := bs.validateBucketReq(bucketReq); err != nil {
+		return nil, err
+	}
+
 	// err, path_mode := bs.ValidateBucket(bucketReq)
 	// if err != nil {
 	// 	return nil, err
@@ -45,13 +51,18 @@ func (bs *BucketService) CreateBucket(bucketReq *models.BucketReq) (*models.Buck
 	// 创建存储桶记录
 	bucket := &models.Bucket{
 		Name:      bucketReq.Name,
+		Provider:  bucketReq.Provider,
 		Region:    bucketReq.Region,
+		Endpoint:  bucketReq.Endpoint,
 		AccessKey: bucketReq.Access.Key,
 		SecretKey: bucketReq.Access.Secret,
 		PathMode:  bucketReq.PathMode,
 	}
 
 	// 插入数据库
+	if err = bucket.SealCredentials(); err != nil {
+		return nil, err
+	}
 	_, err = config.DB.Insert(bucket)
 	if err != nil {
 		return nil, err
@@ -59,10 +70,14 @@ func (bs *BucketService) CreateBucket(bucketReq *models.BucketReq) (*models.Buck
 
 	// 返回响应
 	return &models.BucketResponse{
-		ID:        bucket.ID,
-		Name:      bucket.Name,
-		Region:    bucket.Region,
-		CreatedAt: bucket.CreatedAt,
+		ID:         bucket.ID,
+		Name:       bucket.Name,
+		Provider:   bucket.Provider,
+		Region:     bucket.Region,
+		Endpoint:   bucket.Endpoint,
+		PathMode:   bucket.PathMode,
+		KeyVersion: bucket.KeyVersion,
+		CreatedAt:  bucket.CreatedAt,
 	}, nil
 }
 
@@ -77,16 +92,17 @@ func (bs *BucketService) GetBucket(id int64) (*models.BucketResponse, error) {
 		return nil, errors.New("存储桶不存在")
 	}
 
-	// 返回响应（不包含敏感信息）
+	// 返回响应（不包含敏感信息，access key 只返回掩码）
 	return &models.BucketResponse{
-		ID:     bucket.ID,
-		Name:   bucket.Name,
-		Region: bucket.Region,
-		Access: models.BucketAccess{
-			Key:    bucket.AccessKey,
-			Secret: bucket.SecretKey,
-		},
-		CreatedAt: bucket.CreatedAt,
+		ID:            bucket.ID,
+		Name:          bucket.Name,
+		Provider:      bucket.Provider,
+		Region:        bucket.Region,
+		Endpoint:      bucket.Endpoint,
+		PathMode:      bucket.PathMode,
+		AccessKeyHint: models.MaskCredential(bucket.AccessKey),
+		KeyVersion:    bucket.KeyVersion,
+		CreatedAt:     bucket.CreatedAt,
 	}, nil
 }
 
@@ -127,10 +143,14 @@ func (bs *BucketService) ListBuckets(page, pageSize int) (*models.ListBucketResp
 	bucketResponses := make([]models.BucketResponse, len(buckets))
 	for i, bucket := range buckets {
 		bucketResponses[i] = models.BucketResponse{
-			ID:        bucket.ID,
-			Name:      bucket.Name,
-			Region:    bucket.Region,
-			CreatedAt: bucket.CreatedAt,
+			ID:         bucket.ID,
+			Name:       bucket.Name,
+			Provider:   bucket.Provider,
+			Region:     bucket.Region,
+			Endpoint:   bucket.Endpoint,
+			PathMode:   bucket.PathMode,
+			KeyVersion: bucket.KeyVersion,
+			CreatedAt:  bucket.CreatedAt,
 		}
 	}
 
@@ -164,24 +184,76 @@ func (bs *BucketService) UpdateBucket(id int64, bucketReq *models.BucketReq) (*m
 		}
 	}
 
+	// 详情不返回凭证，未填写时沿用原有凭证
+	if bucketReq.Access.Key == "" {
+		bucketReq.Access.Key = existingBucket.AccessKey
+	}
+	if bucketReq.Access.Secret == "" {
+		bucketReq.Access.Secret = existingBucket.SecretKey
+	}
+
+	if err := bs.validateBucketReq(bucketReq); err != nil {
+		return nil, err
+	}
+
+	// 存储位置改变时原有的对象索引不再有效，更新期间不允许同步写入旧位置的索引
+	relocated := existingBucket.Provider != bucketReq.Provider || existingBucket.Name != bucketReq.Name ||
+		existingBucket.Endpoint != bucketReq.Endpoint
+	if relocated {
+		if _, running := syncingBuckets.LoadOrStore(id, true); running {
+			return nil, ErrBucketSyncRunning
+		}
+		defer syncingBuckets.Delete(id)
+	}
+
 	// 更新存储桶信息
 	existingBucket.Name = bucketReq.Name
+	existingBucket.Provider = bucketReq.Provider
 	existingBucket.Region = bucketReq.Region
+	existingBucket.Endpoint = bucketReq.Endpoint
+	existingBucket.PathMode = bucketReq.PathMode
 	existingBucket.AccessKey = bucketReq.Access.Key
 	existingBucket.SecretKey = bucketReq.Access.Secret
 
-	// 更新数据库
-	_, err = config.DB.ID(id).Update(existingBucket)
+	// 更新数据库，endpoint、path_mode 等可能更新为空值，凭证使用当前主密钥重新加密
+	if err = existingBucket.SealCredentials(); err != nil {
+		return nil, err
+	}
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+	_, err = session.ID(id).Cols("name", "provider", "region", "endpoint", "path_mode", "access_key", "secret_key", "data_key", "key_version").Update(existingBucket)
 	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	// 清除对象索引和同步状态，需要重新同步后才能按索引列举
+	if relocated {
+		if _, err = session.Where("bucket_id = ?", id).Delete(&models.BucketObject{}); err != nil {
+			session.Rollback()
+			return nil, err
+		}
+		if _, err = session.Where("bucket_id = ?", id).Delete(&models.BucketSync{}); err != nil {
+			session.Rollback()
+			return nil, err
+		}
+	}
+	if err = session.Commit(); err != nil {
 		return nil, err
 	}
 
 	// 返回响应
 	return &models.BucketResponse{
-		ID:        existingBucket.ID,
-		Name:      existingBucket.Name,
-		Region:    existingBucket.Region,
-		CreatedAt: existingBucket.CreatedAt,
+		ID:         existingBucket.ID,
+		Name:       existingBucket.Name,
+		Provider:   existingBucket.Provider,
+		Region:     existingBucket.Region,
+		Endpoint:   existingBucket.Endpoint,
+		PathMode:   existingBucket.PathMode,
+		KeyVersion: existingBucket.KeyVersion,
+		CreatedAt:  existingBucket.CreatedAt,
 	}, nil
 }
 
@@ -204,9 +276,66 @@ func (bs *BucketService) DeleteBucket(id int64) error {
 	if has {
 		return errors.New("存储桶下存在关联的packages，无法删除")
 	}
-	// 删除存储桶
-	_, err = config.DB.ID(id).Delete(bucket)
-	return err
+	// 删除存储桶及其对象索引
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return err
+	}
+	if _, err := session.ID(id).Delete(bucket); err != nil {
+		session.Rollback()
+		return err
+	}
+	if _, err := session.Where("bucket_id = ?", id).Delete(&models.BucketObject{}); err != nil {
+		session.Rollback()
+		return err
+	}
+	if _, err := session.Where("bucket_id = ?", id).Delete(&models.BucketSync{}); err != nil {
+		session.Rollback()
+		return err
+	}
+	return session.Commit()
+}
+
+// RotateCredentialKeys 使用当前主密钥重新加密所有存储桶的凭证，返回处理的存储桶数量
+// 任一存储桶解密失败时整体回滚，旧主密钥需要保留到轮换完成
+func (bs *BucketService) RotateCredentialKeys() (int, error) {
+	if models.CredentialKeyVersion() == 0 {
+		return 0, errors.New("未配置主密钥")
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return 0, err
+	}
+
+	var buckets []models.Bucket
+	if err := session.ForUpdate().Find(&buckets); err != nil {
+		session.Rollback()
+		return 0, err
+	}
+
+	for i := range buckets {
+		bucket := &buckets[i]
+		if err := bucket.CredentialError(); err != nil {
+			session.Rollback()
+			return 0, fmt.Errorf("存储桶 %d 凭证解密失败: %v", bucket.ID, err)
+		}
+		if err := bucket.SealCredentials(); err != nil {
+			session.Rollback()
+			return 0, fmt.Errorf("存储桶 %d: %v", bucket.ID, err)
+		}
+		if _, err := session.ID(bucket.ID).Cols("access_key", "secret_key", "data_key", "key_version").NoAutoTime().Update(bucket); err != nil {
+			session.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := session.Commit(); err != nil {
+		return 0, err
+	}
+	return len(buckets), nil
 }
 
 // ExtractBucketNameFromURL 从URL中提取bucket名称
@@ -223,159 +352,323 @@ func (bs *BucketService) ExtractBucketNameFromURL(url string) string {
 	return url
 }
 
-// createS3Client 创建S3客户端
-func (bs *BucketService) createS3Client(region, accessKey, secretKey string, pathMode bool) (*s3.Client, error) {
-	ctx := context.Background()
+// validateBucketReq 按存储类型校验存储桶参数，provider 为空时按 S3 处理
+func (bs *BucketService) validateBucketReq(bucketReq *models.BucketReq) error {
+	if bucketReq.Provider == "" {
+		bucketReq.Provider = storage.ProviderS3
+	}
 
-	cfg, err := awsconfig.LoadDefaultConfig(ctx,
-		awsconfig.WithRegion(region),
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			accessKey,
-			secretKey,
-			"", // session token
-		)),
-	)
-	if err != nil {
-		return nil, err
+	switch bucketReq.Provider {
+	case storage.ProviderS3, storage.ProviderOSS:
+		if bucketReq.Region == "" && bucketReq.Endpoint == "" {
+			return errors.New("region 和 endpoint 不能同时为空")
+		}
+		if bucketReq.Access.Key == "" || bucketReq.Access.Secret == "" {
+			return errors.New("访问凭证不能为空")
+		}
+		// MinIO 等兼容服务不区分 region，SDK 仍需要一个值
+		if bucketReq.Provider == storage.ProviderS3 && bucketReq.Region == "" {
+			bucketReq.Region = "us-east-1"
+		}
+	case storage.ProviderLocal:
+		// 检查根目录是否可用，且位于允许的目录下
+		if config.Storage.LocalRoot == "" {
+			return errors.New("未配置 STORAGE_LOCAL_ROOT，不能使用本地存储")
+		}
+		if _, err := storage.NewLocal(storage.Config{Endpoint: bucketReq.Endpoint, BaseDir: config.Storage.LocalRoot}); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("不支持的存储类型: %s", bucketReq.Provider)
 	}
+	return nil
+}
 
-	// 创建S3客户端，强制使用路径模式（path-style）
-	// 这对于某些bucket是必需的，特别是那些使用路径模式的bucket
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = pathMode
+// newProvider 按存储桶配置创建存储
+func (bs *BucketService) newProvider(bucket *models.Bucket) (storage.Provider, error) {
+	if err := bucket.CredentialError(); err != nil {
+		return nil, fmt.Errorf("存储桶凭证解密失败: %v", err)
+	}
+	if bucket.Provider == storage.ProviderLocal && config.Storage.LocalRoot == "" {
+		return nil, errors.New("未配置 STORAGE_LOCAL_ROOT，不能使用本地存储")
+	}
+	return storage.New(storage.Config{
+		Provider:  bucket.Provider,
+		Bucket:    bucket.Name,
+		Region:    bucket.Region,
+		Endpoint:  bucket.Endpoint,
+		BaseDir:   config.Storage.LocalRoot,
+		PathMode:  bucket.PathMode,
+		AccessKey: bucket.AccessKey,
+		SecretKey: bucket.SecretKey,
+		URLPrefix: fmt.Sprintf("%s/storage/%d", config.Storage.PublicURL, bucket.ID),
+		SignKey:   []byte(config.Storage.SignKey),
 	})
+}
 
-	return client, nil
+// Provider 获取存储桶对应的存储
+func (bs *BucketService) Provider(bucketID int64) (storage.Provider, error) {
+	bucket, err := bs.GetBucketWithCredentials(bucketID)
+	if err != nil {
+		return nil, err
+	}
+	return bs.newProvider(bucket)
 }
 
-// ValidateBucket 验证存储桶访问权限
+// ValidateBucket 验证S3存储桶访问权限
 func (bs *BucketService) ValidateBucket(bucketReq *models.BucketReq) (error, bool) {
 	ctx := context.Background()
 
 	// 先使用pathMode为true创建S3客户端，如果失败再尝试false
-	client, err := bs.createS3Client(bucketReq.Region, bucketReq.Access.Key, bucketReq.Access.Secret, true)
-	if err == nil {
-		_, err = client.HeadBucket(ctx, &s3.HeadBucketInput{
-			Bucket: aws.String(bucketReq.Name),
+	for _, pathMode := range []bool{true, false} {
+		provider, err := storage.NewS3(storage.Config{
+			Bucket:    bucketReq.Name,
+			Region:    bucketReq.Region,
+			Endpoint:  bucketReq.Endpoint,
+			PathMode:  pathMode,
+			AccessKey: bucketReq.Access.Key,
+			SecretKey: bucketReq.Access.Secret,
 		})
-		if err == nil {
-			return nil, true // 成功
+		if err != nil {
+			break
 		}
-		// 失败，尝试pathMode为false
-		client, _ = bs.createS3Client(bucketReq.Region, bucketReq.Access.Key, bucketReq.Access.Secret, false)
-		_, err = client.HeadBucket(ctx, &s3.HeadBucketInput{
-			Bucket: aws.String(bucketReq.Name),
-		})
-		if err == nil {
-			return nil, false // 成功
+		if err := provider.HeadBucket(ctx); err == nil {
+			return nil, pathMode // 成功
 		}
 	}
 
-	if err != nil {
-		return fmt.Errorf("bucket验证失败"), false
+	return fmt.Errorf("bucket验证失败"), false
+}
+
+// 图片和视频扩展名
+var (
+	imageExtensions = map[string]bool{
+		".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+		".bmp": true, ".webp": true, ".svg": true,
 	}
+	videoExtensions = map[string]bool{
+		".mp4": true, ".avi": true, ".mov": true, ".wmv": true,
+		".flv": true, ".mkv": true, ".webm": true,
+	}
+)
 
-	return nil, false
+// objectFileType 按扩展名判断对象是图片还是视频，其他类型返回空
+func objectFileType(key string) string {
+	ext := strings.ToLower(filepath.Ext(key))
+	if imageExtensions[ext] {
+		return "image"
+	}
+	if videoExtensions[ext] {
+		return "video"
+	}
+	return ""
 }
 
-// ListObjects 获取存储桶中的对象列表（仅图片和视频）
-func (bs *BucketService) ListObjects(bucketID int64, prefix string, page, pageSize int) (*models.ListObjectsResponse, error) {
-	ctx := context.Background()
+// ErrBucketNotIndexed 存储桶尚未完成同步，对象索引不可用
+var ErrBucketNotIndexed = errors.New("存储桶尚未完成同步，暂不支持搜索、筛选和排序")
 
-	// 获取存储桶信息
-	bucket, err := bs.GetBucketWithCredentials(bucketID)
+// ListObjects 按 "/" 分隔列出目录下的子目录和一页对象（仅图片和视频）
+// 存储桶完成过同步时查询对象索引，否则使用存储的续传标记实时列出，每次只请求一页
+func (bs *BucketService) ListObjects(req models.ListObjectsRequest) (*models.ListObjectsResponse, error) {
+	state, err := loadBucketSync(req.BucketID)
 	if err != nil {
 		return nil, err
 	}
+	if state.LastSyncedAt != nil {
+		return bs.listIndexedObjects(req)
+	}
+	if req.Search != "" || req.MediaType != "" || req.SortBy != "" || req.Order != "" {
+		return nil, ErrBucketNotIndexed
+	}
 
-	// 创建S3客户端
-	client, err := bs.createS3Client(bucket.Region, bucket.AccessKey, bucket.SecretKey, bucket.PathMode)
+	// 获取存储桶对应的存储
+	provider, err := bs.Provider(req.BucketID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 定义图片和视频扩展名
-	imageExtensions := map[string]bool{
-		".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
-		".bmp": true, ".webp": true, ".svg": true,
+	result, err := provider.List(context.Background(), storage.ListInput{
+		Prefix:            req.Prefix,
+		Delimiter:         "/",
+		ContinuationToken: req.ContinuationToken,
+		MaxKeys:           req.PageSize,
+	})
+	if err != nil {
+		return nil, err
 	}
-	videoExtensions := map[string]bool{
-		".mp4": true, ".avi": true, ".mov": true, ".wmv": true,
-		".flv": true, ".mkv": true, ".webm": true,
+
+	// 筛选图片和视频文件，一页中被过滤的对象不会补齐
+	objects := make([]models.ObjectInfo, 0, len(result.Objects))
+	for _, obj := range result.Objects {
+		// 跳过目录本身
+		if obj.Key == req.Prefix || strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		fileType := objectFileType(obj.Key)
+		if fileType == "" {
+			continue // 跳过非图片/视频文件
+		}
+
+		objects = append(objects, models.ObjectInfo{
+			Key:          obj.Key,
+			Name:         filepath.Base(obj.Key),
+			Type:         fileType,
+			Size:         obj.Size,
+			LastModified: obj.LastModified,
+		})
 	}
 
-	// 列出对象
-	input := &s3.ListObjectsV2Input{
-		Bucket: aws.String(bucket.Name),
-		Prefix: aws.String(prefix),
+	subDirectories := result.CommonPrefixes
+	if subDirectories == nil {
+		subDirectories = []string{}
 	}
 
-	// 由于S3不支持服务器端分页，我们需要获取所有对象然后在内存中分页
-	paginator := s3.NewListObjectsV2Paginator(client, input)
+	return &models.ListObjectsResponse{
+		List:                  objects,
+		Total:                 int64(len(objects)),
+		NextContinuationToken: result.NextContinuationToken,
+		HasMore:               result.IsTruncated,
+		SubDirectories:        subDirectories,
+	}, nil
+}
 
-	var allObjects []types.Object
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			return nil, err
+// listIndexedObjects 从对象索引中列出一页对象，续传标记为偏移量
+// 搜索时匹配前缀下所有子目录中的文件名，否则只列出当前目录，第一页附带子目录
+func (bs *BucketService) listIndexedObjects(req models.ListObjectsRequest) (*models.ListObjectsResponse, error) {
+	offset := 0
+	if req.ContinuationToken != "" {
+		n, err := strconv.Atoi(req.ContinuationToken)
+		if err != nil || n < 0 {
+			return nil, errors.New("无效的续传标记")
 		}
-		allObjects = append(allObjects, page.Contents...)
+		offset = n
 	}
 
-	// 筛选图片和视频文件
-	var filteredObjects []models.ObjectInfo
-	for _, obj := range allObjects {
-		if obj.Key == nil {
-			continue
+	// 前缀可以是目录，也可以是目录加文件名的开头
+	dir := objectDir(req.Prefix)
+	session := config.DB.Where("bucket_id = ? AND deleted = ?", req.BucketID, false)
+	if req.Search != "" {
+		if req.Prefix != "" {
+			session.And("`key` LIKE ?", likeEscape(req.Prefix)+"%")
 		}
-
-		key := *obj.Key
-		ext := strings.ToLower(filepath.Ext(key))
-
-		// 检查是否是图片或视频
-		var fileType string
-		if imageExtensions[ext] {
-			fileType = "image"
-		} else if videoExtensions[ext] {
-			fileType = "video"
-		} else {
-			continue // 跳过非图片/视频文件
+		session.And("name LIKE ?", "%"+likeEscape(req.Search)+"%")
+	} else {
+		session.And("dir = ?", dir)
+		if name := req.Prefix[len(dir):]; name != "" {
+			session.And("name LIKE ?", likeEscape(name)+"%")
 		}
+	}
+	if req.MediaType != "" {
+		session.And("media_type = ?", req.MediaType)
+	}
 
-		// 提取文件名
-		name := filepath.Base(key)
+	// 排序列已由请求校验限定，Asc/Desc 会为列名加引号
+	sortColumn := req.SortBy
+	if sortColumn == "" {
+		sortColumn = "key"
+	}
+	if req.Order == "desc" {
+		session.Desc(sortColumn, "id")
+	} else {
+		session.Asc(sortColumn, "id")
+	}
 
-		filteredObjects = append(filteredObjects, models.ObjectInfo{
-			Key:          key,
-			Name:         name,
-			Type:         fileType,
-			Size:         aws.ToInt64(obj.Size),
-			LastModified: aws.ToTime(obj.LastModified),
+	// 多取一条判断是否还有下一页
+	var rows []models.BucketObject
+	if err := session.Limit(req.PageSize+1, offset).Find(&rows); err != nil {
+		return nil, err
+	}
+	hasMore := len(rows) > req.PageSize
+	if hasMore {
+		rows = rows[:req.PageSize]
+	}
+
+	objects := make([]models.ObjectInfo, 0, len(rows))
+	for _, row := range rows {
+		objects = append(objects, models.ObjectInfo{
+			Key:          row.Key,
+			Name:         row.Name,
+			Type:         row.MediaType,
+			Size:         row.Size,
+			LastModified: row.LastModified,
+			Width:        row.Width,
+			Height:       row.Height,
 		})
 	}
 
-	// 计算分页
-	total := len(filteredObjects)
-	start := (page - 1) * pageSize
-	end := start + pageSize
+	subDirectories := []string{}
+	if req.Search == "" && offset == 0 {
+		var err error
+		if subDirectories, err = indexedSubDirectories(req.BucketID, req.Prefix); err != nil {
+			return nil, err
+		}
+	}
 
-	if start > total {
-		start = total
+	resp := &models.ListObjectsResponse{
+		List:           objects,
+		Total:          int64(len(objects)),
+		HasMore:        hasMore,
+		SubDirectories: subDirectories,
+		Indexed:        true,
 	}
-	if end > total {
-		end = total
+	if hasMore {
+		resp.NextContinuationToken = strconv.Itoa(offset + len(rows))
 	}
+	return resp, nil
+}
 
-	// 获取当前页的对象
-	var pageObjects []models.ObjectInfo
-	if start < end {
-		pageObjects = filteredObjects[start:end]
+// indexedSubDirectories 从对象索引中取出前缀所在目录下、名称匹配前缀的子目录
+func indexedSubDirectories(bucketID int64, prefix string) ([]string, error) {
+	dir := objectDir(prefix)
+	// SUBSTRING 按字符计数，从 1 开始
+	start := utf8.RuneCountInString(dir) + 1
+	var names []string
+	err := config.DB.SQL("SELECT DISTINCT SUBSTRING_INDEX(SUBSTRING(dir, ?), '/', 1) FROM bucket_object "+
+		"WHERE bucket_id = ? AND deleted = ? AND dir LIKE ? AND dir != ?",
+		start, bucketID, false, likeEscape(prefix)+"%", dir).Find(&names)
+	if err != nil {
+		return nil, err
 	}
+	sort.Strings(names)
 
-	return &models.ListObjectsResponse{
-		Objects:  pageObjects,
-		Total:    int64(total),
-		Page:     page,
-		PageSize: pageSize,
-	}, nil
+	subDirectories := make([]string, 0, len(names))
+	for _, name := range names {
+		subDirectories = append(subDirectories, dir+name+"/")
+	}
+	return subDirectories, nil
+}
+
+// likeEscape 转义 LIKE 中的通配符
+func likeEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// ImageSizeReader 读取同一存储桶中图片对象的尺寸
+type ImageSizeReader struct {
+	provider storage.Provider
+}
+
+// NewImageSizeReader 为存储桶创建图片尺寸读取器
+func (bs *BucketService) NewImageSizeReader(bucketID int64) (*ImageSizeReader, error) {
+	provider, err := bs.Provider(bucketID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImageSizeReader{provider: provider}, nil
+}
+
+// Size 读取图片宽高，只解析图片头部，不下载整个对象
+func (r *ImageSizeReader) Size(key string) (int, int, error) {
+	body, err := r.provider.Get(context.Background(), key)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer body.Close()
+
+	cfg, _, err := image.DecodeConfig(body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无法读取图片尺寸 %s: %v", key, err)
+	}
+	return cfg.Width, cfg.Height, nil
 }
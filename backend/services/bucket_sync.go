@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+	"luma-ai-backend/storage"
+)
+
+// ErrBucketSyncRunning 存储桶正在同步
+var ErrBucketSyncRunning = errors.New("存储桶正在同步，请稍后再试")
+
+// 每次从存储读取的对象数，每页处理完成后保存进度
+const bucketSyncPageSize = 1000
+
+// syncingBuckets 正在同步的存储桶，同一存储桶同时只有一个同步任务
+var syncingBuckets sync.Map
+
+// ResyncBucket 手动触发同步，在后台执行，上次扫描中断时从中断处继续
+func (bs *BucketService) ResyncBucket(bucketID int64) error {
+	if _, err := bs.GetBucketWithCredentials(bucketID); err != nil {
+		return err
+	}
+	if _, running := syncingBuckets.LoadOrStore(bucketID, true); running {
+		return ErrBucketSyncRunning
+	}
+
+	go func() {
+		defer syncingBuckets.Delete(bucketID)
+		state, err := bs.syncBucket(bucketID)
+		if err != nil {
+			log.Printf("同步存储桶 %d 失败: %v", bucketID, err)
+			return
+		}
+		log.Printf("存储桶 %d 同步完成: 新增 %d，更新 %d，删除 %d", bucketID, state.Added, state.Updated, state.Removed)
+	}()
+	return nil
+}
+
+// GetBucketSync 获取存储桶的同步状态，从未同步过时返回 idle
+func (bs *BucketService) GetBucketSync(bucketID int64) (*models.BucketSync, error) {
+	state, err := loadBucketSync(bucketID)
+	if err != nil {
+		return nil, err
+	}
+	if _, running := syncingBuckets.Load(bucketID); running {
+		state.Status = models.BucketSyncRunning
+	} else if state.Status == models.BucketSyncRunning {
+		// 服务重启导致中断的扫描，下次同步时继续
+		state.Status = models.BucketSyncIdle
+	}
+	return state, nil
+}
+
+// loadBucketSync 获取存储桶的同步进度，不存在时返回未保存的初始状态
+func loadBucketSync(bucketID int64) (*models.BucketSync, error) {
+	state := &models.BucketSync{}
+	has, err := config.DB.Where("bucket_id = ?", bucketID).Get(state)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		state = &models.BucketSync{BucketID: bucketID, Status: models.BucketSyncIdle}
+	}
+	return state, nil
+}
+
+// saveBucketSync 保存同步进度，进度中的续传标记、时间等可能为空值
+func saveBucketSync(state *models.BucketSync) error {
+	if state.ID == 0 {
+		_, err := config.DB.Insert(state)
+		return err
+	}
+	_, err := config.DB.ID(state.ID).
+		Cols("status", "cursor", "scan_started_at", "last_synced_at", "objects", "added", "updated", "removed", "error").
+		Update(state)
+	return err
+}
+
+// syncBucket 全量扫描存储桶，逐页更新对象索引并保存进度，扫描结束后把本轮未出现的对象标记为已删除
+func (bs *BucketService) syncBucket(bucketID int64) (*models.BucketSync, error) {
+	bucket, err := bs.GetBucketWithCredentials(bucketID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := bs.newProvider(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := loadBucketSync(bucketID)
+	if err != nil {
+		return nil, err
+	}
+	// 没有未完成的扫描时开始新的一轮
+	if state.ScanStartedAt == nil {
+		now := time.Now().Truncate(time.Second)
+		state.ScanStartedAt = &now
+		state.Cursor = ""
+		state.Added, state.Updated, state.Removed = 0, 0, 0
+	}
+	state.Status = models.BucketSyncRunning
+	state.Error = ""
+	if err := saveBucketSync(state); err != nil {
+		return nil, err
+	}
+
+	// 中断时保留续传标记，下次从中断处继续
+	fail := func(err error) (*models.BucketSync, error) {
+		state.Status = models.BucketSyncFailed
+		state.Error = err.Error()
+		if saveErr := saveBucketSync(state); saveErr != nil {
+			log.Printf("保存存储桶 %d 同步进度失败: %v", bucketID, saveErr)
+		}
+		return state, err
+	}
+
+	sizeReader := &ImageSizeReader{provider: provider}
+	for {
+		result, err := provider.List(context.Background(), storage.ListInput{
+			ContinuationToken: state.Cursor,
+			MaxKeys:           bucketSyncPageSize,
+		})
+		if err != nil {
+			return fail(err)
+		}
+
+		added, updated, err := syncObjectPage(bucketID, result.Objects, sizeReader)
+		if err != nil {
+			return fail(err)
+		}
+		state.Added += added
+		state.Updated += updated
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		state.Cursor = result.NextContinuationToken
+		if err := saveBucketSync(state); err != nil {
+			return fail(err)
+		}
+	}
+
+	// 本轮扫描中没有出现的对象已从存储中删除
+	scanStartedAt := *state.ScanStartedAt
+	res, err := config.DB.Exec("UPDATE bucket_object SET deleted = ?, deleted_at = ? WHERE bucket_id = ? AND deleted = ? AND synced_at < ?",
+		true, time.Now(), bucketID, false, scanStartedAt)
+	if err != nil {
+		return fail(err)
+	}
+	removed, _ := res.RowsAffected()
+
+	objects, err := config.DB.Where("bucket_id = ? AND deleted = ?", bucketID, false).Count(&models.BucketObject{})
+	if err != nil {
+		return fail(err)
+	}
+
+	now := time.Now()
+	state.Status = models.BucketSyncIdle
+	state.Cursor = ""
+	state.ScanStartedAt = nil
+	state.LastSyncedAt = &now
+	state.Objects = objects
+	state.Removed = int(removed)
+	if err := saveBucketSync(state); err != nil {
+		return state, err
+	}
+
+	if removed > 0 {
+		bs.notifyMissingObjects(bucket, scanStartedAt)
+	}
+	return state, nil
+}
+
+// syncObjectPage 更新一页对象的索引，只索引图片和视频，返回新增和内容变化的数量
+func syncObjectPage(bucketID int64, objects []storage.Object, sizeReader *ImageSizeReader) (int, int, error) {
+	now := time.Now()
+	byHash := make(map[string]storage.Object, len(objects))
+	hashes := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		if strings.HasSuffix(obj.Key, "/") || objectFileType(obj.Key) == "" {
+			continue
+		}
+		hash := objectKeyHash(obj.Key)
+		byHash[hash] = obj
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) == 0 {
+		return 0, 0, nil
+	}
+
+	var existing []models.BucketObject
+	if err := config.DB.Where("bucket_id = ?", bucketID).In("key_hash", hashes).Find(&existing); err != nil {
+		return 0, 0, err
+	}
+	indexed := make(map[string]*models.BucketObject, len(existing))
+	for i := range existing {
+		indexed[existing[i].KeyHash] = &existing[i]
+	}
+
+	added, updated := 0, 0
+	var unchanged []int64
+	for _, hash := range hashes {
+		obj := byHash[hash]
+		row, has := indexed[hash]
+		if has && !row.Deleted && row.ETag == obj.ETag && row.Size == obj.Size && row.LastModified.Equal(obj.LastModified.Truncate(time.Second)) {
+			unchanged = append(unchanged, row.ID)
+			continue
+		}
+
+		entry := newBucketObject(bucketID, hash, obj, sizeReader)
+		entry.SyncedAt = now
+		if !has {
+			if _, err := config.DB.Insert(entry); err != nil {
+				return added, updated, err
+			}
+			added++
+			continue
+		}
+
+		if _, err := config.DB.ID(row.ID).
+			Cols("size", "etag", "last_modified", "media_type", "width", "height", "deleted", "deleted_at", "synced_at").
+			Update(entry); err != nil {
+			return added, updated, err
+		}
+		if row.Deleted {
+			added++
+		} else {
+			updated++
+		}
+	}
+
+	// 未变化的对象只更新同步时间
+	if len(unchanged) > 0 {
+		if _, err := config.DB.In("id", unchanged).Cols("synced_at").Update(&models.BucketObject{SyncedAt: now}); err != nil {
+			return added, updated, err
+		}
+	}
+	return added, updated, nil
+}
+
+// newBucketObject 由存储中的对象生成索引，图片会读取宽高
+func newBucketObject(bucketID int64, hash string, obj storage.Object, sizeReader *ImageSizeReader) *models.BucketObject {
+	entry := &models.BucketObject{
+		BucketID:     bucketID,
+		KeyHash:      hash,
+		Key:          obj.Key,
+		Dir:          objectDir(obj.Key),
+		Name:         path.Base(obj.Key),
+		Size:         obj.Size,
+		ETag:         obj.ETag,
+		LastModified: obj.LastModified,
+		MediaType:    objectFileType(obj.Key),
+	}
+	if entry.MediaType == "image" {
+		width, height, err := sizeReader.Size(obj.Key)
+		if err != nil {
+			// svg 等无法解析的图片不记录宽高
+			log.Printf("读取图片尺寸失败: %v", err)
+		}
+		entry.Width, entry.Height = width, height
+	}
+	return entry
+}
+
+// objectKeyHash 对象 key 的 sha256
+func objectKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// objectDir 对象所在目录，以 "/" 结尾，根目录为空
+func objectDir(key string) string {
+	return key[:strings.LastIndex(key, "/")+1]
+}
+
+// MissingObjects 列出已从存储中删除但仍被包引用的对象
+func (bs *BucketService) MissingObjects(bucketID int64) (*models.MissingObjectResponse, error) {
+	var deleted []models.BucketObject
+	if err := config.DB.Where("bucket_id = ? AND deleted = ?", bucketID, true).Cols("key", "deleted_at").Find(&deleted); err != nil {
+		return nil, err
+	}
+	list := make([]models.MissingObject, 0)
+	if len(deleted) == 0 {
+		return &models.MissingObjectResponse{List: list}, nil
+	}
+	deletedAt := make(map[string]*time.Time, len(deleted))
+	for _, obj := range deleted {
+		deletedAt[obj.Key] = obj.DeletedAt
+	}
+
+	var packages []models.Package
+	if err := config.DB.Where("bucket_id = ?", bucketID).Asc("id").Find(&packages); err != nil {
+		return nil, err
+	}
+	for _, pkg := range packages {
+		var items []string
+		if pkg.Items != "" {
+			if err := json.Unmarshal([]byte(pkg.Items), &items); err != nil {
+				return nil, err
+			}
+		}
+		for _, key := range items {
+			if at, ok := deletedAt[key]; ok {
+				list = append(list, models.MissingObject{
+					PackageID:   pkg.ID,
+					PackageName: pkg.Name,
+					Key:         key,
+					DeletedAt:   at,
+				})
+			}
+		}
+	}
+	return &models.MissingObjectResponse{List: list, Total: len(list)}, nil
+}
+
+// notifyMissingObjects 本轮扫描发现被包引用的对象已删除时通知管理员
+func (bs *BucketService) notifyMissingObjects(bucket *models.Bucket, since time.Time) {
+	missing, err := bs.MissingObjects(bucket.ID)
+	if err != nil {
+		log.Printf("检查存储桶 %d 中被引用的已删除对象失败: %v", bucket.ID, err)
+		return
+	}
+	count := 0
+	for _, obj := range missing.List {
+		if obj.DeletedAt != nil && !obj.DeletedAt.Before(since) {
+			count++
+		}
+	}
+	if count == 0 {
+		return
+	}
+
+	_, err = NewSysMsgService().CreateSysMsgForRoles("存储桶对象已删除",
+		fmt.Sprintf("存储桶 %s 中有 %d 个被包引用的对象已从存储中删除，请在存储桶页面查看", bucket.Name, count),
+		models.RoleAdmin)
+	if err != nil {
+		log.Printf("发送存储桶对象删除通知失败: %v", err)
+	}
+}
+
+// StartBucketSyncScheduler 启动后台任务，定期全量扫描所有存储桶，间隔为 0 时不启动
+func StartBucketSyncScheduler() {
+	if config.BucketSyncInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(config.BucketSyncInterval)
+		defer ticker.Stop()
+		bs := NewBucketService()
+		for range ticker.C {
+			var buckets []models.Bucket
+			if err := config.DB.Cols("id").Find(&buckets); err != nil {
+				log.Printf("获取存储桶列表失败: %v", err)
+				continue
+			}
+			for _, bucket := range buckets {
+				// 正在手动同步的存储桶跳过
+				if _, running := syncingBuckets.LoadOrStore(bucket.ID, true); running {
+					continue
+				}
+				state, err := bs.syncBucket(bucket.ID)
+				syncingBuckets.Delete(bucket.ID)
+				if err != nil {
+					log.Printf("同步存储桶 %d 失败: %v", bucket.ID, err)
+					continue
+				}
+				log.Printf("存储桶 %d 同步完成: 新增 %d，更新 %d，删除 %d", bucket.ID, state.Added, state.Updated, state.Removed)
+			}
+		}
+	}()
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+)
+
+// consensusIoUThreshold 不同标注员的框被视为同一目标的最小交并比
+const consensusIoUThreshold = 0.5
+
+// defaultAgreementThreshold 包未设置一致性阈值时使用的默认值
+const defaultAgreementThreshold = 0.7
+
+// ErrConsensusNotReady 分片还有副本任务未提交
+var ErrConsensusNotReady = errors.New("该分片还有标注员未提交，暂不能合并")
+
+// ConsensusService 重叠标注合并服务
+type ConsensusService struct{}
+
+// NewConsensusService 创建合并服务实例
+func NewConsensusService() *ConsensusService {
+	return &ConsensusService{}
+}
+
+// ComputePackage 为包内所有副本都已提交的分片计算合并结果
+func (cs *ConsensusService) ComputePackage(packageID int64) (*models.ConsensusComputeResult, error) {
+	pkg := &models.Package{}
+	has, err := config.DB.ID(packageID).Get(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("包不存在")
+	}
+	if pkg.Overlap < 2 {
+		return nil, errors.New("该包未开启重叠标注")
+	}
+
+	var tasks []models.Task
+	if err = config.DB.Where("package_id = ?", packageID).Asc("chunk_idx").Find(&tasks); err != nil {
+		return nil, err
+	}
+
+	result := &models.ConsensusComputeResult{}
+	seen := make(map[int]bool)
+	for _, task := range tasks {
+		if seen[task.ChunkIdx] {
+			continue
+		}
+		seen[task.ChunkIdx] = true
+
+		items, flagged, err := cs.ComputeChunk(packageID, task.ChunkIdx)
+		if errors.Is(err, ErrConsensusNotReady) {
+			result.Pending++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Chunks++
+		result.Items += items
+		result.Flagged += flagged
+	}
+	return result, nil
+}
+
+// ComputeChunk 合并一个分片内各副本的标注，返回合并的条目数和需要裁决的条目数
+// 已经裁决过的条目不会被覆盖
+func (cs *ConsensusService) ComputeChunk(packageID int64, chunkIdx int) (int, int, error) {
+	pkg := &models.Package{}
+	has, err := config.DB.ID(packageID).Get(pkg)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !has {
+		return 0, 0, errors.New("包不存在")
+	}
+
+	var replicas []models.Task
+	err = config.DB.Where("package_id = ? AND chunk_idx = ? AND replica > 0", packageID, chunkIdx).
+		Asc("replica").Find(&replicas)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(replicas) < 2 {
+		return 0, 0, errors.New("该分片没有重叠标注任务")
+	}
+
+	// 所有副本提交后才合并
+	taskIDs := make([]int64, len(replicas))
+	for i, task := range replicas {
+		switch task.Status {
+		case models.TaskStatusProcessed, models.TaskStatusReviewing, models.TaskStatusApproved:
+		default:
+			return 0, 0, ErrConsensusNotReady
+		}
+		taskIDs[i] = task.ID
+	}
+
+	_, items, err := NewTaskService().resolveTaskItems(&replicas[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var annotations []models.SavedAnnotation
+	if err = config.DB.In("task_id", taskIDs).Find(&annotations); err != nil {
+		return 0, 0, err
+	}
+	replicaIndex := make(map[int64]int, len(replicas))
+	for i, task := range replicas {
+		replicaIndex[task.ID] = i
+	}
+	marksByKey := make(map[string][][]models.MarkData, len(items))
+	for _, annotation := range annotations {
+		if _, ok := marksByKey[annotation.Key]; !ok {
+			marksByKey[annotation.Key] = make([][]models.MarkData, len(replicas))
+		}
+		marksByKey[annotation.Key][replicaIndex[annotation.TaskID]] = annotation.Meta.Marks
+	}
+
+	threshold := pkg.AgreementThreshold
+	if threshold <= 0 {
+		threshold = defaultAgreementThreshold
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return 0, 0, err
+	}
+
+	flagged := 0
+	for _, key := range items {
+		perAnnotator, ok := marksByKey[key]
+		if !ok {
+			perAnnotator = make([][]models.MarkData, len(replicas))
+		}
+		marks, agreement := mergeConsensus(perAnnotator)
+
+		existing := &models.ConsensusAnnotation{}
+		has, err := session.Where("package_id = ? AND `key` = ?", packageID, key).Get(existing)
+		if err != nil {
+			session.Rollback()
+			return 0, 0, err
+		}
+		if has && existing.AdjudicatedBy > 0 {
+			continue
+		}
+
+		consensus := &models.ConsensusAnnotation{
+			PackageID:         packageID,
+			Key:               key,
+			ChunkIdx:          chunkIdx,
+			Marks:             marks,
+			Agreement:         agreement,
+			AnnotatorCount:    len(replicas),
+			NeedsAdjudication: agreement < threshold,
+		}
+		if consensus.NeedsAdjudication {
+			flagged++
+		}
+		if has {
+			_, err = session.ID(existing.ID).
+				Cols("chunk_idx", "marks", "agreement", "annotator_count", "needs_adjudication").
+				Update(consensus)
+		} else {
+			_, err = session.Insert(consensus)
+		}
+		if err != nil {
+			session.Rollback()
+			return 0, 0, err
+		}
+	}
+
+	if err = session.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return len(items), flagged, nil
+}
+
+// ListConsensus 分页获取包的合并结果
+func (cs *ConsensusService) ListConsensus(packageID int64, req models.ConsensusListRequest) (*models.ConsensusListResponse, error) {
+	session := config.DB.Where("package_id = ?", packageID)
+	if req.Flagged {
+		session = session.And("needs_adjudication = ?", true)
+	}
+
+	var list []models.ConsensusAnnotation
+	total, err := session.Asc("id").Limit(req.PageSize, (req.Page-1)*req.PageSize).FindAndCount(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConsensusListResponse{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+// Adjudicate 审核员裁决一致性不足的条目，以提交的标记作为最终结果
+func (cs *ConsensusService) Adjudicate(id int64, req models.AdjudicateConsensusReq, reviewerID int64) (*models.ConsensusAnnotation, error) {
+	consensus := &models.ConsensusAnnotation{}
+	has, err := config.DB.ID(id).Get(consensus)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("合并结果不存在")
+	}
+
+	pkg := &models.Package{}
+	has, err = config.DB.ID(consensus.PackageID).Get(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("关联的包不存在")
+	}
+	if errs := validateMarks(pkg.Schema, req.Marks); len(errs) > 0 {
+		return nil, &AnnotationValidationError{Errors: errs}
+	}
+
+	now := time.Now()
+	consensus.Marks = req.Marks
+	consensus.NeedsAdjudication = false
+	consensus.AdjudicatedBy = reviewerID
+	consensus.AdjudicatedAt = &now
+	_, err = config.DB.ID(id).Cols("marks", "needs_adjudication", "adjudicated_by", "adjudicated_at").Update(consensus)
+	if err != nil {
+		return nil, err
+	}
+	return consensus, nil
+}
+
+// consensusCluster 不同标注员标注的同一目标
+type consensusCluster struct {
+	members []consensusMember
+}
+
+// consensusMember 聚类中某个标注员的标记
+type consensusMember struct {
+	annotator int
+	bbox      models.BoundingBox
+	class     string
+	color     string
+}
+
+// mean 聚类中各框的平均值
+func (c *consensusCluster) mean() models.BoundingBox {
+	var box models.BoundingBox
+	for _, m := range c.members {
+		box.X += m.bbox.X
+		box.Y += m.bbox.Y
+		box.Width += m.bbox.Width
+		box.Height += m.bbox.Height
+	}
+	n := float64(len(c.members))
+	return models.BoundingBox{X: box.X / n, Y: box.Y / n, Width: box.Width / n, Height: box.Height / n}
+}
+
+// has 聚类中是否已有该标注员的框
+func (c *consensusCluster) has(annotator int) bool {
+	for _, m := range c.members {
+		if m.annotator == annotator {
+			return true
+		}
+	}
+	return false
+}
+
+// vote 多数投票决定类别，票数相同时取先出现的类别，返回类别、得票数和颜色
+func (c *consensusCluster) vote() (string, int, string) {
+	votes := make(map[string]int)
+	var order []string
+	colors := make(map[string]string)
+	for _, m := range c.members {
+		if _, ok := votes[m.class]; !ok {
+			order = append(order, m.class)
+			colors[m.class] = m.color
+		}
+		votes[m.class]++
+	}
+	best := order[0]
+	for _, class := range order[1:] {
+		if votes[class] > votes[best] {
+			best = class
+		}
+	}
+	return best, votes[best], colors[best]
+}
+
+// mergeConsensus 合并多个标注员对同一条目的标记
+// 按外接矩形的交并比把各标注员的框聚为同一目标，过半数标注员标出的目标取框的平均值、类别按多数投票；
+// 一致性为各目标得票最多的类别票数之和除以（标注员数 × 目标数），都没有标记时为 1。
+// 只合并有面积的标记（矩形、圆形、多边形），合并结果为矩形
+func mergeConsensus(perAnnotator [][]models.MarkData) ([]models.MarkData, float64) {
+	annotators := len(perAnnotator)
+	clusters := clusterMarks(perAnnotator)
+
+	merged := make([]models.MarkData, 0, len(clusters))
+	if len(clusters) == 0 || annotators == 0 {
+		return merged, 1
+	}
+
+	agreeing := 0
+	for _, cluster := range clusters {
+		class, votes, color := cluster.vote()
+		agreeing += votes
+		if len(cluster.members)*2 <= annotators {
+			continue
+		}
+		box := cluster.mean()
+		merged = append(merged, models.MarkData{
+			Type: models.MarkTypeRect,
+			Data: &models.RectMark{
+				X:         box.X,
+				Y:         box.Y,
+				Width:     box.Width,
+				Height:    box.Height,
+				MarkLabel: models.MarkLabel{Color: color, Text: class, Class: class},
+			},
+		})
+	}
+
+	return merged, float64(agreeing) / float64(annotators*len(clusters))
+}
+
+// clusterMarks 按外接矩形的交并比把各标注员有面积的标记聚为同一目标，每个聚类每名标注员最多一个标记
+func clusterMarks(perAnnotator [][]models.MarkData) []*consensusCluster {
+	var clusters []*consensusCluster
+
+	for a, marks := range perAnnotator {
+		var members []consensusMember
+		for _, mark := range marks {
+			shape, ok := exportShape(mark)
+			if !ok {
+				continue
+			}
+			members = append(members, consensusMember{
+				annotator: a,
+				bbox:      shape.BoundingBox(),
+				class:     markCategory(mark),
+				color:     shape.Label().Color,
+			})
+		}
+
+		// 按交并比从高到低将该标注员的框分配给已有聚类，每个聚类每名标注员最多一个框
+		type candidate struct {
+			member, cluster int
+			iou             float64
+		}
+		var candidates []candidate
+		for i, member := range members {
+			for j, cluster := range clusters {
+				if cluster.has(a) {
+					continue
+				}
+				if iou := member.bbox.IoU(cluster.mean()); iou >= consensusIoUThreshold {
+					candidates = append(candidates, candidate{i, j, iou})
+				}
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].iou > candidates[j].iou
+		})
+
+		assigned := make([]bool, len(members))
+		taken := make(map[int]bool)
+		for _, c := range candidates {
+			if assigned[c.member] || taken[c.cluster] {
+				continue
+			}
+			assigned[c.member], taken[c.cluster] = true, true
+			clusters[c.cluster].members = append(clusters[c.cluster].members, members[c.member])
+		}
+		for i, member := range members {
+			if !assigned[i] {
+				clusters = append(clusters, &consensusCluster{members: []consensusMember{member}})
+			}
+		}
+	}
+
+	return clusters
+}
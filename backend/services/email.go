@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"html"
 	"log"
 
 	"luma-ai-backend/config"
@@ -39,6 +40,23 @@ func (es *EmailService) SendVerificationCode(toEmail, code string) error {
 	`, code))
 }
 
+// SendTaskAlert 发送任务提醒邮件
+func (es *EmailService) SendTaskAlert(toEmail, title, content string) error {
+	if config.Brevo == nil || config.Brevo.Client == nil || config.Brevo.Sender == nil {
+		log.Println("Brevo configuration is not available, skipping email sending")
+		return nil
+	}
+
+	return es.SendEmail(toEmail, "CosCos - "+title, fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+			<h2 style="color: #333;">%s</h2>
+			<p>%s</p>
+			<hr style="margin: 20px 0; border: none; border-top: 1px solid #eee;">
+			<p style="color: #999; font-size: 12px;">此邮件由系统自动发送，请勿回复。</p>
+		</div>
+	`, html.EscapeString(title), html.EscapeString(content)))
+}
+
 func (es *EmailService) SendEmail(toEmail, subject, html string) error {
 	email := brevo.SendSmtpEmail{
 		Sender:      config.Brevo.Sender,
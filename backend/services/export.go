@@ -0,0 +1,418 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+)
+
+// ExportService 标注导出服务
+type ExportService struct{}
+
+// NewExportService 创建导出服务实例
+func NewExportService() *ExportService {
+	return &ExportService{}
+}
+
+// exportSource 导出数据来源：包、条目以及已审核通过任务的标注
+type exportSource struct {
+	Package     *models.Package
+	Items       []string
+	Annotations map[string]*models.SavedAnnotation // key -> 标注
+}
+
+// exportShape 返回可导出为区域的标记形状，折线、关键点、视频片段等没有面积的标记不导出
+func exportShape(mark models.MarkData) (models.Shape, bool) {
+	shape, ok := mark.Shape()
+	if !ok || shape.Area() <= 0 {
+		return nil, false
+	}
+	return shape, true
+}
+
+// markCategory 标记的导出类别名称：未设置类别或只有默认文本时使用标记类型
+func markCategory(mark models.MarkData) string {
+	if label := mark.Label(); label != nil {
+		if name := label.ClassName(); name != "" && !isDefaultMarkText(mark.Type, name) {
+			return name
+		}
+	}
+	return mark.Type
+}
+
+// isDefaultMarkText 是否为标注工具自动生成的文本，如 "Rect 3"、"Polygon 12"
+func isDefaultMarkText(markType, text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) != 2 || !strings.EqualFold(fields[0], markType) {
+		return false
+	}
+	_, err := strconv.Atoi(fields[1])
+	return err == nil
+}
+
+// loadTaskSource 加载已审核通过任务的导出数据
+func (es *ExportService) loadTaskSource(taskID int64) (*exportSource, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(taskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+	if task.Status != models.TaskStatusApproved {
+		return nil, errors.New("只有 approved 状态的任务可以导出")
+	}
+
+	return es.loadSource([]models.Task{*task})
+}
+
+// loadPackageSource 加载包内所有已审核通过任务的导出数据
+func (es *ExportService) loadPackageSource(packageID int64) (*exportSource, error) {
+	has, err := config.DB.ID(packageID).Exist(&models.Package{})
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("包不存在")
+	}
+
+	var tasks []models.Task
+	if err = config.DB.Where("package_id = ? AND status = ?", packageID, models.TaskStatusApproved).Asc("id").Find(&tasks); err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("该包没有 approved 状态的任务")
+	}
+
+	src, err := es.loadSource(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重叠标注的包导出合并结果，而不是某一个副本的标注
+	if src.Package.Overlap > 1 {
+		if src.Annotations, err = es.loadConsensus(packageID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 每个任务都会标注金标准条目，包导出时这些条目使用参考标注
+	var golds []models.GoldItem
+	if err = config.DB.Where("package_id = ?", packageID).Find(&golds); err != nil {
+		return nil, err
+	}
+	for _, gold := range golds {
+		annotation := &models.SavedAnnotation{Key: gold.Key}
+		annotation.Meta.Marks = gold.Marks
+		src.Annotations[gold.Key] = annotation
+	}
+	return src, nil
+}
+
+// loadConsensus 获取包的合并结果，按 key 索引；等待裁决的条目不导出
+func (es *ExportService) loadConsensus(packageID int64) (map[string]*models.SavedAnnotation, error) {
+	var consensus []models.ConsensusAnnotation
+	if err := config.DB.Where("package_id = ? AND needs_adjudication = ?", packageID, false).Find(&consensus); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*models.SavedAnnotation, len(consensus))
+	for _, c := range consensus {
+		annotation := &models.SavedAnnotation{Key: c.Key}
+		annotation.Meta.Marks = c.Marks
+		result[c.Key] = annotation
+	}
+	return result, nil
+}
+
+// loadSource 按任务解析包和 items（与任务详情使用相同的解析逻辑），并加载标注
+func (es *ExportService) loadSource(tasks []models.Task) (*exportSource, error) {
+	taskService := NewTaskService()
+	src := &exportSource{}
+	seen := make(map[string]bool)
+	taskIDs := make([]int64, len(tasks))
+
+	for i := range tasks {
+		pkg, items, err := taskService.resolveTaskItems(&tasks[i])
+		if err != nil {
+			return nil, err
+		}
+		src.Package = pkg
+		taskIDs[i] = tasks[i].ID
+		for _, key := range items {
+			if !seen[key] {
+				seen[key] = true
+				src.Items = append(src.Items, key)
+			}
+		}
+	}
+
+	annotations, err := es.loadAnnotations(taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	src.Annotations = annotations
+	return src, nil
+}
+
+// loadAnnotations 获取任务的标注，按 key 索引
+func (es *ExportService) loadAnnotations(taskIDs []int64) (map[string]*models.SavedAnnotation, error) {
+	var annotations []models.SavedAnnotation
+	if err := config.DB.In("task_id", taskIDs).Find(&annotations); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*models.SavedAnnotation, len(annotations))
+	for i := range annotations {
+		result[annotations[i].Key] = &annotations[i]
+	}
+	return result, nil
+}
+
+// ExportTaskCOCO 将任务的标注导出为 COCO instances 格式
+func (es *ExportService) ExportTaskCOCO(taskID int64) (*models.CocoDataset, error) {
+	src, err := es.loadTaskSource(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return es.buildCOCO(src)
+}
+
+// ExportPackageCOCO 将包的标注导出为 COCO instances 格式
+func (es *ExportService) ExportPackageCOCO(packageID int64) (*models.CocoDataset, error) {
+	src, err := es.loadPackageSource(packageID)
+	if err != nil {
+		return nil, err
+	}
+	return es.buildCOCO(src)
+}
+
+// buildCOCO 生成 COCO 数据集，图片尺寸优先取存储桶对象索引，索引中没有时从存储读取
+func (es *ExportService) buildCOCO(src *exportSource) (*models.CocoDataset, error) {
+	sizes, err := loadIndexedImageSizes(src.Package.BucketID, src.Items)
+	if err != nil {
+		return nil, err
+	}
+	var sizeReader *ImageSizeReader
+
+	dataset := &models.CocoDataset{
+		Info: models.CocoInfo{
+			Description: src.Package.Name,
+			Version:     "1.0",
+			DateCreated: time.Now().Format(time.RFC3339),
+		},
+		Images:      make([]models.CocoImage, 0, len(src.Items)),
+		Annotations: make([]models.CocoAnnotation, 0),
+		Categories:  make([]models.CocoCategory, 0),
+	}
+
+	categories, categoryIndex := es.collectCategories(src)
+	for i, name := range categories {
+		dataset.Categories = append(dataset.Categories, models.CocoCategory{
+			ID:            i + 1,
+			Name:          name,
+			Supercategory: "none",
+		})
+	}
+
+	var annotationID int64
+
+	for i, key := range src.Items {
+		size, ok := sizes[key]
+		if !ok {
+			if sizeReader == nil {
+				if sizeReader, err = NewBucketService().NewImageSizeReader(src.Package.BucketID); err != nil {
+					return nil, err
+				}
+			}
+			if size.width, size.height, err = sizeReader.Size(key); err != nil {
+				return nil, err
+			}
+		}
+		image := models.CocoImage{
+			ID:       int64(i + 1),
+			FileName: key,
+			Width:    size.width,
+			Height:   size.height,
+		}
+
+		annotation, has := src.Annotations[key]
+		if has {
+			image.TaskID = annotation.TaskID
+			if annotation.Review != nil {
+				score := annotation.Review.Score
+				image.ReviewScore = &score
+				image.ReviewComment = annotation.Review.Comment
+				image.ReviewerID = annotation.Review.ReviewerID
+				image.ReviewedAt = annotation.Review.ReviewedAt
+			}
+		}
+		dataset.Images = append(dataset.Images, image)
+		if !has {
+			continue
+		}
+
+		for _, mark := range annotation.Meta.Marks {
+			shape, ok := exportShape(mark)
+			if !ok {
+				continue
+			}
+
+			polygon := shape.Polygon()
+			segmentation := make([]float64, 0, len(polygon)*2)
+			for _, p := range polygon {
+				segmentation = append(segmentation, p.X, p.Y)
+			}
+			bbox := shape.BoundingBox()
+
+			annotationID++
+			dataset.Annotations = append(dataset.Annotations, models.CocoAnnotation{
+				ID:           annotationID,
+				ImageID:      image.ID,
+				CategoryID:   categoryIndex[markCategory(mark)] + 1,
+				Segmentation: [][]float64{segmentation},
+				Area:         shape.Area(),
+				BBox:         []float64{bbox.X, bbox.Y, bbox.Width, bbox.Height},
+				IsCrowd:      0,
+			})
+		}
+	}
+
+	return dataset, nil
+}
+
+// imageSize 图片宽高
+type imageSize struct {
+	width, height int
+}
+
+// indexedSizeBatch 每次从对象索引中查询的 key 数
+const indexedSizeBatch = 500
+
+// loadIndexedImageSizes 从存储桶对象索引中批量读取图片尺寸，没有索引或尺寸未知的 key 不在结果中
+func loadIndexedImageSizes(bucketID int64, keys []string) (map[string]imageSize, error) {
+	sizes := make(map[string]imageSize, len(keys))
+	for start := 0; start < len(keys); start += indexedSizeBatch {
+		end := start + indexedSizeBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+		hashes := make([]string, 0, end-start)
+		for _, key := range keys[start:end] {
+			hashes = append(hashes, objectKeyHash(key))
+		}
+
+		var objects []models.BucketObject
+		err := config.DB.Where("bucket_id = ? AND deleted = ? AND width > 0 AND height > 0", bucketID, false).
+			In("key_hash", hashes).Cols("key", "width", "height").Find(&objects)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			sizes[obj.Key] = imageSize{obj.Width, obj.Height}
+		}
+	}
+	return sizes, nil
+}
+
+// collectCategories 按条目顺序收集所有可导出标记的类别，返回类别列表及其下标
+func (es *ExportService) collectCategories(src *exportSource) ([]string, map[string]int) {
+	categories := make([]string, 0)
+	index := make(map[string]int)
+	for _, key := range src.Items {
+		annotation, has := src.Annotations[key]
+		if !has {
+			continue
+		}
+		for _, mark := range annotation.Meta.Marks {
+			if _, ok := exportShape(mark); !ok {
+				continue
+			}
+			name := markCategory(mark)
+			if _, exists := index[name]; !exists {
+				index[name] = len(categories)
+				categories = append(categories, name)
+			}
+		}
+	}
+	return categories, index
+}
+
+// archiveWriter 导出归档写入器
+type archiveWriter interface {
+	WriteFile(name string, data []byte) error
+	Close() error
+}
+
+// newArchiveWriter 根据归档格式创建写入器，默认 zip
+func newArchiveWriter(format models.ArchiveFormat, w io.Writer) archiveWriter {
+	if format == models.ArchiveFormatTar {
+		return &tarArchive{tw: tar.NewWriter(w)}
+	}
+	return &zipArchive{zw: zip.NewWriter(w)}
+}
+
+// zipArchive zip 归档
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchive) WriteFile(name string, data []byte) error {
+	fw, err := a.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
+
+// tarArchive tar 归档
+type tarArchive struct {
+	tw *tar.Writer
+}
+
+func (a *tarArchive) WriteFile(name string, data []byte) error {
+	err := a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = a.tw.Write(data)
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	return a.tw.Close()
+}
+
+// archivePath 将对象 key 转换为归档内的相对路径，并替换扩展名
+// key 会先清理，绝对路径或清理后跳出归档目录的 key 不允许写入归档
+func archivePath(dir, key, ext string) (string, error) {
+	name := strings.ReplaceAll(strings.TrimSuffix(key, path.Ext(key)), "\\", "/")
+	if path.IsAbs(name) {
+		return "", fmt.Errorf("对象路径不能是绝对路径: %s", key)
+	}
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("对象路径超出归档目录: %s", key)
+	}
+	return dir + "/" + name + ext, nil
+}
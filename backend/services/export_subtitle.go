@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strings"
+
+	"luma-ai-backend/models"
+)
+
+// ExportTaskSubtitle 将任务中单个视频的片段标注导出为字幕文件，返回文件名和内容
+func (es *ExportService) ExportTaskSubtitle(taskID int64, format models.ExportFormat, key string) (string, []byte, error) {
+	src, err := es.loadTaskSource(taskID)
+	if err != nil {
+		return "", nil, err
+	}
+	return es.buildSubtitleFile(src, format, key)
+}
+
+// ExportPackageSubtitle 将包中单个视频的片段标注导出为字幕文件，返回文件名和内容
+func (es *ExportService) ExportPackageSubtitle(packageID int64, format models.ExportFormat, key string) (string, []byte, error) {
+	src, err := es.loadPackageSource(packageID)
+	if err != nil {
+		return "", nil, err
+	}
+	return es.buildSubtitleFile(src, format, key)
+}
+
+// ExportTaskSubtitles 将任务中所有视频的片段标注导出为字幕归档
+func (es *ExportService) ExportTaskSubtitles(taskID int64, format models.ExportFormat, archive models.ArchiveFormat, w io.Writer) error {
+	src, err := es.loadTaskSource(taskID)
+	if err != nil {
+		return err
+	}
+	return es.writeSubtitles(src, format, archive, w)
+}
+
+// ExportPackageSubtitles 将包中所有视频的片段标注导出为字幕归档
+func (es *ExportService) ExportPackageSubtitles(packageID int64, format models.ExportFormat, archive models.ArchiveFormat, w io.Writer) error {
+	src, err := es.loadPackageSource(packageID)
+	if err != nil {
+		return err
+	}
+	return es.writeSubtitles(src, format, archive, w)
+}
+
+// buildSubtitleFile 生成单个视频的字幕文件
+func (es *ExportService) buildSubtitleFile(src *exportSource, format models.ExportFormat, key string) (string, []byte, error) {
+	annotation, has := src.Annotations[key]
+	if !has {
+		return "", nil, errors.New("该条目没有标注")
+	}
+	segments := models.ParseVideoSegments(annotation.Meta.Marks)
+	if len(segments) == 0 {
+		return "", nil, errors.New("该条目没有视频片段标注")
+	}
+
+	name := strings.TrimSuffix(path.Base(key), path.Ext(key)) + "." + string(format)
+	return name, renderSubtitle(format, segments), nil
+}
+
+// writeSubtitles 为每个有视频片段标注的条目写出一个字幕文件
+func (es *ExportService) writeSubtitles(src *exportSource, format models.ExportFormat, archiveFormat models.ArchiveFormat, w io.Writer) error {
+	archive := newArchiveWriter(archiveFormat, w)
+
+	for _, key := range src.Items {
+		annotation, has := src.Annotations[key]
+		if !has {
+			continue
+		}
+		segments := models.ParseVideoSegments(annotation.Meta.Marks)
+		if len(segments) == 0 {
+			continue
+		}
+		name, err := archivePath("subtitles", key, "."+string(format))
+		if err != nil {
+			return err
+		}
+		if err := archive.WriteFile(name, renderSubtitle(format, segments)); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// renderSubtitle 按 WebVTT 或 SRT 格式渲染片段
+func renderSubtitle(format models.ExportFormat, segments []models.VideoSegment) []byte {
+	var sb strings.Builder
+	if format == models.ExportFormatVTT {
+		sb.WriteString("WEBVTT\n\n")
+	}
+
+	for i, seg := range segments {
+		start, end := seg.Start, seg.End
+		if end < start {
+			start, end = end, start
+		}
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1,
+			subtitleTimestamp(format, start), subtitleTimestamp(format, end), subtitleText(seg.Text))
+	}
+	return []byte(sb.String())
+}
+
+// subtitleTimestamp 格式化时间戳：VTT 为 00:00:00.000，SRT 为 00:00:00,000
+func subtitleTimestamp(format models.ExportFormat, seconds float64) string {
+	ms := int64(math.Round(math.Max(0, seconds) * 1000))
+	h := ms / 3600000
+	m := ms % 3600000 / 60000
+	s := ms % 60000 / 1000
+	ms = ms % 1000
+
+	sep := ","
+	if format == models.ExportFormatVTT {
+		sep = "."
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", h, m, s, sep, ms)
+}
+
+// subtitleText 清理字幕文本：空行会提前结束字幕块，"-->" 在 VTT 中不允许出现
+func subtitleText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	cleaned := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.ReplaceAll(line, "-->", "->"))
+		if line != "" {
+			cleaned = append(cleaned, line)
+		}
+	}
+	return strings.Join(cleaned, "\n")
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"path"
+
+	"luma-ai-backend/models"
+)
+
+// ExportTaskVOC 将任务的标注导出为 Pascal VOC 归档
+func (es *ExportService) ExportTaskVOC(taskID int64, archive models.ArchiveFormat, w io.Writer) error {
+	src, err := es.loadTaskSource(taskID)
+	if err != nil {
+		return err
+	}
+	return es.writeVOC(src, archive, w)
+}
+
+// ExportPackageVOC 将包的标注导出为 Pascal VOC 归档
+func (es *ExportService) ExportPackageVOC(packageID int64, archive models.ArchiveFormat, w io.Writer) error {
+	src, err := es.loadPackageSource(packageID)
+	if err != nil {
+		return err
+	}
+	return es.writeVOC(src, archive, w)
+}
+
+// writeVOC 为每个有标注的条目写出一个 Annotations/*.xml
+func (es *ExportService) writeVOC(src *exportSource, format models.ArchiveFormat, w io.Writer) error {
+	sizeReader, err := NewBucketService().NewImageSizeReader(src.Package.BucketID)
+	if err != nil {
+		return err
+	}
+
+	archive := newArchiveWriter(format, w)
+
+	for _, key := range src.Items {
+		annotation, has := src.Annotations[key]
+		if !has || len(annotation.Meta.Marks) == 0 {
+			continue
+		}
+
+		width, height, err := sizeReader.Size(key)
+		if err != nil {
+			return err
+		}
+
+		doc := models.VocAnnotation{
+			Folder:   path.Dir(key),
+			Filename: path.Base(key),
+			Path:     key,
+			Source:   models.VocSource{Database: src.Package.Name},
+			Size:     models.VocSize{Width: width, Height: height, Depth: 3},
+			Objects:  make([]models.VocObject, 0, len(annotation.Meta.Marks)),
+		}
+
+		for _, mark := range annotation.Meta.Marks {
+			shape, ok := exportShape(mark)
+			if !ok {
+				continue
+			}
+			// rect 直接使用，circle / polygon 使用其外接矩形
+			bbox := shape.BoundingBox()
+			doc.Objects = append(doc.Objects, models.VocObject{
+				Name: markCategory(mark),
+				Pose: "Unspecified",
+				BndBox: models.VocBndBox{
+					XMin: vocCoord(bbox.X+1, width),
+					YMin: vocCoord(bbox.Y+1, height),
+					XMax: vocCoord(bbox.X+bbox.Width, width),
+					YMax: vocCoord(bbox.Y+bbox.Height, height),
+				},
+			})
+		}
+
+		data, err := xml.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		name, err := archivePath("Annotations", key, ".xml")
+		if err != nil {
+			return err
+		}
+		if err = archive.WriteFile(name, append(data, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// vocCoord 将像素坐标取整并限制在图片范围内（VOC 坐标从 1 开始，max 为闭区间）
+func vocCoord(value float64, size int) int {
+	return int(math.Max(1, math.Min(float64(size), math.Round(value))))
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"luma-ai-backend/models"
+)
+
+// ExportTaskYOLO 将任务的标注导出为 YOLO zip 包
+func (es *ExportService) ExportTaskYOLO(taskID int64, w io.Writer) error {
+	src, err := es.loadTaskSource(taskID)
+	if err != nil {
+		return err
+	}
+	return es.writeYOLO(src, w)
+}
+
+// ExportPackageYOLO 将包的标注导出为 YOLO zip 包
+func (es *ExportService) ExportPackageYOLO(packageID int64, w io.Writer) error {
+	src, err := es.loadPackageSource(packageID)
+	if err != nil {
+		return err
+	}
+	return es.writeYOLO(src, w)
+}
+
+// writeYOLO 写出 Ultralytics 格式：每个条目一个 labels/*.txt，外加 data.yaml
+func (es *ExportService) writeYOLO(src *exportSource, w io.Writer) error {
+	categories, categoryIndex := es.collectCategories(src)
+
+	// SavedAnnotation.Meta 中没有图片尺寸，需要从存储桶读取
+	sizeReader, err := NewBucketService().NewImageSizeReader(src.Package.BucketID)
+	if err != nil {
+		return err
+	}
+
+	archive := newArchiveWriter(models.ArchiveFormatZip, w)
+
+	for _, key := range src.Items {
+		var lines []string
+		if annotation, has := src.Annotations[key]; has && len(annotation.Meta.Marks) > 0 {
+			width, height, err := sizeReader.Size(key)
+			if err != nil {
+				return err
+			}
+
+			for _, mark := range annotation.Meta.Marks {
+				shape, ok := exportShape(mark)
+				if !ok {
+					continue
+				}
+				classID := categoryIndex[markCategory(mark)]
+
+				if mark.Type == models.MarkTypePolygon {
+					// 多边形导出为 YOLO-seg 格式：class x1 y1 x2 y2 ...
+					fields := []string{strconv.Itoa(classID)}
+					for _, p := range shape.Polygon() {
+						fields = append(fields, yoloCoord(p.X, width), yoloCoord(p.Y, height))
+					}
+					lines = append(lines, strings.Join(fields, " "))
+					continue
+				}
+
+				// 矩形和圆形导出为检测框：class cx cy w h
+				bbox := shape.BoundingBox()
+				cx := bbox.X + bbox.Width/2
+				cy := bbox.Y + bbox.Height/2
+				lines = append(lines, fmt.Sprintf("%d %s %s %s %s", classID,
+					yoloCoord(cx, width), yoloCoord(cy, height),
+					yoloCoord(bbox.Width, width), yoloCoord(bbox.Height, height)))
+			}
+		}
+
+		// 没有标注的条目也输出空文件，作为负样本
+		var content string
+		if len(lines) > 0 {
+			content = strings.Join(lines, "\n") + "\n"
+		}
+		name, err := archivePath("labels", key, ".txt")
+		if err != nil {
+			return err
+		}
+		if err = archive.WriteFile(name, []byte(content)); err != nil {
+			return err
+		}
+	}
+
+	if err = archive.WriteFile("data.yaml", []byte(yoloDataYAML(categories))); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// yoloCoord 将像素坐标归一化到 [0, 1]
+func yoloCoord(value float64, size int) string {
+	if size <= 0 {
+		return "0"
+	}
+	normalized := math.Max(0, math.Min(1, value/float64(size)))
+	return strconv.FormatFloat(normalized, 'f', 6, 64)
+}
+
+// yoloDataYAML 生成 data.yaml 内容
+func yoloDataYAML(categories []string) string {
+	var sb strings.Builder
+	sb.WriteString("path: .\n")
+	sb.WriteString("train: images\n")
+	sb.WriteString("val: images\n")
+	sb.WriteString(fmt.Sprintf("nc: %d\n", len(categories)))
+	sb.WriteString("names:\n")
+	for i, name := range categories {
+		sb.WriteString(fmt.Sprintf("  %d: %s\n", i, strconv.Quote(name)))
+	}
+	return sb.String()
+}
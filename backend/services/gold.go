@@ -0,0 +1,246 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// GoldService 金标准条目服务
+type GoldService struct{}
+
+// NewGoldService 创建金标准服务实例
+func NewGoldService() *GoldService {
+	return &GoldService{}
+}
+
+// SetGoldItems 替换包的金标准条目并设置准确率阈值，条目必须属于该包
+func (gs *GoldService) SetGoldItems(packageID int64, req models.SetGoldItemsReq, adminID int64) (*models.GoldItemsResponse, error) {
+	pkg := &models.Package{}
+	has, err := config.DB.ID(packageID).Get(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("包不存在")
+	}
+
+	var items []string
+	if pkg.Items != "" {
+		if err = json.Unmarshal([]byte(pkg.Items), &items); err != nil {
+			return nil, err
+		}
+	}
+	inPackage := make(map[string]bool, len(items))
+	for _, key := range items {
+		inPackage[key] = true
+	}
+
+	seen := make(map[string]bool, len(req.Items))
+	for _, item := range req.Items {
+		if !inPackage[item.Key] {
+			return nil, fmt.Errorf("条目 %s 不在包中", item.Key)
+		}
+		if seen[item.Key] {
+			return nil, fmt.Errorf("条目 %s 重复", item.Key)
+		}
+		seen[item.Key] = true
+		if errs := validateMarks(pkg.Schema, item.Marks); len(errs) > 0 {
+			return nil, &AnnotationValidationError{Errors: errs}
+		}
+	}
+
+	action := req.Action
+	if action == "" {
+		action = models.GoldActionFlag
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+
+	if _, err = session.Where("package_id = ?", packageID).Delete(new(models.GoldItem)); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	for _, item := range req.Items {
+		gold := &models.GoldItem{
+			PackageID: packageID,
+			Key:       item.Key,
+			Marks:     item.Marks,
+			CreatedBy: adminID,
+		}
+		if _, err = session.Insert(gold); err != nil {
+			session.Rollback()
+			return nil, err
+		}
+	}
+
+	pkg.GoldThreshold = req.Threshold
+	pkg.GoldAction = action
+	if _, err = session.ID(packageID).Cols("gold_threshold", "gold_action").Update(pkg); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+	return gs.GetGoldItems(packageID)
+}
+
+// GetGoldItems 获取包的金标准设置
+func (gs *GoldService) GetGoldItems(packageID int64) (*models.GoldItemsResponse, error) {
+	pkg := &models.Package{}
+	has, err := config.DB.ID(packageID).Get(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("包不存在")
+	}
+
+	items := make([]models.GoldItem, 0)
+	if err = config.DB.Where("package_id = ?", packageID).Asc("id").Find(&items); err != nil {
+		return nil, err
+	}
+
+	return &models.GoldItemsResponse{
+		Threshold: pkg.GoldThreshold,
+		Action:    pkg.GoldAction,
+		Items:     items,
+	}, nil
+}
+
+// ListGoldScores 分页获取标注员的金标准准确率历史，最新的在前
+func (gs *GoldService) ListGoldScores(userID int64, req models.GoldScoreListRequest) (*models.GoldScoreListResponse, error) {
+	session := config.DB.Where("user_id = ?", userID)
+	if req.PackageID > 0 {
+		session = session.And("package_id = ?", req.PackageID)
+	}
+
+	var list []models.GoldScore
+	total, err := session.Desc("id").Limit(req.PageSize, (req.Page-1)*req.PageSize).FindAndCount(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	meanSession := config.DB.Where("user_id = ?", userID)
+	if req.PackageID > 0 {
+		meanSession = meanSession.And("package_id = ?", req.PackageID)
+	}
+	sums, err := meanSession.Sums(new(models.GoldScore), "accuracy")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.GoldScoreListResponse{
+		List:  list,
+		Total: total,
+	}
+	if total > 0 {
+		response.Mean = sums[0] / float64(total)
+	}
+	return response, nil
+}
+
+// mixGoldItems 把包的金标准条目混入任务的条目中，位置由任务和条目决定，
+// 同一任务每次得到相同的顺序，不同任务之间位置不同
+func mixGoldItems(task *models.Task, items []string) ([]string, error) {
+	var golds []models.GoldItem
+	if err := config.DB.Where("package_id = ?", task.PackageID).Asc("id").Find(&golds); err != nil {
+		return nil, err
+	}
+	if len(golds) == 0 {
+		return items, nil
+	}
+
+	has := make(map[string]bool, len(items))
+	for _, key := range items {
+		has[key] = true
+	}
+	mixed := append(make([]string, 0, len(items)+len(golds)), items...)
+	for _, gold := range golds {
+		if has[gold.Key] {
+			continue
+		}
+		h := fnv.New32a()
+		fmt.Fprintf(h, "%d:%s", task.ID, gold.Key)
+		pos := int(h.Sum32() % uint32(len(mixed)+1))
+		mixed = append(mixed, "")
+		copy(mixed[pos+1:], mixed[pos:])
+		mixed[pos] = gold.Key
+	}
+	return mixed, nil
+}
+
+// ScoreTask 在事务中将任务中金标准条目的标注与参考标注比较并记录准确率，包没有金标准条目时返回 nil
+// 准确率低于包设置的阈值时，返回的记录中 Action 为需要执行的处理
+func (gs *GoldService) ScoreTask(session *xorm.Session, task *models.Task, pkg *models.Package) (*models.GoldScore, error) {
+	var golds []models.GoldItem
+	if err := session.Where("package_id = ?", pkg.ID).Find(&golds); err != nil {
+		return nil, err
+	}
+	if len(golds) == 0 {
+		return nil, nil
+	}
+
+	candidate, err := loadTaskMarks(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	acc := &metricsAccumulator{keys: len(golds)}
+	for _, gold := range golds {
+		acc.add(gold.Marks, candidate[gold.Key])
+	}
+	metrics := acc.result()
+
+	score := &models.GoldScore{
+		TaskID:    task.ID,
+		PackageID: pkg.ID,
+		UserID:    task.Annotator,
+		GoldItems: len(golds),
+		Precision: metrics.Detection.Precision,
+		Recall:    metrics.Detection.Recall,
+		Accuracy:  acc.accuracy(),
+		Threshold: pkg.GoldThreshold,
+	}
+	if pkg.GoldThreshold > 0 && score.Accuracy < pkg.GoldThreshold {
+		score.Action = pkg.GoldAction
+		if score.Action == "" {
+			score.Action = models.GoldActionFlag
+		}
+	}
+	if _, err = session.Insert(score); err != nil {
+		return nil, err
+	}
+	return score, nil
+}
+
+// notifyGoldScore 金标准准确率过低时发送通知：驳回时通知标注员，标记时通知管理员和审核员
+func (gs *GoldService) notifyGoldScore(task *models.Task, score *models.GoldScore) error {
+	sysMsgService := NewSysMsgService()
+	switch score.Action {
+	case models.GoldActionReject:
+		_, err := sysMsgService.CreateSysMsg(&models.SysMsgCreateRequest{
+			Title:   "任务被自动驳回",
+			Content: fmt.Sprintf("任务 %s 的抽检准确率为 %.0f%%，低于要求的 %.0f%%，请重新检查后提交 [任务ID: %d]", task.Name, score.Accuracy*100, score.Threshold*100, task.ID),
+			UserID:  task.Annotator,
+		})
+		return err
+	case models.GoldActionFlag:
+		_, err := sysMsgService.CreateSysMsgForRoles("任务抽检准确率过低",
+			fmt.Sprintf("任务 %s 的抽检准确率为 %.0f%%，低于要求的 %.0f%%，已标记为需要重点审核 [任务ID: %d]", task.Name, score.Accuracy*100, score.Threshold*100, task.ID),
+			models.RoleAdmin, models.RoleReviewer)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+)
+
+// 导入格式
+const (
+	ImportFormatCOCO = "coco"
+	ImportFormatYOLO = "yolo"
+)
+
+// importMarkColor 导入标记使用的默认颜色，与前端一致
+const importMarkColor = "#ff0000"
+
+// ImportService 预标注导入服务
+type ImportService struct{}
+
+// NewImportService 创建导入服务实例
+func NewImportService() *ImportService {
+	return &ImportService{}
+}
+
+// cocoImportFile 导入用的 COCO 文件，segmentation 可能是多边形数组或 RLE 对象
+type cocoImportFile struct {
+	Images []struct {
+		ID       int64  `json:"id"`
+		FileName string `json:"file_name"`
+	} `json:"images"`
+	Annotations []struct {
+		ImageID      int64           `json:"image_id"`
+		CategoryID   int             `json:"category_id"`
+		BBox         []float64       `json:"bbox"`
+		Segmentation json.RawMessage `json:"segmentation"`
+	} `json:"annotations"`
+	Categories []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"categories"`
+}
+
+// itemMatcher 按相对路径将导入条目匹配到任务 items
+type itemMatcher struct {
+	byKey    map[string]string
+	bySuffix map[string]string // 不含扩展名的路径后缀 -> key，多个 key 共用同一后缀时为空
+}
+
+// newItemMatcher 创建匹配器，为每个 key 的各级路径后缀建立索引
+// 如 a/b/img.jpg 可以由 a/b/img、b/img、img 匹配，不同目录下的同名文件不会互相覆盖
+func newItemMatcher(items []string) *itemMatcher {
+	m := &itemMatcher{
+		byKey:    make(map[string]string, len(items)),
+		bySuffix: make(map[string]string, len(items)),
+	}
+	for _, key := range items {
+		m.byKey[key] = key
+		segments := pathSegments(key)
+		for i := range segments {
+			suffix := strings.Join(segments[i:], "/")
+			if existing, ok := m.bySuffix[suffix]; ok && existing != key {
+				m.bySuffix[suffix] = ""
+				continue
+			}
+			m.bySuffix[suffix] = key
+		}
+	}
+	return m
+}
+
+// Match 优先按完整 key 匹配，其次从最长的路径后缀开始匹配，后缀对应多个 key 时不匹配
+func (m *itemMatcher) Match(name string) (string, bool) {
+	if key, ok := m.byKey[name]; ok {
+		return key, true
+	}
+	segments := pathSegments(name)
+	for i := range segments {
+		key, ok := m.bySuffix[strings.Join(segments[i:], "/")]
+		if !ok {
+			continue
+		}
+		return key, key != ""
+	}
+	return "", false
+}
+
+// pathSegments 返回不含扩展名的路径的各级名称
+func pathSegments(name string) []string {
+	name = strings.Trim(strings.ReplaceAll(name, "\\", "/"), "/")
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return strings.Split(name, "/")
+}
+
+// ImportAnnotations 将 COCO JSON 或 YOLO zip 导入为任务的预标注
+func (is *ImportService) ImportAnnotations(taskID int64, format, filename string, data []byte, userID int64, userRole string) (*models.AnnotationImportResult, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(taskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+	if task.Status != models.TaskStatusCreated && task.Status != models.TaskStatusProcessing {
+		return nil, errors.New("只有 created/processing 状态的任务可以导入预标注")
+	}
+
+	pkg, items, err := NewTaskService().resolveTaskItems(task)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		switch strings.ToLower(path.Ext(filename)) {
+		case ".json":
+			format = ImportFormatCOCO
+		case ".zip":
+			format = ImportFormatYOLO
+		}
+	}
+
+	result := &models.AnnotationImportResult{
+		Imported:  make([]string, 0),
+		Unmatched: make([]string, 0),
+		Conflicts: make([]string, 0),
+		Invalid:   make([]string, 0),
+		Failed:    make([]string, 0),
+	}
+
+	var marks map[string][]models.MarkData
+	matcher := newItemMatcher(items)
+	switch format {
+	case ImportFormatCOCO:
+		marks, err = is.parseCOCO(data, matcher, result)
+	case ImportFormatYOLO:
+		marks, err = is.parseYOLO(data, matcher, pkg.BucketID, result)
+	default:
+		return nil, errors.New("不支持的导入格式，请上传 COCO JSON 或 YOLO zip")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+
+	// 已有标注的条目不覆盖，记为冲突；在事务中加锁读取，避免与并发保存同时写入
+	var existing []models.SavedAnnotation
+	if err = session.Where("task_id = ?", taskID).Cols("key").ForUpdate().Find(&existing); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	annotated := make(map[string]bool, len(existing))
+	for _, annotation := range existing {
+		annotated[annotation.Key] = true
+	}
+
+	for _, key := range items {
+		itemMarks, ok := marks[key]
+		if !ok || len(itemMarks) == 0 {
+			continue
+		}
+		if annotated[key] {
+			result.Conflicts = append(result.Conflicts, key)
+			continue
+		}
+
+		annotation := &models.SavedAnnotation{
+			TaskID: taskID,
+			Key:    key,
+			Source: models.AnnotationSourceImport,
+		}
+		annotation.Meta.BucketID = pkg.BucketID
+		annotation.Meta.Marks = itemMarks
+		if _, err = session.Insert(annotation); err != nil {
+			session.Rollback()
+			return nil, err
+		}
+		if err = recordAnnotationRevision(session, annotation, models.RevisionActionImport, userID, userRole, 0); err != nil {
+			session.Rollback()
+			return nil, err
+		}
+		result.Imported = append(result.Imported, key)
+	}
+
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseCOCO 解析 COCO instances 文件
+func (is *ImportService) parseCOCO(data []byte, matcher *itemMatcher, result *models.AnnotationImportResult) (map[string][]models.MarkData, error) {
+	file := &cocoImportFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("COCO 文件解析失败: %v", err)
+	}
+
+	categories := make(map[int]string, len(file.Categories))
+	for _, category := range file.Categories {
+		categories[category.ID] = category.Name
+	}
+
+	imageKeys := make(map[int64]string, len(file.Images))
+	marks := make(map[string][]models.MarkData)
+	for _, image := range file.Images {
+		key, ok := matcher.Match(image.FileName)
+		if !ok {
+			result.Unmatched = append(result.Unmatched, image.FileName)
+			continue
+		}
+		imageKeys[image.ID] = key
+	}
+
+	for i, annotation := range file.Annotations {
+		key, ok := imageKeys[annotation.ImageID]
+		if !ok {
+			continue
+		}
+		label := categories[annotation.CategoryID]
+		if label == "" {
+			label = strconv.Itoa(annotation.CategoryID)
+		}
+
+		// 优先使用多边形分割，RLE 或缺失时退回到 bbox
+		var polygons [][]float64
+		if len(annotation.Segmentation) > 0 && annotation.Segmentation[0] == '[' {
+			_ = json.Unmarshal(annotation.Segmentation, &polygons)
+		}
+		if len(polygons) > 0 && len(polygons[0]) >= 6 {
+			points := make([]models.Point, 0, len(polygons[0])/2)
+			for j := 0; j+1 < len(polygons[0]); j += 2 {
+				points = append(points, models.Point{X: polygons[0][j], Y: polygons[0][j+1]})
+			}
+			marks[key] = append(marks[key], polygonMark(points, label))
+		} else if len(annotation.BBox) == 4 {
+			marks[key] = append(marks[key], rectMark(annotation.BBox[0], annotation.BBox[1], annotation.BBox[2], annotation.BBox[3], label))
+		} else {
+			result.Invalid = append(result.Invalid, fmt.Sprintf("annotations[%d]", i))
+		}
+	}
+
+	return marks, nil
+}
+
+// parseYOLO 解析 YOLO zip：*.txt 标签文件，可选 data.yaml 提供类别名称
+func (is *ImportService) parseYOLO(data []byte, matcher *itemMatcher, bucketID int64, result *models.AnnotationImportResult) (map[string][]models.MarkData, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("zip 文件解析失败: %v", err)
+	}
+
+	var names []string
+	labels := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		switch {
+		case path.Base(f.Name) == "data.yaml" || path.Base(f.Name) == "data.yml":
+			content, err := readZipFile(f)
+			if err != nil {
+				return nil, err
+			}
+			names = parseYOLONames(string(content))
+		case strings.EqualFold(path.Ext(f.Name), ".txt"):
+			key, ok := matcher.Match(f.Name)
+			if !ok {
+				result.Unmatched = append(result.Unmatched, f.Name)
+				continue
+			}
+			if other, exists := labels[key]; exists {
+				result.Invalid = append(result.Invalid, fmt.Sprintf("%s: 与 %s 对应同一条目", f.Name, other.Name))
+				continue
+			}
+			labels[key] = f
+		}
+	}
+	if len(labels) == 0 {
+		return map[string][]models.MarkData{}, nil
+	}
+
+	// YOLO 坐标是归一化的，需要图片尺寸还原为像素坐标
+	sizeReader, err := NewBucketService().NewImageSizeReader(bucketID)
+	if err != nil {
+		return nil, err
+	}
+
+	marks := make(map[string][]models.MarkData, len(labels))
+	for key, f := range labels {
+		// 单个文件读取失败只记录原因，继续导入其他文件
+		content, err := readZipFile(f)
+		if err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", f.Name, err))
+			continue
+		}
+		width, height, err := sizeReader.Size(key)
+		if err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", f.Name, err))
+			continue
+		}
+
+		itemMarks := make([]models.MarkData, 0)
+		for n, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			values, err := parseFloats(fields[1:])
+			classID, convErr := strconv.Atoi(fields[0])
+			if err != nil || convErr != nil || (len(values) != 4 && (len(values) < 6 || len(values)%2 != 0)) {
+				result.Invalid = append(result.Invalid, fmt.Sprintf("%s:%d", f.Name, n+1))
+				continue
+			}
+
+			label := strconv.Itoa(classID)
+			if classID >= 0 && classID < len(names) {
+				label = names[classID]
+			}
+
+			w, h := float64(width), float64(height)
+			if len(values) == 4 {
+				// class cx cy w h
+				bw, bh := values[2]*w, values[3]*h
+				itemMarks = append(itemMarks, rectMark(values[0]*w-bw/2, values[1]*h-bh/2, bw, bh, label))
+				continue
+			}
+			// YOLO-seg：class x1 y1 x2 y2 ...
+			points := make([]models.Point, 0, len(values)/2)
+			for j := 0; j+1 < len(values); j += 2 {
+				points = append(points, models.Point{X: values[j] * w, Y: values[j+1] * h})
+			}
+			itemMarks = append(itemMarks, polygonMark(points, label))
+		}
+		marks[key] = itemMarks
+	}
+
+	return marks, nil
+}
+
+// parseYOLONames 从 data.yaml 中读取 names，支持列表、映射和行内数组三种写法
+func parseYOLONames(content string) []string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "names:") {
+			continue
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(trimmed, "names:"))
+		if strings.HasPrefix(rest, "[") {
+			parts := strings.Split(strings.Trim(rest, "[]"), ",")
+			names := make([]string, 0, len(parts))
+			for _, part := range parts {
+				names = append(names, unquoteYAML(part))
+			}
+			return names
+		}
+
+		indexed := make(map[int]string)
+		var listed []string
+		for _, next := range lines[i+1:] {
+			item := strings.TrimSpace(next)
+			if item == "" || strings.HasPrefix(item, "#") {
+				continue
+			}
+			if !strings.HasPrefix(next, " ") && !strings.HasPrefix(next, "\t") && !strings.HasPrefix(item, "-") {
+				break
+			}
+			if strings.HasPrefix(item, "-") {
+				listed = append(listed, unquoteYAML(strings.TrimPrefix(item, "-")))
+				continue
+			}
+			if idx, name, ok := strings.Cut(item, ":"); ok {
+				if n, err := strconv.Atoi(strings.TrimSpace(idx)); err == nil {
+					indexed[n] = unquoteYAML(name)
+				}
+			}
+		}
+		if len(listed) > 0 {
+			return listed
+		}
+		names := make([]string, len(indexed))
+		for n, name := range indexed {
+			if n >= 0 && n < len(names) {
+				names[n] = name
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// unquoteYAML 去掉 YAML 标量两侧的空白和引号
+func unquoteYAML(value string) string {
+	value = strings.TrimSpace(value)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return strings.Trim(value, `'"`)
+}
+
+// readZipFile 读取 zip 中的文件内容
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// parseFloats 将字符串列表解析为浮点数
+func parseFloats(fields []string) ([]float64, error) {
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// rectMark 生成与前端格式一致的矩形标记
+func rectMark(x, y, width, height float64, label string) models.MarkData {
+	return models.MarkData{
+		Type: models.MarkTypeRect,
+		Data: &models.RectMark{
+			X:         x,
+			Y:         y,
+			Width:     width,
+			Height:    height,
+			MarkLabel: importMarkLabel(label),
+		},
+	}
+}
+
+// polygonMark 生成与前端格式一致的多边形标记
+func polygonMark(points []models.Point, label string) models.MarkData {
+	return models.MarkData{
+		Type: models.MarkTypePolygon,
+		Data: &models.PolygonMark{
+			Points:    points,
+			MarkLabel: importMarkLabel(label),
+		},
+	}
+}
+
+// importMarkLabel 导入标记的显示文本和类别
+func importMarkLabel(label string) models.MarkLabel {
+	return models.MarkLabel{Color: importMarkColor, Text: label, Class: label}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"luma-ai-backend/models"
+)
+
+// AnnotationValidationError 标注校验失败，包含字段级错误
+type AnnotationValidationError struct {
+	Errors []models.FieldError
+}
+
+func (e *AnnotationValidationError) Error() string {
+	return fmt.Sprintf("标注数据校验失败，共 %d 处错误", len(e.Errors))
+}
+
+// knownMarkTypes 系统支持的标记类型
+var knownMarkTypes = map[string]bool{
+	models.MarkTypeRect:     true,
+	models.MarkTypeCircle:   true,
+	models.MarkTypePolygon:  true,
+	models.MarkTypePolyline: true,
+	models.MarkTypePoint:    true,
+	models.MarkTypeKeypoint: true,
+	models.MarkTypeVideo:    true,
+}
+
+// validateLabelSchema 校验包的标注规范本身是否合法
+func validateLabelSchema(schema *models.LabelSchema) error {
+	if schema == nil {
+		return nil
+	}
+	for _, t := range schema.MarkTypes {
+		if !knownMarkTypes[t] {
+			return fmt.Errorf("不支持的标记类型: %s", t)
+		}
+	}
+	names := make(map[string]bool, len(schema.Classes))
+	for _, class := range schema.Classes {
+		name := strings.TrimSpace(class.Name)
+		if name == "" {
+			return errors.New("类别名称不能为空")
+		}
+		if names[name] {
+			return fmt.Errorf("类别名称重复: %s", name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// validateMarks 校验标记的类型、几何形状，以及包标注规范中的类别和必填属性
+// schema 为空时只校验类型和几何形状
+func validateMarks(schema *models.LabelSchema, marks []models.MarkData) []models.FieldError {
+	var errs []models.FieldError
+	addErr := func(field, message string) {
+		errs = append(errs, models.FieldError{Field: field, Message: message})
+	}
+
+	for i, mark := range marks {
+		field := fmt.Sprintf("meta.marks[%d]", i)
+
+		if mark.IsVideoMark() {
+			if schema != nil && !schema.AllowsMarkType(models.MarkTypeVideo) {
+				addErr(field+".type", "该包不允许视频片段标记")
+				continue
+			}
+			for j, seg := range mark.VideoSegments() {
+				if seg.Start < 0 || seg.End <= seg.Start {
+					addErr(fmt.Sprintf("%s.data[%d]", field, j), "片段的结束时间必须大于开始时间")
+				}
+			}
+			continue
+		}
+
+		if !knownMarkTypes[mark.Type] || mark.Type == models.MarkTypeVideo {
+			addErr(field+".type", fmt.Sprintf("不支持的标记类型: %s", mark.Type))
+			continue
+		}
+		if schema != nil && !schema.AllowsMarkType(mark.Type) {
+			addErr(field+".type", fmt.Sprintf("该包不允许 %s 标记", mark.Type))
+			continue
+		}
+
+		switch data := mark.Data.(type) {
+		case *models.RectMark:
+			bbox := data.BoundingBox()
+			if bbox.Width <= 0 {
+				addErr(field+".data.width", "宽度不能为 0")
+			}
+			if bbox.Height <= 0 {
+				addErr(field+".data.height", "高度不能为 0")
+			}
+		case *models.CircleMark:
+			if data.Radius <= 0 {
+				addErr(field+".data.radius", "半径必须大于 0")
+			}
+		case *models.PolygonMark:
+			if len(data.Points) < 3 {
+				addErr(field+".data.points", "多边形至少需要 3 个点")
+			}
+		case *models.PolylineMark:
+			if len(data.Points) < 2 {
+				addErr(field+".data.points", "折线至少需要 2 个点")
+			}
+		case *models.PointMark:
+		default:
+			// 与 type 不符的数据在解析时保留为原始结构
+			addErr(field+".data", "标记数据格式错误")
+			continue
+		}
+
+		if schema == nil || len(schema.Classes) == 0 {
+			continue
+		}
+		label := mark.Label()
+		className := label.ClassName()
+		if className == "" {
+			addErr(field+".data.class", "类别不能为空")
+			continue
+		}
+		class, ok := schema.FindClass(className)
+		if !ok {
+			addErr(field+".data.class", fmt.Sprintf("类别不在标注规范中: %s", className))
+			continue
+		}
+		for _, attr := range class.RequiredAttributes {
+			value, ok := label.Attributes[attr]
+			if !ok || value == nil || strings.TrimSpace(fmt.Sprint(value)) == "" {
+				addErr(fmt.Sprintf("%s.data.attributes.%s", field, attr), fmt.Sprintf("类别 %s 必须填写属性 %s", class.Name, attr))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// leaseDeadline 从现在开始的租约到期时间，未启用租约时为 nil
+func leaseDeadline() *time.Time {
+	if config.TaskLeaseTTL <= 0 {
+		return nil
+	}
+	deadline := time.Now().Add(config.TaskLeaseTTL)
+	return &deadline
+}
+
+// renewTaskLease 在事务中续期任务的领取租约，任务处于 processing/reviewing 时有效
+func renewTaskLease(session *xorm.Session, task *models.Task) error {
+	task.LeaseExpiresAt = leaseDeadline()
+	_, err := session.ID(task.ID).Cols("lease_expires_at").Update(task)
+	return err
+}
+
+// ReleaseExpiredTasks 释放租约过期的任务：标注中的回到 created，审核中的回到 processed，返回释放的任务数
+func (ts *TaskService) ReleaseExpiredTasks() (int, error) {
+	var tasks []models.Task
+	err := config.DB.Where("lease_expires_at < ?", time.Now()).
+		In("status", models.TaskStatusProcessing, models.TaskStatusReviewing).
+		Asc("id").Find(&tasks)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for i := range tasks {
+		task := &tasks[i]
+		to := models.TaskStatusCreated
+		if task.Status == models.TaskStatusReviewing {
+			to = models.TaskStatusProcessed
+		}
+		// 扫描后用户可能刚刚操作过或提交了任务，此时跳过
+		if _, err := ts.fireTransition([]string{taskActionRelease}, task, to, 0, roleSystem, 0); err != nil {
+			log.Printf("释放任务 %d 失败: %v", task.ID, err)
+			continue
+		}
+		released++
+	}
+	return released, nil
+}
+
+// backfillTaskLeases 为启用租约前已被领取、还没有租约的任务补上租约，从现在开始计算
+func backfillTaskLeases() (int64, error) {
+	return config.DB.In("status", models.TaskStatusProcessing, models.TaskStatusReviewing).
+		Where("lease_expires_at IS NULL").
+		Cols("lease_expires_at").Update(&models.Task{LeaseExpiresAt: leaseDeadline()})
+}
+
+// StartLeaseReaper 启动后台任务，定期释放租约过期的任务，未启用租约时不启动
+func StartLeaseReaper() {
+	if config.TaskLeaseTTL <= 0 {
+		return
+	}
+	// 没有租约的任务永远不会被释放
+	if filled, err := backfillTaskLeases(); err != nil {
+		log.Printf("补充任务租约失败: %v", err)
+	} else if filled > 0 {
+		log.Printf("已为 %d 个进行中的任务补充租约", filled)
+	}
+	go func() {
+		ticker := time.NewTicker(config.TaskLeaseScanInterval)
+		defer ticker.Stop()
+		ts := NewTaskService()
+		for range ticker.C {
+			released, err := ts.ReleaseExpiredTasks()
+			if err != nil {
+				log.Printf("检查过期任务租约失败: %v", err)
+				continue
+			}
+			if released > 0 {
+				log.Printf("已释放 %d 个租约过期的任务", released)
+			}
+		}
+	}()
+}
@@ -0,0 +1,479 @@
+package services
+
+import (
+	"errors"
+	"sort"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+)
+
+// metricsIoUThreshold 两个标记或片段被视为同一目标的最小交并比
+const metricsIoUThreshold = 0.5
+
+// MetricsService 标注质量指标服务
+type MetricsService struct{}
+
+// NewMetricsService 创建指标服务实例
+func NewMetricsService() *MetricsService {
+	return &MetricsService{}
+}
+
+// TaskMetrics 将任务的标注与对照任务或包的合并结果比较
+func (ms *MetricsService) TaskMetrics(taskID int64, req models.TaskMetricsReq) (*models.TaskMetricsResponse, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(taskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+
+	ts := NewTaskService()
+	_, items, err := ts.resolveTaskItems(task)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := loadTaskMarks(task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.TaskMetricsResponse{TaskID: task.ID}
+	var reference map[string][]models.MarkData
+	if req.ReferenceTaskID > 0 {
+		refTask := &models.Task{}
+		has, err = config.DB.ID(req.ReferenceTaskID).Get(refTask)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			return nil, errors.New("对照任务不存在")
+		}
+		if refTask.PackageID != task.PackageID {
+			return nil, errors.New("对照任务必须属于同一个包")
+		}
+		_, refItems, err := ts.resolveTaskItems(refTask)
+		if err != nil {
+			return nil, err
+		}
+		items = intersectKeys(items, refItems)
+		if reference, err = loadTaskMarks(refTask.ID); err != nil {
+			return nil, err
+		}
+		response.Reference = models.MetricsReferenceTask
+		response.ReferenceTaskID = refTask.ID
+	} else {
+		if task.Replica == 0 {
+			return nil, errors.New("该任务不是重叠标注任务，请指定对照任务")
+		}
+		if reference, err = loadConsensusMarks(task.PackageID, items); err != nil {
+			return nil, err
+		}
+		if len(reference) == 0 {
+			return nil, errors.New("该任务所在分片还没有合并结果")
+		}
+		items = intersectKeys(items, mapKeys(reference))
+		response.Reference = models.MetricsReferenceConsensus
+	}
+
+	acc := &metricsAccumulator{keys: len(items)}
+	for _, key := range items {
+		acc.add(reference[key], candidate[key])
+	}
+	response.Metrics = acc.result()
+	return response, nil
+}
+
+// AnnotatorMetrics 汇总标注员所有已提交的重叠标注任务与合并结果的比较
+func (ms *MetricsService) AnnotatorMetrics(userID int64, req models.AnnotatorMetricsReq) (*models.AnnotatorMetricsResponse, error) {
+	session := config.DB.Where("annotator = ? AND replica > 0", userID).
+		In("status", models.TaskStatusProcessed, models.TaskStatusReviewing, models.TaskStatusApproved)
+	if req.PackageID > 0 {
+		session = session.And("package_id = ?", req.PackageID)
+	}
+	var tasks []models.Task
+	if err := session.Asc("id").Find(&tasks); err != nil {
+		return nil, err
+	}
+
+	ts := NewTaskService()
+	response := &models.AnnotatorMetricsResponse{UserID: userID}
+	acc := &metricsAccumulator{}
+	for i := range tasks {
+		_, items, err := ts.resolveTaskItems(&tasks[i])
+		if err != nil {
+			return nil, err
+		}
+		reference, err := loadConsensusMarks(tasks[i].PackageID, items)
+		if err != nil {
+			return nil, err
+		}
+		// 分片还没有合并结果时跳过
+		if len(reference) == 0 {
+			continue
+		}
+		candidate, err := loadTaskMarks(tasks[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		keys := intersectKeys(items, mapKeys(reference))
+		for _, key := range keys {
+			acc.add(reference[key], candidate[key])
+		}
+		acc.keys += len(keys)
+		response.Tasks++
+	}
+	response.Metrics = acc.result()
+	return response, nil
+}
+
+// PackageMetrics 统计包内各分片已提交副本之间的两两一致性，以及所有副本的 Fleiss' kappa
+func (ms *MetricsService) PackageMetrics(packageID int64) (*models.PackageMetricsResponse, error) {
+	pkg := &models.Package{}
+	has, err := config.DB.ID(packageID).Get(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("包不存在")
+	}
+	if pkg.Overlap < 2 {
+		return nil, errors.New("该包未开启重叠标注")
+	}
+
+	var replicas []models.Task
+	err = config.DB.Where("package_id = ? AND replica > 0", packageID).
+		In("status", models.TaskStatusProcessed, models.TaskStatusReviewing, models.TaskStatusApproved).
+		Asc("chunk_idx").Asc("replica").Find(&replicas)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(map[int][]models.Task)
+	var order []int
+	for _, task := range replicas {
+		if _, ok := chunks[task.ChunkIdx]; !ok {
+			order = append(order, task.ChunkIdx)
+		}
+		chunks[task.ChunkIdx] = append(chunks[task.ChunkIdx], task)
+	}
+
+	ts := NewTaskService()
+	response := &models.PackageMetricsResponse{PackageID: packageID}
+	acc := &metricsAccumulator{}
+	var subjects []map[string]int
+	for _, chunkIdx := range order {
+		tasks := chunks[chunkIdx]
+		if len(tasks) < 2 {
+			continue
+		}
+		_, items, err := ts.resolveTaskItems(&tasks[0])
+		if err != nil {
+			return nil, err
+		}
+		marks := make([]map[string][]models.MarkData, len(tasks))
+		for i, task := range tasks {
+			if marks[i], err = loadTaskMarks(task.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, key := range items {
+			perAnnotator := make([][]models.MarkData, len(tasks))
+			for i := range tasks {
+				perAnnotator[i] = marks[i][key]
+			}
+			for i := 0; i < len(tasks); i++ {
+				for j := i + 1; j < len(tasks); j++ {
+					acc.add(perAnnotator[i], perAnnotator[j])
+				}
+			}
+			// 只有所有副本都标出的目标参与类别一致性统计，漏标由检测指标反映
+			for _, cluster := range clusterMarks(perAnnotator) {
+				if len(cluster.members) < len(tasks) {
+					continue
+				}
+				counts := make(map[string]int)
+				for _, m := range cluster.members {
+					counts[m.class]++
+				}
+				subjects = append(subjects, counts)
+			}
+		}
+		acc.keys += len(items)
+		response.Chunks++
+		response.Pairs += len(tasks) * (len(tasks) - 1) / 2
+	}
+
+	response.Metrics = acc.result()
+	response.Metrics.FleissKappa = fleissKappa(subjects)
+	return response, nil
+}
+
+// loadTaskMarks 按条目获取任务已保存的标记
+func loadTaskMarks(taskID int64) (map[string][]models.MarkData, error) {
+	var annotations []models.SavedAnnotation
+	if err := config.DB.Where("task_id = ?", taskID).Find(&annotations); err != nil {
+		return nil, err
+	}
+	marks := make(map[string][]models.MarkData, len(annotations))
+	for _, annotation := range annotations {
+		marks[annotation.Key] = annotation.Meta.Marks
+	}
+	return marks, nil
+}
+
+// loadConsensusMarks 按条目获取包的合并结果，只返回有合并结果的条目
+func loadConsensusMarks(packageID int64, keys []string) (map[string][]models.MarkData, error) {
+	marks := make(map[string][]models.MarkData)
+	if len(keys) == 0 {
+		return marks, nil
+	}
+	var list []models.ConsensusAnnotation
+	if err := config.DB.Where("package_id = ?", packageID).In("key", keys).Find(&list); err != nil {
+		return nil, err
+	}
+	for _, consensus := range list {
+		marks[consensus.Key] = consensus.Marks
+	}
+	return marks, nil
+}
+
+// intersectKeys 保持 a 的顺序返回同时出现在 b 中的条目
+func intersectKeys(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, key := range b {
+		inB[key] = true
+	}
+	keys := make([]string, 0, len(a))
+	for _, key := range a {
+		if inB[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// mapKeys 返回标记映射中的所有条目
+func mapKeys(marks map[string][]models.MarkData) []string {
+	keys := make([]string, 0, len(marks))
+	for key := range marks {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// metricsAccumulator 逐条目累计两组标注的比较结果，keys 由调用方按参与比较的条目数累计
+type metricsAccumulator struct {
+	keys          int
+	truePositive  int
+	falsePositive int
+	falseNegative int
+	classPairs    [][2]string
+	refSegments   int
+	candSegments  int
+	matchedSegs   int
+	segmentIoUSum float64
+	segmentSlots  int
+}
+
+// add 比较同一条目的对照标记和被评估标记
+func (acc *metricsAccumulator) add(reference, candidate []models.MarkData) {
+	// 有面积的标记按交并比一对一贪心匹配
+	type shaped struct {
+		shape models.Shape
+		class string
+	}
+	collect := func(marks []models.MarkData) []shaped {
+		var list []shaped
+		for _, mark := range marks {
+			if shape, ok := exportShape(mark); ok {
+				list = append(list, shaped{shape, markCategory(mark)})
+			}
+		}
+		return list
+	}
+	refShapes, candShapes := collect(reference), collect(candidate)
+
+	type pair struct {
+		ref, cand int
+		iou       float64
+	}
+	var pairs []pair
+	for i, r := range refShapes {
+		for j, c := range candShapes {
+			if iou := models.IoU(r.shape, c.shape); iou >= metricsIoUThreshold {
+				pairs = append(pairs, pair{i, j, iou})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		return pairs[a].iou > pairs[b].iou
+	})
+	usedRef := make([]bool, len(refShapes))
+	usedCand := make([]bool, len(candShapes))
+	matched := 0
+	for _, p := range pairs {
+		if usedRef[p.ref] || usedCand[p.cand] {
+			continue
+		}
+		usedRef[p.ref], usedCand[p.cand] = true, true
+		matched++
+		acc.classPairs = append(acc.classPairs, [2]string{refShapes[p.ref].class, candShapes[p.cand].class})
+	}
+	acc.truePositive += matched
+	acc.falseNegative += len(refShapes) - matched
+	acc.falsePositive += len(candShapes) - matched
+
+	// 视频片段按时间交并比一对一贪心匹配
+	refSegs := models.ParseVideoSegments(reference)
+	candSegs := models.ParseVideoSegments(candidate)
+	var segPairs []pair
+	for i, r := range refSegs {
+		for j, c := range candSegs {
+			if iou := r.IoU(c); iou > 0 {
+				segPairs = append(segPairs, pair{i, j, iou})
+			}
+		}
+	}
+	sort.SliceStable(segPairs, func(a, b int) bool {
+		return segPairs[a].iou > segPairs[b].iou
+	})
+	usedRef = make([]bool, len(refSegs))
+	usedCand = make([]bool, len(candSegs))
+	paired := 0
+	for _, p := range segPairs {
+		if usedRef[p.ref] || usedCand[p.cand] {
+			continue
+		}
+		usedRef[p.ref], usedCand[p.cand] = true, true
+		paired++
+		acc.segmentIoUSum += p.iou
+		if p.iou >= metricsIoUThreshold {
+			acc.matchedSegs++
+		}
+	}
+	acc.refSegments += len(refSegs)
+	acc.candSegments += len(candSegs)
+	acc.segmentSlots += len(refSegs) + len(candSegs) - paired
+}
+
+// result 汇总累计的比较结果
+func (acc *metricsAccumulator) result() models.QualityMetrics {
+	metrics := models.QualityMetrics{
+		Keys: acc.keys,
+		Detection: models.DetectionMetrics{
+			TruePositive:  acc.truePositive,
+			FalsePositive: acc.falsePositive,
+			FalseNegative: acc.falseNegative,
+		},
+		CohenKappa: cohenKappa(acc.classPairs),
+		Temporal: models.TemporalMetrics{
+			ReferenceSegments: acc.refSegments,
+			CandidateSegments: acc.candSegments,
+			Matched:           acc.matchedSegs,
+		},
+	}
+
+	d := &metrics.Detection
+	if predicted := d.TruePositive + d.FalsePositive; predicted > 0 {
+		d.Precision = float64(d.TruePositive) / float64(predicted)
+	}
+	if actual := d.TruePositive + d.FalseNegative; actual > 0 {
+		d.Recall = float64(d.TruePositive) / float64(actual)
+	}
+	if d.Precision+d.Recall > 0 {
+		d.F1 = 2 * d.Precision * d.Recall / (d.Precision + d.Recall)
+	}
+	if acc.segmentSlots > 0 {
+		metrics.Temporal.MeanIoU = acc.segmentIoUSum / float64(acc.segmentSlots)
+	}
+	return metrics
+}
+
+// accuracy 以对照标注为准的综合得分：类别正确的匹配标记计 1，配对的视频片段计其时间交并比，
+// 按 Dice 系数归一化到 0-1，两边都没有标记时为 1
+func (acc *metricsAccumulator) accuracy() float64 {
+	correct := 0
+	for _, p := range acc.classPairs {
+		if p[0] == p[1] {
+			correct++
+		}
+	}
+	total := 2*acc.truePositive + acc.falsePositive + acc.falseNegative + acc.refSegments + acc.candSegments
+	if total == 0 {
+		return 1
+	}
+	return 2 * (float64(correct) + acc.segmentIoUSum) / float64(total)
+}
+
+// cohenKappa 两名标注员对同一组目标分类的 Cohen's kappa，没有样本时返回 nil
+func cohenKappa(pairs [][2]string) *float64 {
+	if len(pairs) == 0 {
+		return nil
+	}
+	n := float64(len(pairs))
+	agree := 0
+	first := make(map[string]int)
+	second := make(map[string]int)
+	for _, p := range pairs {
+		if p[0] == p[1] {
+			agree++
+		}
+		first[p[0]]++
+		second[p[1]]++
+	}
+
+	observed := float64(agree) / n
+	expected := 0.0
+	for class, count := range first {
+		expected += float64(count) / n * float64(second[class]) / n
+	}
+	kappa := 1.0
+	if expected < 1 {
+		kappa = (observed - expected) / (1 - expected)
+	}
+	return &kappa
+}
+
+// fleissKappa 多名标注员分类的 Fleiss' kappa，每个样本为各类别的得票数，没有样本时返回 nil
+func fleissKappa(subjects []map[string]int) *float64 {
+	totals := make(map[string]int)
+	ratings := 0
+	agreement := 0.0
+	counted := 0
+	for _, counts := range subjects {
+		n := 0
+		sumSquares := 0
+		for _, count := range counts {
+			n += count
+			sumSquares += count * count
+		}
+		if n < 2 {
+			continue
+		}
+		for class, count := range counts {
+			totals[class] += count
+		}
+		ratings += n
+		agreement += float64(sumSquares-n) / float64(n*(n-1))
+		counted++
+	}
+	if counted == 0 {
+		return nil
+	}
+
+	observed := agreement / float64(counted)
+	expected := 0.0
+	for _, count := range totals {
+		p := float64(count) / float64(ratings)
+		expected += p * p
+	}
+	kappa := 1.0
+	if expected < 1 {
+		kappa = (observed - expected) / (1 - expected)
+	}
+	return &kappa
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"luma-ai-backend/models"
+)
+
+func TestCohenKappa(t *testing.T) {
+	if cohenKappa(nil) != nil {
+		t.Error("没有样本时应返回 nil")
+	}
+
+	// 两名评审对 50 个样本的判断：都为是 20，A 是 B 否 5，A 否 B 是 10，都为否 15，
+	// 观察一致率 0.7，期望一致率 0.5，kappa 为 0.4
+	var pairs [][2]string
+	add := func(a, b string, n int) {
+		for i := 0; i < n; i++ {
+			pairs = append(pairs, [2]string{a, b})
+		}
+	}
+	add("yes", "yes", 20)
+	add("yes", "no", 5)
+	add("no", "yes", 10)
+	add("no", "no", 15)
+	if got := cohenKappa(pairs); got == nil || math.Abs(*got-0.4) > 1e-9 {
+		t.Errorf("kappa 为 %v，期望 0.4", got)
+	}
+
+	// 完全一致时为 1，只有一个类别时期望一致率为 1，同样视为完全一致
+	if got := cohenKappa([][2]string{{"a", "a"}, {"b", "b"}}); got == nil || *got != 1 {
+		t.Errorf("完全一致时 kappa 为 %v，期望 1", got)
+	}
+	if got := cohenKappa([][2]string{{"a", "a"}, {"a", "a"}}); got == nil || *got != 1 {
+		t.Errorf("只有一个类别时 kappa 为 %v，期望 1", got)
+	}
+}
+
+func TestFleissKappa(t *testing.T) {
+	if fleissKappa(nil) != nil {
+		t.Error("没有样本时应返回 nil")
+	}
+
+	// Fleiss (1971) 的示例：14 名评审把 10 个样本分到 5 个类别，kappa 约为 0.210
+	table := [][5]int{
+		{0, 0, 0, 0, 14},
+		{0, 2, 6, 4, 2},
+		{0, 0, 3, 5, 6},
+		{0, 3, 9, 2, 0},
+		{2, 2, 8, 1, 1},
+		{7, 7, 0, 0, 0},
+		{3, 2, 6, 3, 0},
+		{2, 5, 3, 2, 2},
+		{6, 5, 2, 1, 0},
+		{0, 2, 2, 3, 7},
+	}
+	classes := []string{"1", "2", "3", "4", "5"}
+	subjects := make([]map[string]int, 0, len(table))
+	for _, row := range table {
+		counts := make(map[string]int)
+		for i, count := range row {
+			if count > 0 {
+				counts[classes[i]] = count
+			}
+		}
+		subjects = append(subjects, counts)
+	}
+	if got := fleissKappa(subjects); got == nil || math.Abs(*got-0.210) > 0.001 {
+		t.Errorf("kappa 为 %v，期望约 0.210", got)
+	}
+
+	// 少于两票的样本不参与计算
+	if fleissKappa([]map[string]int{{"a": 1}}) != nil {
+		t.Error("只有一票的样本不应参与计算")
+	}
+}
+
+func TestMetricsAccumulatorDetection(t *testing.T) {
+	rect := func(x, y float64, class string) models.MarkData {
+		return models.MarkData{Type: models.MarkTypeRect, Data: &models.RectMark{
+			X: x, Y: y, Width: 10, Height: 10, MarkLabel: models.MarkLabel{Class: class},
+		}}
+	}
+	reference := []models.MarkData{rect(0, 0, "car"), rect(100, 100, "car"), rect(200, 200, "person")}
+	candidate := []models.MarkData{
+		rect(0, 0, "car"),       // 与对照完全重合
+		rect(102, 100, "truck"), // 交并比 8/12，类别不同
+		rect(500, 500, "car"),   // 没有对应的对照标记
+	}
+
+	acc := &metricsAccumulator{keys: 1}
+	acc.add(reference, candidate)
+	metrics := acc.result()
+
+	d := metrics.Detection
+	if d.TruePositive != 2 || d.FalsePositive != 1 || d.FalseNegative != 1 {
+		t.Fatalf("TP/FP/FN 为 %d/%d/%d，期望 2/1/1", d.TruePositive, d.FalsePositive, d.FalseNegative)
+	}
+	for name, got := range map[string]float64{"precision": d.Precision, "recall": d.Recall, "f1": d.F1} {
+		if math.Abs(got-2.0/3) > 1e-9 {
+			t.Errorf("%s 为 %v，期望 2/3", name, got)
+		}
+	}
+	// 匹配上的两对类别为 car/car、car/truck，kappa 为 0
+	if metrics.CohenKappa == nil || math.Abs(*metrics.CohenKappa) > 1e-9 {
+		t.Errorf("kappa 为 %v，期望 0", metrics.CohenKappa)
+	}
+	// 类别正确的匹配 1 个，Dice 归一化为 2*1/(2*2+1+1)
+	if got := acc.accuracy(); math.Abs(got-1.0/3) > 1e-9 {
+		t.Errorf("accuracy 为 %v，期望 1/3", got)
+	}
+
+	empty := &metricsAccumulator{}
+	empty.add(nil, nil)
+	if got := empty.result().Detection; got.Precision != 0 || got.Recall != 0 || got.F1 != 0 {
+		t.Errorf("没有标记时指标应为 0，实际 %+v", got)
+	}
+	if empty.accuracy() != 1 {
+		t.Error("两边都没有标记时 accuracy 应为 1")
+	}
+}
+
+func TestVideoSegmentIoU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b models.VideoSegment
+		want float64
+	}{
+		{"相同", models.VideoSegment{Start: 0, End: 10}, models.VideoSegment{Start: 0, End: 10}, 1},
+		{"部分重叠", models.VideoSegment{Start: 0, End: 10}, models.VideoSegment{Start: 5, End: 15}, 1.0 / 3},
+		{"包含", models.VideoSegment{Start: 0, End: 10}, models.VideoSegment{Start: 2, End: 4}, 0.2},
+		{"首尾相接", models.VideoSegment{Start: 0, End: 10}, models.VideoSegment{Start: 10, End: 20}, 0},
+		{"不相交", models.VideoSegment{Start: 0, End: 10}, models.VideoSegment{Start: 20, End: 30}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.IoU(tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: IoU 为 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsAccumulatorTemporal(t *testing.T) {
+	video := func(segments ...models.VideoSegment) []models.MarkData {
+		return []models.MarkData{{Type: models.MarkTypeVideo, Data: models.VideoMark(segments)}}
+	}
+	reference := video(models.VideoSegment{Start: 0, End: 10}, models.VideoSegment{Start: 20, End: 30})
+	candidate := video(models.VideoSegment{Start: 5, End: 10}, models.VideoSegment{Start: 40, End: 50})
+
+	acc := &metricsAccumulator{keys: 1}
+	acc.add(reference, candidate)
+	temporal := acc.result().Temporal
+
+	if temporal.ReferenceSegments != 2 || temporal.CandidateSegments != 2 || temporal.Matched != 1 {
+		t.Fatalf("片段统计为 %+v，期望对照 2、被评估 2、匹配 1", temporal)
+	}
+	// 配对的片段交并比 0.5，未配对的对照和被评估片段各计 0，平均为 0.5/3
+	if math.Abs(temporal.MeanIoU-0.5/3) > 1e-9 {
+		t.Errorf("平均交并比为 %v，期望 %v", temporal.MeanIoU, 0.5/3)
+	}
+}
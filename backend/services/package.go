@@ -28,6 +28,17 @@ func (ps *PackageService) SavePackage(req *models.PackageReq) (*models.PackageRe
 		return nil, errors.New("存储桶不存在")
 	}
 
+	// 校验标注规范
+	if err = validateLabelSchema(req.Schema); err != nil {
+		return nil, err
+	}
+
+	// 未开启重叠标注时每个条目只由一名标注员标注
+	overlap := req.Overlap
+	if overlap < 1 {
+		overlap = 1
+	}
+
 	// 将 items 数组转换为 JSON 字符串
 	itemsJSON, err := json.Marshal(req.Items)
 	if err != nil {
@@ -55,6 +66,10 @@ func (ps *PackageService) SavePackage(req *models.PackageReq) (*models.PackageRe
 		pkg.Name = req.Name
 		pkg.BucketID = req.BucketID
 		pkg.Items = string(itemsJSON)
+		pkg.Schema = req.Schema
+		pkg.Overlap = overlap
+		pkg.AgreementThreshold = req.AgreementThreshold
+		pkg.SkillTags = req.SkillTags
 
 		// 检查包名是否已存在
 		count, err := config.DB.Where("name = ? AND id != ?", req.Name, *req.ID).Count(&models.Package{})
@@ -64,17 +79,22 @@ func (ps *PackageService) SavePackage(req *models.PackageReq) (*models.PackageRe
 		if count > 0 {
 			return nil, errors.New("包名已存在")
 		}
-		_, err = config.DB.ID(*req.ID).Update(pkg)
+		// 标注规范、一致性阈值和技能标签允许被清空
+		_, err = config.DB.ID(*req.ID).MustCols("label_schema", "agreement_threshold", "skill_tags").Update(pkg)
 		if err != nil {
 			return nil, err
 		}
 	} else {
 		// 创建新包
 		pkg = &models.Package{
-			Name:     req.Name,
-			BucketID: req.BucketID,
-			Items:    string(itemsJSON),
-			Status:   models.PackageStatusPending,
+			Name:               req.Name,
+			BucketID:           req.BucketID,
+			Items:              string(itemsJSON),
+			Status:             models.PackageStatusPending,
+			Schema:             req.Schema,
+			Overlap:            overlap,
+			AgreementThreshold: req.AgreementThreshold,
+			SkillTags:          req.SkillTags,
 		}
 
 		// 检查包名是否已存在
@@ -115,18 +135,32 @@ func (ps *PackageService) GetPackage(id int64) (*models.PackageResponse, error)
 		}
 	}
 
+	progress, err := ps.loadProgress([]models.Package{*pkg})
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.PackageResponse{
-		ID:        pkg.ID,
-		BucketID:  pkg.BucketID,
-		Name:      pkg.Name,
-		Items:     items,
-		Status:    pkg.Status,
-		CreatedAt: pkg.CreatedAt,
+		ID:                 pkg.ID,
+		BucketID:           pkg.BucketID,
+		Name:               pkg.Name,
+		Items:              items,
+		Status:             pkg.Status,
+		Schema:             pkg.Schema,
+		Overlap:            pkg.Overlap,
+		Progress:           progress[pkg.ID],
+		AgreementThreshold: pkg.AgreementThreshold,
+		SkillTags:          pkg.SkillTags,
+		CreatedAt:          pkg.CreatedAt,
 	}, nil
 }
 
-// PublishPackage 发布包
-func (ps *PackageService) PublishPackage(id int64) (*models.PackageResponse, error) {
+// PublishPackage 发布包，并按 chunkSize 或 taskCount 拆分创建任务
+func (ps *PackageService) PublishPackage(id int64, req models.PublishPackageReq) (*models.PackageResponse, error) {
+	if req.ChunkSize > 0 && req.TaskCount > 0 {
+		return nil, errors.New("chunkSize 和 taskCount 只能指定一个")
+	}
+
 	pkg := &models.Package{}
 	has, err := config.DB.ID(id).Get(pkg)
 	if err != nil {
@@ -141,25 +175,130 @@ func (ps *PackageService) PublishPackage(id int64) (*models.PackageResponse, err
 		return nil, errors.New("包已经是已发布状态")
 	}
 
+	// 发布和创建任务在同一事务中，任务创建失败时包保持未发布
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+
 	// 更新包状态为已发布
 	pkg.Status = models.PackageStatusPublished
-	_, err = config.DB.ID(id).Update(pkg)
+	_, err = session.ID(id).Update(pkg)
 	if err != nil {
+		session.Rollback()
 		return nil, err
 	}
 
 	// 创建任务
 	taskService := NewTaskService()
-	_, err = taskService.CreateTaskForPackage(id, pkg.Name)
-	if err != nil {
-		// 如果创建任务失败，回滚包状态？
-		// 这里我们记录错误但继续，因为包已经发布
-		// 在实际应用中可能需要更复杂的错误处理
+	if _, err = taskService.CreateTasksForPackage(session, pkg, req); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	if err = session.Commit(); err != nil {
+		return nil, err
 	}
 
 	return ps.GetPackage(id)
 }
 
+// loadProgress 按包汇总任务状态、已标注条目数和审核通过的条目数
+// 条目数按包内不同的条目统计，重叠标注的多个副本和混入的金标准条目不重复计数
+func (ps *PackageService) loadProgress(packages []models.Package) (map[int64]*models.PackageProgress, error) {
+	progress := make(map[int64]*models.PackageProgress)
+	packageIDs := make([]int64, 0, len(packages))
+	itemCounts := make(map[int64]int, len(packages))
+	itemSets := make(map[int64]map[string]bool, len(packages))
+	for _, pkg := range packages {
+		if pkg.Status != models.PackageStatusPublished {
+			continue
+		}
+		var items []string
+		if pkg.Items != "" {
+			if err := json.Unmarshal([]byte(pkg.Items), &items); err != nil {
+				return nil, err
+			}
+		}
+		packageIDs = append(packageIDs, pkg.ID)
+		itemCounts[pkg.ID] = len(items)
+		itemSets[pkg.ID] = make(map[string]bool, len(items))
+		for _, key := range items {
+			itemSets[pkg.ID][key] = true
+		}
+	}
+	if len(packageIDs) == 0 {
+		return progress, nil
+	}
+
+	var tasks []models.Task
+	if err := config.DB.In("package_id", packageIDs).Find(&tasks); err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return progress, nil
+	}
+
+	// 各包已保存标注的不同条目，只统计包内的条目
+	var saved []struct {
+		TaskID int64  `xorm:"'task_id'"`
+		Key    string `xorm:"'key'"`
+	}
+	taskIDs := make([]int64, len(tasks))
+	taskPackages := make(map[int64]int64, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+		taskPackages[task.ID] = task.PackageID
+	}
+	err := config.DB.Table(new(models.SavedAnnotation)).Select("DISTINCT task_id, `key`").
+		In("task_id", taskIDs).Find(&saved)
+	if err != nil {
+		return nil, err
+	}
+	annotatedKeys := make(map[int64]map[string]bool, len(packageIDs))
+	for _, row := range saved {
+		packageID := taskPackages[row.TaskID]
+		if !itemSets[packageID][row.Key] {
+			continue
+		}
+		if annotatedKeys[packageID] == nil {
+			annotatedKeys[packageID] = make(map[string]bool)
+		}
+		annotatedKeys[packageID][row.Key] = true
+	}
+
+	// 同一分片的多个副本只计一次
+	type chunkRange struct {
+		packageID  int64
+		start, end int
+	}
+	approvedChunks := make(map[chunkRange]bool)
+
+	for _, task := range tasks {
+		p, ok := progress[task.PackageID]
+		if !ok {
+			p = &models.PackageProgress{
+				TaskStatus: make(map[models.TaskStatus]int),
+				ItemCount:  itemCounts[task.PackageID],
+			}
+			progress[task.PackageID] = p
+		}
+		p.TaskCount++
+		p.TaskStatus[task.Status]++
+		p.AnnotatedCount = int64(len(annotatedKeys[task.PackageID]))
+		if task.Status == models.TaskStatusApproved {
+			start, end := task.ItemRange(p.ItemCount)
+			chunk := chunkRange{task.PackageID, start, end}
+			if !approvedChunks[chunk] {
+				approvedChunks[chunk] = true
+				p.ApprovedItemCount += end - start
+			}
+		}
+	}
+	return progress, nil
+}
+
 // ListPackages 获取包列表
 func (ps *PackageService) ListPackages(page, pageSize int) (*models.PackageListResponse, error) {
 	var packages []models.Package
@@ -179,6 +318,12 @@ func (ps *PackageService) ListPackages(page, pageSize int) (*models.PackageListR
 		return nil, err
 	}
 
+	// 汇总已发布包的进度
+	progress, err := ps.loadProgress(packages)
+	if err != nil {
+		return nil, err
+	}
+
 	// 转换为响应列表
 	packageItems := make([]models.PackageItem, len(packages))
 	for i, pkg := range packages {
@@ -187,6 +332,7 @@ func (ps *PackageService) ListPackages(page, pageSize int) (*models.PackageListR
 			BucketID:  pkg.BucketID,
 			Name:      pkg.Name,
 			Status:    pkg.Status,
+			Progress:  progress[pkg.ID],
 			CreatedAt: pkg.CreatedAt,
 		}
 	}
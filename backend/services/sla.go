@@ -0,0 +1,297 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// SetTaskPriority 管理员设置任务的优先级和截止时间，修改截止时间后重新发送提醒
+func (ts *TaskService) SetTaskPriority(req models.TaskPriorityRequest, adminID int64) (*models.TaskResponse, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(req.TaskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+	if err = setTaskPriority(session, task, req.Priority, req.DueAt, adminID); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+	return toTaskResponse(task), nil
+}
+
+// setTaskPriority 在事务中修改任务的优先级和截止时间并记录事件，截止时间变化时清除已发送的提醒
+func setTaskPriority(session *xorm.Session, task *models.Task, priority int, dueAt *time.Time, adminID int64) error {
+	if task.Priority != priority {
+		err := recordTaskEvent(session, &models.TaskEvent{
+			TaskID:    task.ID,
+			Type:      models.TaskEventPriority,
+			ActorID:   adminID,
+			ActorRole: models.RoleAdmin,
+			Field:     "priority",
+			OldValue:  strconv.Itoa(task.Priority),
+			NewValue:  strconv.Itoa(priority),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if formatDueAt(task.DueAt) != formatDueAt(dueAt) {
+		err := recordTaskEvent(session, &models.TaskEvent{
+			TaskID:    task.ID,
+			Type:      models.TaskEventPriority,
+			ActorID:   adminID,
+			ActorRole: models.RoleAdmin,
+			Field:     "due_at",
+			OldValue:  formatDueAt(task.DueAt),
+			NewValue:  formatDueAt(dueAt),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = session.Where("task_id = ?", task.ID).Delete(new(models.TaskSlaAlert)); err != nil {
+			return err
+		}
+	}
+
+	task.Priority = priority
+	task.DueAt = dueAt
+	_, err := session.ID(task.ID).Cols("priority", "due_at").Update(task)
+	return err
+}
+
+// formatDueAt 事件中的截止时间，未设置时为空
+func formatDueAt(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// SendSLAAlerts 为到达提醒时间点的未完成任务发送提醒，返回发送的提醒数。
+// 同一任务只提醒已到达的最晚时间点，之前错过的时间点不再补发
+func (ts *TaskService) SendSLAAlerts() (int, error) {
+	thresholds := config.TaskSLAThresholds
+	if len(thresholds) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	earliest, latest := thresholds[0], thresholds[len(thresholds)-1]
+
+	// 已经发送过最晚时间点提醒的任务不再需要检查
+	var tasks []models.Task
+	err := config.DB.Where("due_at IS NOT NULL AND due_at <= ? AND status != ?", now.Add(-earliest), models.TaskStatusApproved).
+		And("NOT EXISTS (SELECT 1 FROM task_sla_alert a WHERE a.task_id = task.id AND a.threshold = ?)", int64(latest/time.Second)).
+		Asc("id").Find(&tasks)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range tasks {
+		task := &tasks[i]
+		// 已到达的最晚时间点
+		reached := earliest
+		for _, threshold := range thresholds {
+			if !task.DueAt.Add(threshold).After(now) {
+				reached = threshold
+			}
+		}
+
+		count, err := config.DB.Where("task_id = ? AND threshold >= ?", task.ID, int64(reached/time.Second)).Count(new(models.TaskSlaAlert))
+		if err != nil {
+			return sent, err
+		}
+		if count > 0 {
+			continue
+		}
+		sentAlert, err := sendSLAAlert(task, int64(reached/time.Second), now)
+		if err != nil {
+			log.Printf("发送任务 %d 截止时间提醒失败: %v", task.ID, err)
+			continue
+		}
+		if sentAlert {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendSLAAlert 记录提醒并创建系统消息，两者在同一事务中，提交后再推送消息和发送邮件。
+// 唯一索引保证多个实例同时扫描时只有一个发送；邮件逐个发送，失败时只记录日志，不会重复发送系统消息
+func sendSLAAlert(task *models.Task, threshold int64, now time.Time) (bool, error) {
+	var assignee int64
+	switch task.Status {
+	case models.TaskStatusProcessing, models.TaskStatusRejected:
+		assignee = task.Annotator
+	case models.TaskStatusReviewing:
+		assignee = task.Reviewer
+	}
+	overdue := !task.DueAt.After(now)
+
+	title := "任务即将到期"
+	content := fmt.Sprintf("任务 %s 将于 %s 到期，当前状态 %s [任务ID: %d]",
+		task.Name, task.DueAt.Local().Format("2006-01-02 15:04"), task.Status, task.ID)
+	if overdue {
+		title = "任务已逾期"
+		content = fmt.Sprintf("任务 %s 已于 %s 到期，当前状态 %s [任务ID: %d]",
+			task.Name, task.DueAt.Local().Format("2006-01-02 15:04"), task.Status, task.ID)
+	}
+
+	// 通知任务当前的负责人，逾期或没有负责人时同时通知管理员和审核员
+	var recipients []int64
+	if assignee > 0 {
+		recipients = append(recipients, assignee)
+	}
+	if overdue || assignee == 0 {
+		var users []models.User
+		if err := config.DB.In("role", models.RoleAdmin, models.RoleReviewer).Cols("id").Find(&users); err != nil {
+			return false, err
+		}
+		for _, user := range users {
+			if user.ID != assignee {
+				recipients = append(recipients, user.ID)
+			}
+		}
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return false, err
+	}
+	if _, err := session.Insert(&models.TaskSlaAlert{TaskID: task.ID, Threshold: threshold}); err != nil {
+		// 其他实例已经发送了该提醒
+		session.Rollback()
+		return false, nil
+	}
+	msgs := make([]*models.SysMsg, 0, len(recipients))
+	for _, userID := range recipients {
+		msg, err := insertSysMsg(session, &models.SysMsgCreateRequest{Title: title, Content: content, UserID: userID})
+		if err != nil {
+			session.Rollback()
+			return false, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := session.Commit(); err != nil {
+		return false, err
+	}
+
+	sysMsgService := NewSysMsgService()
+	for _, msg := range msgs {
+		sysMsgService.pushToUser(msg)
+	}
+	if len(recipients) == 0 {
+		return true, nil
+	}
+	var users []models.User
+	if err := config.DB.In("id", recipients).Cols("id", "email").Find(&users); err != nil {
+		log.Printf("获取任务 %d 提醒邮件的收件人失败: %v", task.ID, err)
+		return true, nil
+	}
+	for _, user := range users {
+		if err := emailService.SendTaskAlert(user.Email, title, content); err != nil {
+			log.Printf("向用户 %d 发送任务 %d 提醒邮件失败: %v", user.ID, task.ID, err)
+		}
+	}
+	return true, nil
+}
+
+// StartSLAScheduler 启动后台任务，定期发送截止时间提醒，没有配置提醒时间点时不启动
+func StartSLAScheduler() {
+	if len(config.TaskSLAThresholds) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(config.TaskSLAScanInterval)
+		defer ticker.Stop()
+		ts := NewTaskService()
+		for range ticker.C {
+			sent, err := ts.SendSLAAlerts()
+			if err != nil {
+				log.Printf("检查任务截止时间失败: %v", err)
+				continue
+			}
+			if sent > 0 {
+				log.Printf("已发送 %d 个任务截止时间提醒", sent)
+			}
+		}
+	}()
+}
+
+// PackageSLA 统计包的 SLA 达成情况，packageID 为 0 时统计所有设置了截止时间的包
+func (ts *TaskService) PackageSLA(packageID int64) (*models.PackageSLAResponse, error) {
+	session := config.DB.Where("due_at IS NOT NULL")
+	if packageID > 0 {
+		session = session.And("package_id = ?", packageID)
+	}
+	var tasks []models.Task
+	if err := session.Cols("id", "package_id", "status", "due_at", "completed_at").Asc("package_id").Find(&tasks); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	list := make([]models.PackageSLA, 0)
+	index := make(map[int64]int)
+	for _, task := range tasks {
+		i, ok := index[task.PackageID]
+		if !ok {
+			i = len(list)
+			index[task.PackageID] = i
+			list = append(list, models.PackageSLA{PackageID: task.PackageID})
+		}
+		sla := &list[i]
+		sla.Tasks++
+		switch {
+		case task.Status == models.TaskStatusApproved:
+			sla.Completed++
+			// 没有记录完成时间的历史任务按按时完成统计
+			if task.CompletedAt == nil || !task.CompletedAt.After(*task.DueAt) {
+				sla.CompletedOnTime++
+			}
+		case task.DueAt.Before(now):
+			sla.Overdue++
+		default:
+			sla.Pending++
+		}
+	}
+
+	ids := make([]int64, 0, len(list))
+	for i := range list {
+		ids = append(ids, list[i].PackageID)
+		list[i].Compliance = 1
+		if n := list[i].Completed + list[i].Overdue; n > 0 {
+			list[i].Compliance = float64(list[i].CompletedOnTime) / float64(n)
+		}
+	}
+	if len(ids) > 0 {
+		var packages []models.Package
+		if err := config.DB.In("id", ids).Cols("id", "name").Find(&packages); err != nil {
+			return nil, err
+		}
+		for _, pkg := range packages {
+			list[index[pkg.ID]].PackageName = pkg.Name
+		}
+	}
+	return &models.PackageSLAResponse{List: list}, nil
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"luma-ai-backend/config"
 	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
 )
 
 // SysMsgService 系统消息服务
@@ -15,15 +17,8 @@ func NewSysMsgService() *SysMsgService {
 
 // CreateSysMsg 创建系统消息并推送给用户
 func (s *SysMsgService) CreateSysMsg(req *models.SysMsgCreateRequest) (*models.SysMsg, error) {
-	sysMsg := &models.SysMsg{
-		Title:   req.Title,
-		Content: req.Content,
-		Status:  "unread",
-		UserID:  req.UserID,
-	}
-
 	// 插入数据库
-	_, err := config.DB.Insert(sysMsg)
+	sysMsg, err := insertSysMsg(config.DB, req)
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +29,36 @@ func (s *SysMsgService) CreateSysMsg(req *models.SysMsgCreateRequest) (*models.S
 	return sysMsg, nil
 }
 
+// CreateSysMsgForRoles 为指定角色的每个用户创建系统消息，返回接收消息的用户ID
+func (s *SysMsgService) CreateSysMsgForRoles(title, content string, roles ...string) ([]int64, error) {
+	var users []models.User
+	if err := config.DB.In("role", roles).Cols("id").Find(&users); err != nil {
+		return nil, err
+	}
+	userIDs := make([]int64, 0, len(users))
+	for _, user := range users {
+		if _, err := s.CreateSysMsg(&models.SysMsgCreateRequest{Title: title, Content: content, UserID: user.ID}); err != nil {
+			return userIDs, err
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	return userIDs, nil
+}
+
+// insertSysMsg 写入系统消息但不推送，在事务中使用时由调用方在提交后推送
+func insertSysMsg(db xorm.Interface, req *models.SysMsgCreateRequest) (*models.SysMsg, error) {
+	sysMsg := &models.SysMsg{
+		Title:   req.Title,
+		Content: req.Content,
+		Status:  "unread",
+		UserID:  req.UserID,
+	}
+	if _, err := db.Insert(sysMsg); err != nil {
+		return nil, err
+	}
+	return sysMsg, nil
+}
+
 // GetSysMsgList 获取系统消息列表
 func (s *SysMsgService) GetSysMsgList(userID int64, status string, page, pageSize int, isAdmin bool) ([]models.SysMsg, int64, error) {
 	sysMsgs := make([]models.SysMsg, 0)
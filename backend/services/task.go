@@ -1,13 +1,17 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"luma-ai-backend/config"
 	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
 )
 
 // TaskService 任务服务
@@ -18,54 +22,122 @@ func NewTaskService() *TaskService {
 	return &TaskService{}
 }
 
-// CreateTaskForPackage 为包创建任务
-func (ts *TaskService) CreateTaskForPackage(packageID int64, packageName string) (*models.TaskResponse, error) {
-	// 获取包信息
-	pkg := &models.Package{}
-	has, err := config.DB.ID(packageID).Get(pkg)
-	if err != nil {
-		return nil, err
-	}
-	if !has {
-		return nil, errors.New("包不存在")
+// toTaskResponse 将任务模型转换为响应
+func toTaskResponse(task *models.Task) *models.TaskResponse {
+	return &models.TaskResponse{
+		ID:             task.ID,
+		Name:           task.Name,
+		PackageID:      task.PackageID,
+		Annotator:      task.Annotator,
+		Reviewer:       task.Reviewer,
+		Status:         task.Status,
+		WipIdx:         task.WipIdx,
+		ItemStart:      task.ItemStart,
+		ItemEnd:        task.ItemEnd,
+		ChunkIdx:       task.ChunkIdx,
+		Replica:        task.Replica,
+		Flagged:        task.Flagged,
+		ReviewRound:    task.ReviewRound,
+		Priority:       task.Priority,
+		DueAt:          task.DueAt,
+		CompletedAt:    task.CompletedAt,
+		LeaseExpiresAt: task.LeaseExpiresAt,
+		CreatedAt:      task.CreatedAt,
 	}
+}
 
+// CreateTasksForPackage 在事务中为包创建任务
+// 按 chunkSize（每个任务的条目数）或 taskCount（任务数）将包的 items 切分给多个任务，两者都为 0 时整个包作为一个任务，
+// 任务使用发布时指定的优先级和截止时间
+func (ts *TaskService) CreateTasksForPackage(session *xorm.Session, pkg *models.Package, req models.PublishPackageReq) ([]models.TaskResponse, error) {
 	// 检查是否已经存在该包的任务
-	existingTask := &models.Task{}
-	has, err = config.DB.Where("package_id = ?", packageID).Get(existingTask)
+	count, err := session.Where("package_id = ?", pkg.ID).Count(new(models.Task))
 	if err != nil {
 		return nil, err
 	}
-	if has {
+	if count > 0 {
 		return nil, errors.New("该包已存在任务")
 	}
 
-	// 创建任务
-	// 任务名称使用包名称 + "任务"
-	taskName := packageName + "任务"
+	var items []string
+	if pkg.Items != "" {
+		if err = json.Unmarshal([]byte(pkg.Items), &items); err != nil {
+			return nil, err
+		}
+	}
+
+	// 开启重叠标注时，每个分片创建 overlap 个副本任务，由不同标注员独立完成
+	overlap := pkg.Overlap
+	if overlap < 1 {
+		overlap = 1
+	}
+
+	// 任务名称使用包名称 + "任务"，拆分时附加序号
+	chunks := splitItems(len(items), req.ChunkSize, req.TaskCount)
+	responses := make([]models.TaskResponse, 0, len(chunks)*overlap)
+	for i, chunk := range chunks {
+		for replica := 1; replica <= overlap; replica++ {
+			task := &models.Task{
+				Name:      pkg.Name + "任务",
+				PackageID: pkg.ID,
+				Annotator: 0, // 未分配
+				Reviewer:  0, // 未分配
+				Status:    models.TaskStatusCreated,
+				ChunkIdx:  i,
+				Priority:  req.Priority,
+				DueAt:     req.DueAt,
+			}
+			if len(chunks) > 1 {
+				task.Name = fmt.Sprintf("%s任务 %d/%d", pkg.Name, i+1, len(chunks))
+				task.ItemStart, task.ItemEnd = chunk[0], chunk[1]
+			}
+			if overlap > 1 {
+				task.Name = fmt.Sprintf("%s #%d", task.Name, replica)
+				task.Replica = replica
+			}
 
-	task := &models.Task{
-		Name:      taskName,
-		PackageID: packageID,
-		Annotator: 0, // 未分配
-		Reviewer:  0, // 未分配
-		Status:    models.TaskStatusCreated,
+			if _, err = session.Insert(task); err != nil {
+				return nil, err
+			}
+			responses = append(responses, *toTaskResponse(task))
+		}
 	}
 
-	_, err = config.DB.Insert(task)
-	if err != nil {
-		return nil, err
+	return responses, nil
+}
+
+// splitItems 计算每个任务负责的 items 区间 [start, end)
+// 指定任务数时尽量均分，否则按 chunkSize 切分，最后一个任务可能不足 chunkSize
+func splitItems(total, chunkSize, taskCount int) [][2]int {
+	if taskCount > 1 && total > 1 {
+		if taskCount > total {
+			taskCount = total
+		}
+		chunks := make([][2]int, 0, taskCount)
+		start := 0
+		for i := 0; i < taskCount; i++ {
+			size := total / taskCount
+			if i < total%taskCount {
+				size++
+			}
+			chunks = append(chunks, [2]int{start, start + size})
+			start += size
+		}
+		return chunks
 	}
 
-	return &models.TaskResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		CreatedAt: task.CreatedAt,
-	}, nil
+	if taskCount > 0 || chunkSize <= 0 || chunkSize >= total {
+		return [][2]int{{0, total}}
+	}
+	chunks := make([][2]int, 0, (total+chunkSize-1)/chunkSize)
+	for start := 0; start < total; start += chunkSize {
+		end := start + chunkSize
+		if end > total {
+			end = total
+		}
+		chunks = append(chunks, [2]int{start, end})
+	}
+	return chunks
 }
 
 // GetTaskByPackageID 根据包ID获取任务
@@ -79,19 +151,12 @@ func (ts *TaskService) GetTaskByPackageID(packageID int64) (*models.TaskResponse
 		return nil, errors.New("任务不存在")
 	}
 
-	return &models.TaskResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		CreatedAt: task.CreatedAt,
-	}, nil
+	return toTaskResponse(task), nil
 }
 
-// UpdateTaskStatus 更新任务状态
-func (ts *TaskService) UpdateTaskStatus(taskID int64, status models.TaskStatus) (*models.TaskResponse, error) {
+// GetTaskDetail 获取任务详情（包含items）
+func (ts *TaskService) GetTaskDetail(taskID int64) (*models.TaskDetailResponse, error) {
+	// 获取任务信息
 	task := &models.Task{}
 	has, err := config.DB.ID(taskID).Get(task)
 	if err != nil {
@@ -101,27 +166,45 @@ func (ts *TaskService) UpdateTaskStatus(taskID int64, status models.TaskStatus)
 		return nil, errors.New("任务不存在")
 	}
 
-	// 更新任务状态
-	task.Status = status
-	_, err = config.DB.ID(taskID).Update(task)
+	// 获取关联的包及items
+	_, items, err := ts.resolveTaskItems(task)
 	if err != nil {
 		return nil, err
 	}
+	// 金标准条目不对标注员公开，混在任务条目中
+	if items, err = mixGoldItems(task, items); err != nil {
+		return nil, err
+	}
 
-	return &models.TaskResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		CreatedAt: task.CreatedAt,
+	// 返工中的条目附带审核意见
+	var reworks []models.SavedAnnotation
+	if err = config.DB.Where("task_id = ? AND needs_rework = ?", taskID, true).Asc("id").Find(&reworks); err != nil {
+		return nil, err
+	}
+	reworkItems := make([]models.ReworkItem, 0, len(reworks))
+	for _, annotation := range reworks {
+		reworkItems = append(reworkItems, models.ReworkItem{Key: annotation.Key, Review: annotation.Review})
+	}
+
+	return &models.TaskDetailResponse{
+		ID:             task.ID,
+		Name:           task.Name,
+		PackageID:      task.PackageID,
+		Annotator:      task.Annotator,
+		Reviewer:       task.Reviewer,
+		Status:         task.Status,
+		WipIdx:         task.WipIdx,
+		Items:          items,
+		ItemStart:      task.ItemStart,
+		ReviewRound:    task.ReviewRound,
+		LeaseExpiresAt: task.LeaseExpiresAt,
+		ReworkItems:    reworkItems,
+		CreatedAt:      task.CreatedAt,
 	}, nil
 }
 
-// GetTaskDetail 获取任务详情（包含items）
-func (ts *TaskService) GetTaskDetail(taskID int64) (*models.TaskDetailResponse, error) {
-	// 获取任务信息
+// GetTaskItemURL 生成任务条目的限时下载地址，idx 为条目在任务详情 items 中的下标，只有管理员和任务的标注员、审核员可以获取
+func (ts *TaskService) GetTaskItemURL(taskID int64, idx int, userID int64, userRole string) (*models.TaskItemURLResponse, error) {
 	task := &models.Task{}
 	has, err := config.DB.ID(taskID).Get(task)
 	if err != nil {
@@ -130,15 +213,48 @@ func (ts *TaskService) GetTaskDetail(taskID int64) (*models.TaskDetailResponse,
 	if !has {
 		return nil, errors.New("任务不存在")
 	}
+	if !canViewTaskAnnotations(task, userID, userRole) {
+		return nil, ErrTaskItemForbidden
+	}
 
-	// 获取关联的包信息
-	pkg := &models.Package{}
-	has, err = config.DB.ID(task.PackageID).Get(pkg)
+	// 与任务详情返回的 items 保持一致，包括混入的金标准条目
+	pkg, items, err := ts.resolveTaskItems(task)
 	if err != nil {
 		return nil, err
 	}
+	if items, err = mixGoldItems(task, items); err != nil {
+		return nil, err
+	}
+	if idx < 0 || idx >= len(items) {
+		return nil, errors.New("条目下标超出范围")
+	}
+
+	provider, err := NewBucketService().Provider(pkg.BucketID)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(config.StoragePresignTTL)
+	url, err := provider.PresignGet(context.Background(), items[idx], config.StoragePresignTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TaskItemURLResponse{
+		Key:       items[idx],
+		URL:       url,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// resolveTaskItems 获取任务关联的包及该任务负责的 items
+func (ts *TaskService) resolveTaskItems(task *models.Task) (*models.Package, []string, error) {
+	pkg := &models.Package{}
+	has, err := config.DB.ID(task.PackageID).Get(pkg)
+	if err != nil {
+		return nil, nil, err
+	}
 	if !has {
-		return nil, errors.New("关联的包不存在")
+		return nil, nil, errors.New("关联的包不存在")
 	}
 
 	// 解析包中的items
@@ -146,50 +262,64 @@ func (ts *TaskService) GetTaskDetail(taskID int64) (*models.TaskDetailResponse,
 	if pkg.Items != "" {
 		err = json.Unmarshal([]byte(pkg.Items), &items)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
-	return &models.TaskDetailResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		WipIdx:    task.WipIdx,
-		Items:     items,
-		CreatedAt: task.CreatedAt,
-	}, nil
+	// 拆分发布的任务只负责包中的一段
+	start, end := task.ItemRange(len(items))
+	return pkg, items[start:end], nil
 }
 
 // GetTaskList 获取任务列表（支持分页和过滤）
-func (ts *TaskService) GetTaskList(userID int64, status models.TaskStatus, page, pageSize int) (*models.TaskListResponse, error) {
+func (ts *TaskService) GetTaskList(req models.TaskListRequest) (*models.TaskListResponse, error) {
 	var tasks []models.Task
 
 	// 构建查询条件
 	session := config.DB.NewSession()
+	defer session.Close()
 
-	if userID > 0 {
+	if req.UserID > 0 {
 		// 查询用户作为 annotator 或 reviewer 的任务
-		session = session.Where("annotator = ? OR reviewer = ?", userID, userID)
+		session = session.Where("(annotator = ? OR reviewer = ?)", req.UserID, req.UserID)
 	}
 
-	if status != "" {
-		session = session.Where("status = ?", status)
+	if req.Status != "" {
+		session = session.And("status = ?", req.Status)
+	}
+	if req.PackageID > 0 {
+		session = session.And("package_id = ?", req.PackageID)
+	}
+	if req.MinPriority != nil {
+		session = session.And("priority >= ?", *req.MinPriority)
+	}
+	if req.Overdue {
+		session = session.And("due_at < ? AND status != ?", time.Now(), models.TaskStatusApproved)
 	}
 
-	// 计算偏移量
-	offset := (page - 1) * pageSize
-
-	// 获取任务列表
-	err := session.Limit(pageSize, offset).Find(&tasks)
-	if err != nil {
-		return nil, err
+	// 排序，截止时间升序时没有截止时间的任务排在最后
+	order := "ASC"
+	if req.Order == "desc" {
+		order = "DESC"
 	}
+	switch req.SortBy {
+	case "priority", "created_at":
+		session = session.OrderBy(req.SortBy + " " + order + ", id ASC")
+	case "due_at":
+		if order == "ASC" {
+			session = session.OrderBy("due_at IS NULL, due_at ASC, id ASC")
+		} else {
+			session = session.OrderBy("due_at DESC, id ASC")
+		}
+	default:
+		session = session.OrderBy("id " + order)
+	}
+
+	// 计算偏移量
+	offset := (req.Page - 1) * req.PageSize
 
-	// 获取总任务数
-	total, err := session.Count(&models.Task{})
+	// 获取任务列表和总任务数
+	total, err := session.Limit(req.PageSize, offset).FindAndCount(&tasks)
 	if err != nil {
 		return nil, err
 	}
@@ -197,15 +327,7 @@ func (ts *TaskService) GetTaskList(userID int64, status models.TaskStatus, page,
 	// 转换为响应列表
 	taskResponses := make([]models.TaskResponse, len(tasks))
 	for i, task := range tasks {
-		taskResponses[i] = models.TaskResponse{
-			ID:        task.ID,
-			Name:      task.Name,
-			PackageID: task.PackageID,
-			Annotator: task.Annotator,
-			Reviewer:  task.Reviewer,
-			Status:    task.Status,
-			CreatedAt: task.CreatedAt,
-		}
+		taskResponses[i] = *toTaskResponse(&task)
 	}
 
 	return &models.TaskListResponse{
@@ -214,7 +336,8 @@ func (ts *TaskService) GetTaskList(userID int64, status models.TaskStatus, page,
 	}, nil
 }
 
-// ClaimTask 用户领取任务
+// ClaimTask 用户领取指定任务：标注员领取 created 任务，审核员领取 processed 任务，
+// 通过带条件的 UPDATE 保证任务只会被一个用户领取
 func (ts *TaskService) ClaimTask(taskID, userID int64, userRole string) (*models.TaskResponse, error) {
 	task := &models.Task{}
 	has, err := config.DB.ID(taskID).Get(task)
@@ -235,50 +358,23 @@ func (ts *TaskService) ClaimTask(taskID, userID int64, userRole string) (*models
 		return nil, errors.New("用户不存在")
 	}
 
-	// 根据任务状态和用户角色验证领取权限
-	if task.Status == models.TaskStatusCreated {
-		// created 状态的任务只能由 annotator 领取
-		if userRole != models.RoleAnnotator {
-			return nil, errors.New("只有标注员可以领取 created 状态的任务")
-		}
-		// 检查是否已经有标注员
-		if task.Annotator > 0 {
-			return nil, errors.New("任务已经被标注员领取")
-		}
-		task.Status = models.TaskStatusProcessing
-		task.Annotator = userID
-	} else if task.Status == models.TaskStatusProcessed {
-		// processed 状态的任务只能由 reviewer 领取
-		if userRole != models.RoleReviewer {
-			return nil, errors.New("只有审核员可以领取 processed 状态的任务")
-		}
-		// 检查是否已经有审核员
-		if task.Reviewer > 0 {
-			return nil, errors.New("任务已经被审核员领取")
-		}
-		task.Status = models.TaskStatusReviewing
-		task.Reviewer = userID
-	} else {
+	var to models.TaskStatus
+	switch task.Status {
+	case models.TaskStatusCreated:
+		to = models.TaskStatusProcessing
+	case models.TaskStatusProcessed:
+		to = models.TaskStatusReviewing
+	default:
 		return nil, errors.New("该状态的任务不能被领取")
 	}
 
-	_, err = config.DB.ID(taskID).Update(task)
-	if err != nil {
+	if _, err = ts.fireTransition([]string{taskActionClaim}, task, to, userID, userRole, userID); err != nil {
 		return nil, err
 	}
-
-	return &models.TaskResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		CreatedAt: task.CreatedAt,
-	}, nil
+	return toTaskResponse(task), nil
 }
 
-// UpdateTaskStatusWithValidation 更新任务状态（带权限验证和系统消息）
+// UpdateTaskStatusWithValidation 更新任务状态（带权限验证和系统消息），转换规则见 task_state.go
 func (ts *TaskService) UpdateTaskStatusWithValidation(taskID, userID int64, userRole string, newStatus models.TaskStatus) (*models.TaskResponse, error) {
 	task := &models.Task{}
 	has, err := config.DB.ID(taskID).Get(task)
@@ -289,81 +385,67 @@ func (ts *TaskService) UpdateTaskStatusWithValidation(taskID, userID int64, user
 		return nil, errors.New("任务不存在")
 	}
 
-	// 检查用户是否有权限更新任务状态
-	if userRole != models.RoleAdmin {
-		if userRole == models.RoleAnnotator && task.Annotator != userID {
-			return nil, errors.New("只有任务分配的标注员可以更新任务状态")
-		}
-		if userRole == models.RoleReviewer && task.Reviewer != userID {
-			return nil, errors.New("只有任务分配的审核员可以更新任务状态")
-		}
+	if _, err = ts.fireTransition(statusUpdateActions, task, newStatus, userID, userRole, 0); err != nil {
+		return nil, err
 	}
+	return toTaskResponse(task), nil
+}
 
-	// 验证状态转换的合法性
-	switch userRole {
-	case models.RoleAnnotator:
-		// annotator 只能将任务状态从 created 变为 processed
-		if task.Status != models.TaskStatusProcessing || newStatus != models.TaskStatusProcessed {
-			return nil, errors.New("标注员只能将 processing 状态的任务变为 processed")
-		}
-	case models.RoleReviewer:
-		// reviewer 只能将 processed 状态的任务变为 approved 或 rejected
-		if task.Status != models.TaskStatusReviewing || (newStatus != models.TaskStatusApproved && newStatus != models.TaskStatusRejected) {
-			return nil, errors.New("审核员只能将 processed 状态的任务变为 approved 或 rejected")
-		}
-	case models.RoleAdmin:
-		// admin 可以任意更改状态
-		// 不做限制
-	default:
-		return nil, errors.New("用户角色无效")
+func (ts *TaskService) UpdateTaskWipIdx(taskID, userID int64, userRole string, newWipIdx int) (*models.TaskResponse, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(taskID).Get(task)
+	if err != nil {
+		return nil, err
 	}
-
-	if newStatus == models.TaskStatusProcessed {
-		//检查是否每一项都有标注
-		annotationCount, _ := config.DB.Where("task_id = ?", taskID).Count(&models.SavedAnnotation{})
-		pack := &models.Package{}
-		if _, err = config.DB.Where("id = ?", task.PackageID).Cols("items").Get(pack); err != nil {
-			return nil, err
-		}
-		var items []string
-		if err = json.Unmarshal([]byte(pack.Items), &items); err != nil {
-			return nil, err
-		}
-		if annotationCount != int64(len(items)) {
-			return nil, errors.New("未完成所有标注")
-		}
-		// 清除reviewer
-		task.Reviewer = 0
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+	if task.Status != models.TaskStatusProcessing && task.Status != models.TaskStatusReviewing {
+		return nil, errors.New("只有 processing/reviewing 状态的任务才能更新 wipIdx")
 	}
-	// 保存旧状态用于消息生成
-	oldStatus := task.Status
+	// 只有当前阶段的标注员或审核员可以更新进度并续期租约
+	if (task.Status == models.TaskStatusProcessing && task.Annotator != userID) ||
+		(task.Status == models.TaskStatusReviewing && task.Reviewer != userID) {
+		return nil, errors.New("只有任务分配的标注员或审核员可以更新 wipIdx")
+	}
+	oldWipIdx := task.WipIdx
+	task.WipIdx = newWipIdx
+	task.LeaseExpiresAt = leaseDeadline()
 
-	// 更新任务状态
-	task.Status = newStatus
-	_, err = config.DB.ID(taskID).Update(task)
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+	_, err = session.ID(taskID).Cols("wip_idx", "lease_expires_at").Update(task)
 	if err != nil {
+		session.Rollback()
 		return nil, err
 	}
 
-	// 生成系统消息
-	err = ts.generateStatusChangeMessages(task, oldStatus, newStatus, userID)
+	err = recordTaskEvent(session, &models.TaskEvent{
+		TaskID:    taskID,
+		Type:      models.TaskEventWip,
+		ActorID:   userID,
+		ActorRole: userRole,
+		Field:     "wip_idx",
+		OldValue:  strconv.Itoa(oldWipIdx),
+		NewValue:  strconv.Itoa(newWipIdx),
+	})
 	if err != nil {
-		// 如果创建系统消息失败，记录错误但不影响任务状态更新
-		// 在实际应用中可能需要记录日志
+		session.Rollback()
+		return nil, err
 	}
 
-	return &models.TaskResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		CreatedAt: task.CreatedAt,
-	}, nil
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+	return toTaskResponse(task), nil
 }
 
-func (ts *TaskService) UpdateTaskWipIdx(taskID, userID int64, newWipIdx int) (*models.TaskResponse, error) {
+// StartRework 被驳回的任务由原标注员返工，任务回到 processing。
+// 审核分数低于及格线的条目需要重新标注，没有不及格条目时（整体驳回）所有条目都需要重新标注
+func (ts *TaskService) StartRework(taskID, userID int64, userRole string) (*models.TaskReworkResponse, error) {
 	task := &models.Task{}
 	has, err := config.DB.ID(taskID).Get(task)
 	if err != nil {
@@ -372,26 +454,15 @@ func (ts *TaskService) UpdateTaskWipIdx(taskID, userID int64, newWipIdx int) (*m
 	if !has {
 		return nil, errors.New("任务不存在")
 	}
-	if task.Status != models.TaskStatusProcessing && task.Status != models.TaskStatusReviewing {
-		return nil, errors.New("只有 processing/reviewing 状态的任务才能更新 wipIdx")
-	}
-	task.WipIdx = newWipIdx
-	_, err = config.DB.ID(taskID).Update(task)
+
+	tc, err := ts.fireTransition([]string{taskActionRework}, task, models.TaskStatusProcessing, userID, userRole, 0)
 	if err != nil {
 		return nil, err
 	}
-
-	return &models.TaskResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		WipIdx:    task.WipIdx,
-		CreatedAt: task.CreatedAt,
+	return &models.TaskReworkResponse{
+		Task:  toTaskResponse(task),
+		Items: tc.reworkItems,
 	}, nil
-
 }
 
 // generateStatusChangeMessages 生成状态变更的系统消息
@@ -424,8 +495,8 @@ func (ts *TaskService) generateStatusChangeMessages(task *models.Task, oldStatus
 				}
 			}
 		}
-	} else if oldStatus == models.TaskStatusProcessed && newStatus == models.TaskStatusApproved {
-		// 审核通过：processed -> approved
+	} else if oldStatus == models.TaskStatusReviewing && newStatus == models.TaskStatusApproved {
+		// 审核通过：reviewing -> approved
 		// 获取审核员信息
 		reviewerUser := &models.User{}
 		if task.Reviewer > 0 {
@@ -442,8 +513,8 @@ func (ts *TaskService) generateStatusChangeMessages(task *models.Task, oldStatus
 				}
 			}
 		}
-	} else if oldStatus == models.TaskStatusProcessed && newStatus == models.TaskStatusRejected {
-		// 审核不通过：processed -> rejected
+	} else if oldStatus == models.TaskStatusReviewing && newStatus == models.TaskStatusRejected {
+		// 审核不通过：reviewing -> rejected
 		// 获取审核员信息
 		reviewerUser := &models.User{}
 		if task.Reviewer > 0 {
@@ -499,51 +570,51 @@ func (ts *TaskService) AssignTaskWithNotification(taskID, userID, adminID int64)
 		return nil, errors.New("管理员不存在")
 	}
 
-	// 只有管理员可以重新分配任务
-	// 根据用户角色分配任务
-	// 这里需要知道用户角色，但API没有传递，暂时根据任务状态判断
-	// 在实际应用中，应该传递用户角色信息
-	if user.Role == models.RoleAnnotator {
-		// 分配给标注员
-		task.Annotator = userID
-		task.Status = models.TaskStatusProcessing
-	} else if user.Role == models.RoleReviewer {
-		// 分配给审核员
-		task.Reviewer = userID
-		task.Status = models.TaskStatusReviewing
+	// 根据被分配用户的角色决定任务进入标注还是审核
+	var to models.TaskStatus
+	switch user.Role {
+	case models.RoleAnnotator:
+		to = models.TaskStatusProcessing
+	case models.RoleReviewer:
+		to = models.TaskStatusReviewing
+	default:
+		return nil, errors.New("只能把任务分配给标注员或审核员")
 	}
 
-	_, err = config.DB.ID(taskID).Update(task)
-	if err != nil {
+	if _, err = ts.fireTransition([]string{taskActionAssign}, task, to, adminID, admin.Role, userID); err != nil {
 		return nil, err
 	}
+	return toTaskResponse(task), nil
+}
 
-	// 生成系统消息
-	sysMsgService := NewSysMsgService()
-	message := &models.SysMsgCreateRequest{
-		Title:   "任务分配通知",
-		Content: fmt.Sprintf("您已被分配了任务: %s [任务ID: %d]", task.Name, task.ID),
-		UserID:  userID,
-	}
-	_, err = sysMsgService.CreateSysMsg(message)
-	if err != nil {
-		// 如果创建系统消息失败，记录错误但不影响任务分配
-		// 在实际应用中可能需要记录日志
-	}
+// ErrTaskItemForbidden 没有分配到该任务的用户获取条目地址
+var ErrTaskItemForbidden = errors.New("没有权限查看该任务的条目")
 
-	return &models.TaskResponse{
-		ID:        task.ID,
-		Name:      task.Name,
-		PackageID: task.PackageID,
-		Annotator: task.Annotator,
-		Reviewer:  task.Reviewer,
-		Status:    task.Status,
-		CreatedAt: task.CreatedAt,
-	}, nil
+// ErrAnnotationVersionRequired 更新已有标注时未提供 If-Match
+var ErrAnnotationVersionRequired = errors.New("该条目已有标注，请通过 If-Match 提供读取时的版本号")
+
+// AnnotationConflictError 标注版本冲突，包含服务端当前的标注
+type AnnotationConflictError struct {
+	Current *models.SavedAnnotation
+}
+
+func (e *AnnotationConflictError) Error() string {
+	return "标注已被其他人修改，请基于最新版本重新保存"
+}
+
+// annotationConflict 读取服务端当前的标注生成版本冲突错误，标注不存在时返回 nil
+func (ts *TaskService) annotationConflict(taskID int64, key string) *AnnotationConflictError {
+	current := &models.SavedAnnotation{}
+	has, err := config.DB.Where("task_id = ? AND `key` = ?", taskID, key).Get(current)
+	if err != nil || !has {
+		return nil
+	}
+	return &AnnotationConflictError{Current: current}
 }
 
 // SaveAnnotation 保存标注数据
-func (ts *TaskService) SaveAnnotation(req models.SavedAnnotationRequest, userID int64) (*models.SavedAnnotation, error) {
+// ifMatch 为客户端读取时的版本号，更新已有标注时必须提供
+func (ts *TaskService) SaveAnnotation(req models.SavedAnnotationRequest, userID int64, userRole string, ifMatch *int) (*models.SavedAnnotation, error) {
 	// 获取任务信息
 	task := &models.Task{}
 	has, err := config.DB.ID(req.TaskID).Get(task)
@@ -565,10 +636,35 @@ func (ts *TaskService) SaveAnnotation(req models.SavedAnnotationRequest, userID
 		return nil, errors.New("只有 processing 状态的任务可以保存标注")
 	}
 
+	// 只能保存本任务负责的条目（拆分后的分段及混入的金标准条目）
+	pkg, items, err := ts.resolveTaskItems(task)
+	if err != nil {
+		return nil, err
+	}
+	mixed, err := mixGoldItems(task, items)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(mixed, req.Key) {
+		return nil, errors.New("该条目不属于当前任务")
+	}
+
+	// 按包的标注规范校验标记
+	if errs := validateMarks(pkg.Schema, req.Meta.Marks); len(errs) > 0 {
+		return nil, &AnnotationValidationError{Errors: errs}
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+
 	// 检查是否已存在相同 key 的标注
 	existingAnnotation := &models.SavedAnnotation{}
-	has, err = config.DB.Where("task_id = ? AND `key` = ?", req.TaskID, req.Key).Get(existingAnnotation)
+	has, err = session.Where("task_id = ? AND `key` = ?", req.TaskID, req.Key).Get(existingAnnotation)
 	if err != nil {
+		session.Rollback()
 		return nil, err
 	}
 
@@ -577,38 +673,85 @@ func (ts *TaskService) SaveAnnotation(req models.SavedAnnotationRequest, userID
 		TaskID: req.TaskID,
 		Key:    req.Key,
 		Meta:   req.Meta,
+		Source: models.AnnotationSourceManual,
 	}
 
 	if has {
-		// 更新现有标注
+		// 返工时审核已通过的条目保持不变
+		if task.ReviewRound > 0 && !existingAnnotation.NeedsRework &&
+			existingAnnotation.Review != nil && existingAnnotation.Review.Score >= models.ReviewPassScore {
+			session.Rollback()
+			return nil, errors.New("该条目审核已通过，无需返工")
+		}
+		// 更新现有标注，必须基于当前版本修改
+		if ifMatch == nil {
+			session.Rollback()
+			return nil, ErrAnnotationVersionRequired
+		}
+		if *ifMatch != existingAnnotation.Version {
+			session.Rollback()
+			return nil, &AnnotationConflictError{Current: existingAnnotation}
+		}
 		annotation.ID = existingAnnotation.ID
-		_, err = config.DB.ID(annotation.ID).Update(annotation)
+		annotation.Version = existingAnnotation.Version
+		// 重新保存即完成该条目的返工
+		affected, err := session.ID(annotation.ID).MustCols("needs_rework").Update(annotation)
 		if err != nil {
+			session.Rollback()
 			return nil, err
 		}
+		if affected == 0 {
+			session.Rollback()
+			if conflict := ts.annotationConflict(req.TaskID, req.Key); conflict != nil {
+				return nil, conflict
+			}
+			return nil, errors.New("标注不存在")
+		}
 	} else {
-		// 创建新标注
-		_, err = config.DB.Insert(annotation)
+		// 创建新标注，并发创建时由 (task_id, key) 唯一索引拦截
+		_, err = session.Insert(annotation)
 		if err != nil {
+			session.Rollback()
+			if conflict := ts.annotationConflict(req.TaskID, req.Key); conflict != nil {
+				return nil, conflict
+			}
 			return nil, err
 		}
 	}
 
 	// 获取完整的标注数据（包含创建时间等）
 	fullAnnotation := &models.SavedAnnotation{}
-	has, err = config.DB.ID(annotation.ID).Get(fullAnnotation)
+	has, err = session.ID(annotation.ID).Get(fullAnnotation)
 	if err != nil {
+		session.Rollback()
 		return nil, err
 	}
 	if !has {
+		session.Rollback()
 		return nil, errors.New("保存标注后获取数据失败")
 	}
 
+	// 记录修订历史
+	if err = recordAnnotationRevision(session, fullAnnotation, models.RevisionActionSave, userID, userRole, 0); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	// 保存标注视为仍在处理，续期领取租约
+	if err = renewTaskLease(session, task); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+
 	return fullAnnotation, nil
 }
 
 // ReviewAnnotation 审核标注数据
-func (ts *TaskService) ReviewAnnotation(req models.ReviewAnnotationReq, userID int64) (*models.SavedAnnotation, error) {
+func (ts *TaskService) ReviewAnnotation(req models.ReviewAnnotationReq, userID int64, userRole string) (*models.SavedAnnotation, error) {
 	// 获取标注信息
 	annotation := &models.SavedAnnotation{}
 	has, err := config.DB.ID(req.AnnotationID).Get(annotation)
@@ -648,23 +791,55 @@ func (ts *TaskService) ReviewAnnotation(req models.ReviewAnnotationReq, userID i
 		ReviewedAt: time.Now().Format(time.RFC3339),
 	}
 
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+
 	// 更新标注的审核信息
 	annotation.Review = reviewInfo
-	_, err = config.DB.ID(annotation.ID).Update(annotation)
+	affected, err := session.ID(annotation.ID).Update(annotation)
 	if err != nil {
+		session.Rollback()
 		return nil, err
 	}
+	if affected == 0 {
+		session.Rollback()
+		if conflict := ts.annotationConflict(annotation.TaskID, annotation.Key); conflict != nil {
+			return nil, conflict
+		}
+		return nil, errors.New("标注不存在")
+	}
 
 	// 获取完整的标注数据（包含审核信息等）
 	fullAnnotation := &models.SavedAnnotation{}
-	has, err = config.DB.ID(annotation.ID).Get(fullAnnotation)
+	has, err = session.ID(annotation.ID).Get(fullAnnotation)
 	if err != nil {
+		session.Rollback()
 		return nil, err
 	}
 	if !has {
+		session.Rollback()
 		return nil, errors.New("审核标注后获取数据失败")
 	}
 
+	// 记录修订历史
+	if err = recordAnnotationRevision(session, fullAnnotation, models.RevisionActionReview, userID, userRole, 0); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	// 审核条目视为仍在处理，续期领取租约
+	if err = renewTaskLease(session, task); err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+
 	return fullAnnotation, nil
 }
 
@@ -681,14 +856,7 @@ func (ts *TaskService) GetAnnotationByTaskAndKey(taskID int64, key string, userI
 	}
 
 	// 验证用户是否有权限查看该任务的标注
-	// 管理员可以查看所有标注
-	if userRole == models.RoleAdmin {
-		// 管理员可以查看，继续执行
-	} else if userRole == models.RoleAnnotator && task.Annotator == userID {
-		// 标注员可以查看自己任务的标注
-	} else if userRole == models.RoleReviewer && task.Reviewer == userID {
-		// 审核员可以查看自己任务的标注
-	} else {
+	if !canViewTaskAnnotations(task, userID, userRole) {
 		return nil, errors.New("没有权限查看该任务的标注")
 	}
 
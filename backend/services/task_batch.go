@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// batchNoticeMaxLines 汇总通知中最多列出的任务数
+const batchNoticeMaxLines = 20
+
+// batchNotice 发给一个用户的汇总通知
+type batchNotice struct {
+	lines []string
+}
+
+// BatchUpdateTasks 管理员批量分配、取消分配、修改状态或优先级。
+// 所有任务在同一事务中处理，任一任务失败时整批回滚；提交后每个受影响的用户只收到一条汇总通知
+func (ts *TaskService) BatchUpdateTasks(req models.TaskBatchRequest, adminID int64) (*models.TaskBatchResponse, error) {
+	if len(req.TaskIDs) == 0 && (req.Filter == nil || *req.Filter == models.TaskBatchFilter{}) {
+		return nil, errors.New("需要指定任务ID或筛选条件")
+	}
+
+	admin := &models.User{}
+	has, err := config.DB.ID(adminID).Get(admin)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("管理员不存在")
+	}
+
+	// 分配时根据被分配用户的角色决定任务进入标注还是审核
+	var assignTo models.TaskStatus
+	switch req.Action {
+	case models.TaskBatchAssign:
+		user := &models.User{}
+		has, err = config.DB.ID(req.UserID).Get(user)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			return nil, errors.New("用户不存在")
+		}
+		switch user.Role {
+		case models.RoleAnnotator:
+			assignTo = models.TaskStatusProcessing
+		case models.RoleReviewer:
+			assignTo = models.TaskStatusReviewing
+		default:
+			return nil, errors.New("只能把任务分配给标注员或审核员")
+		}
+	case models.TaskBatchStatus:
+		if req.Status == "" {
+			return nil, errors.New("需要指定目标状态")
+		}
+	case models.TaskBatchPriority:
+		if req.ClearDueAt && req.DueAt != nil {
+			return nil, errors.New("dueAt 和 clearDueAt 只能指定一个")
+		}
+		if req.Priority == nil && req.DueAt == nil && !req.ClearDueAt {
+			return nil, errors.New("需要指定优先级或截止时间")
+		}
+	}
+
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return nil, err
+	}
+
+	tasks, missing, err := loadBatchTasks(session, req)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	response := &models.TaskBatchResponse{Results: make([]models.TaskBatchResult, len(tasks), len(tasks)+len(missing))}
+	type applied struct {
+		tc *transitionContext
+		t  *taskTransition
+	}
+	transitions := make([]applied, 0, len(tasks))
+	notices := make(map[int64]*batchNotice)
+	failed := len(missing) > 0
+	for _, id := range missing {
+		response.Results = append(response.Results, models.TaskBatchResult{TaskID: id, Error: "任务不存在"})
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		result := &response.Results[i]
+		result.TaskID = task.ID
+
+		oldAnnotator, oldReviewer, oldStatus := task.Annotator, task.Reviewer, task.Status
+		var tc *transitionContext
+		var t *taskTransition
+		switch req.Action {
+		case models.TaskBatchAssign:
+			tc, t, err = ts.applyTransition(session, []string{taskActionAssign}, task, assignTo, adminID, admin.Role, req.UserID)
+		case models.TaskBatchUnassign:
+			to := models.TaskStatusCreated
+			if task.Status == models.TaskStatusReviewing {
+				to = models.TaskStatusProcessed
+			}
+			tc, t, err = ts.applyTransition(session, []string{taskActionUnassign}, task, to, adminID, admin.Role, 0)
+		case models.TaskBatchStatus:
+			tc, t, err = ts.applyTransition(session, []string{taskActionOverride}, task, req.Status, adminID, admin.Role, 0)
+		case models.TaskBatchPriority:
+			// 没有指定优先级或截止时间时保留每个任务原有的值
+			priority := task.Priority
+			if req.Priority != nil {
+				priority = *req.Priority
+			}
+			dueAt := task.DueAt
+			if req.ClearDueAt {
+				dueAt = nil
+			} else if req.DueAt != nil {
+				dueAt = req.DueAt
+			}
+			err = setTaskPriority(session, task, priority, dueAt, adminID)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			failed = true
+			continue
+		}
+		result.Success = true
+		result.Task = toTaskResponse(task)
+		if tc != nil {
+			transitions = append(transitions, applied{tc, t})
+		}
+
+		// 汇总通知：分配通知新用户，取消分配通知原用户，状态和优先级变更通知任务前后的负责人
+		switch req.Action {
+		case models.TaskBatchAssign:
+			addBatchNotice(notices, req.UserID, fmt.Sprintf("%s [任务ID: %d]", task.Name, task.ID))
+		case models.TaskBatchUnassign:
+			addBatchNotice(notices, tc.released, fmt.Sprintf("%s [任务ID: %d]", task.Name, task.ID))
+		case models.TaskBatchStatus:
+			line := fmt.Sprintf("%s: %s → %s [任务ID: %d]", task.Name, oldStatus, task.Status, task.ID)
+			for _, userID := range []int64{oldAnnotator, oldReviewer, task.Annotator, task.Reviewer} {
+				addBatchNotice(notices, userID, line)
+			}
+		case models.TaskBatchPriority:
+			line := fmt.Sprintf("%s: 优先级 %d，截止时间 %s [任务ID: %d]", task.Name, task.Priority, formatDueAtText(task.DueAt), task.ID)
+			for _, userID := range []int64{task.Annotator, task.Reviewer} {
+				addBatchNotice(notices, userID, line)
+			}
+		}
+	}
+
+	if failed {
+		session.Rollback()
+		return response, nil
+	}
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+	response.Committed = true
+
+	for _, a := range transitions {
+		a.tc.session = nil
+		runTransitionHooks(a.tc, a.t.After)
+	}
+	// 通知失败不影响批量操作结果
+	_ = sendBatchNotices(req.Action, notices)
+	return response, nil
+}
+
+// loadBatchTasks 按任务ID列表和筛选条件加载任务，只按ID指定时同时返回不存在的任务ID
+func loadBatchTasks(session *xorm.Session, req models.TaskBatchRequest) ([]models.Task, []int64, error) {
+	query := session.Asc("id")
+	if len(req.TaskIDs) > 0 {
+		query = query.In("id", req.TaskIDs)
+	}
+	if f := req.Filter; f != nil {
+		if f.PackageID > 0 {
+			query = query.And("package_id = ?", f.PackageID)
+		}
+		if f.Status != "" {
+			query = query.And("status = ?", f.Status)
+		}
+		if f.Assignee > 0 {
+			query = query.And("(annotator = ? OR reviewer = ?)", f.Assignee, f.Assignee)
+		}
+	}
+
+	var tasks []models.Task
+	if err := query.Limit(models.TaskBatchMaxTasks + 1).Find(&tasks); err != nil {
+		return nil, nil, err
+	}
+	if len(tasks) > models.TaskBatchMaxTasks {
+		return nil, nil, fmt.Errorf("一次最多操作 %d 个任务", models.TaskBatchMaxTasks)
+	}
+
+	var missing []int64
+	if len(req.TaskIDs) > 0 && req.Filter == nil {
+		found := make(map[int64]bool, len(tasks))
+		for _, task := range tasks {
+			found[task.ID] = true
+		}
+		for _, id := range req.TaskIDs {
+			if !found[id] {
+				found[id] = true
+				missing = append(missing, id)
+			}
+		}
+	}
+	if len(tasks) == 0 && len(missing) == 0 {
+		return nil, nil, errors.New("没有符合条件的任务")
+	}
+	return tasks, missing, nil
+}
+
+// addBatchNotice 为用户追加一行通知内容，userID 为 0 时忽略
+func addBatchNotice(notices map[int64]*batchNotice, userID int64, line string) {
+	if userID == 0 {
+		return
+	}
+	notice, ok := notices[userID]
+	if !ok {
+		notice = &batchNotice{}
+		notices[userID] = notice
+	}
+	// 同一任务的前后负责人可能是同一个用户
+	if n := len(notice.lines); n > 0 && notice.lines[n-1] == line {
+		return
+	}
+	notice.lines = append(notice.lines, line)
+}
+
+// sendBatchNotices 为每个受影响的用户发送一条汇总通知
+func sendBatchNotices(action string, notices map[int64]*batchNotice) error {
+	var title, summary string
+	switch action {
+	case models.TaskBatchAssign:
+		title, summary = "任务分配通知", "您已被分配了 %d 个任务:"
+	case models.TaskBatchUnassign:
+		title, summary = "任务取消分配通知", "管理员取消了您的 %d 个任务:"
+	case models.TaskBatchStatus:
+		title, summary = "任务状态变更通知", "管理员修改了 %d 个任务的状态:"
+	case models.TaskBatchPriority:
+		title, summary = "任务优先级变更通知", "管理员修改了 %d 个任务的优先级或截止时间:"
+	}
+
+	sysMsgService := NewSysMsgService()
+	for userID, notice := range notices {
+		lines := notice.lines
+		more := ""
+		if len(lines) > batchNoticeMaxLines {
+			more = fmt.Sprintf("\n... 等共 %d 个任务", len(lines))
+			lines = lines[:batchNoticeMaxLines]
+		}
+		_, err := sysMsgService.CreateSysMsg(&models.SysMsgCreateRequest{
+			Title:   title,
+			Content: fmt.Sprintf(summary, len(notice.lines)) + "\n" + strings.Join(lines, "\n") + more,
+			UserID:  userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatDueAtText 通知中的截止时间
+func formatDueAtText(dueAt *time.Time) string {
+	if dueAt == nil {
+		return "无"
+	}
+	return dueAt.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// recordTaskEvent 在事务中追加一条任务事件
+func recordTaskEvent(session *xorm.Session, event *models.TaskEvent) error {
+	_, err := session.Insert(event)
+	return err
+}
+
+// recordTransitionEvents 记录一次状态变更：领取、分配、取消分配或释放时先记录人员变更，再记录状态变更
+func recordTransitionEvents(tc *transitionContext, action string, oldAnnotator, oldReviewer int64) error {
+	role := tc.role
+	if action == taskActionAutoReject {
+		role = roleSystem
+	}
+
+	if action == taskActionClaim || action == taskActionAssign || action == taskActionRelease || action == taskActionUnassign {
+		eventType := models.TaskEventClaim
+		switch action {
+		case taskActionAssign, taskActionUnassign:
+			eventType = models.TaskEventAssign
+		case taskActionRelease:
+			eventType = models.TaskEventRelease
+		}
+		// 释放和取消分配时任务离开 reviewing，变更的是审核员
+		field, oldValue, newValue := "annotator", oldAnnotator, tc.task.Annotator
+		if tc.to == models.TaskStatusReviewing || tc.from == models.TaskStatusReviewing && tc.task.Reviewer != oldReviewer {
+			field, oldValue, newValue = "reviewer", oldReviewer, tc.task.Reviewer
+		}
+		err := recordTaskEvent(tc.session, &models.TaskEvent{
+			TaskID:    tc.task.ID,
+			Type:      eventType,
+			Action:    action,
+			ActorID:   tc.userID,
+			ActorRole: role,
+			Field:     field,
+			OldValue:  formatUserID(oldValue),
+			NewValue:  formatUserID(newValue),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 重新分配时状态可能不变
+	if tc.from == tc.to {
+		return nil
+	}
+	return recordTaskEvent(tc.session, &models.TaskEvent{
+		TaskID:    tc.task.ID,
+		Type:      models.TaskEventStatus,
+		Action:    action,
+		ActorID:   tc.userID,
+		ActorRole: role,
+		Field:     "status",
+		OldValue:  string(tc.from),
+		NewValue:  string(tc.to),
+	})
+}
+
+// formatUserID 事件中的用户ID，0 表示未分配记为空
+func formatUserID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// GetTaskTimeline 获取任务的事件时间线，管理员、任务的标注员和审核员可以查看
+func (ts *TaskService) GetTaskTimeline(taskID, userID int64, userRole string) (*models.TaskTimelineResponse, error) {
+	task := &models.Task{}
+	has, err := config.DB.ID(taskID).Get(task)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("任务不存在")
+	}
+	if !canViewTaskAnnotations(task, userID, userRole) {
+		return nil, errors.New("没有权限查看该任务的时间线")
+	}
+
+	var events []models.TaskEvent
+	total, err := config.DB.Where("task_id = ?", taskID).Asc("id").FindAndCount(&events)
+	if err != nil {
+		return nil, err
+	}
+
+	// 批量获取操作人用户名
+	actorIDs := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, event := range events {
+		if event.ActorID > 0 && !seen[event.ActorID] {
+			seen[event.ActorID] = true
+			actorIDs = append(actorIDs, event.ActorID)
+		}
+	}
+	names := make(map[int64]string, len(actorIDs))
+	if len(actorIDs) > 0 {
+		var users []models.User
+		if err = config.DB.In("id", actorIDs).Cols("id", "username").Find(&users); err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			names[user.ID] = user.Username
+		}
+	}
+
+	list := make([]models.TaskTimelineItem, len(events))
+	for i, event := range events {
+		list[i] = models.TaskTimelineItem{TaskEvent: event, ActorName: names[event.ActorID]}
+	}
+	return &models.TaskTimelineResponse{
+		List:  list,
+		Total: total,
+	}, nil
+}
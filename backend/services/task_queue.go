@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// nextTaskBatchSize 每次从队列中读取的候选任务数
+const nextTaskBatchSize = 50
+
+// ErrNoTaskAvailable 队列中没有当前用户可以领取的任务
+var ErrNoTaskAvailable = errors.New("暂无可领取的任务")
+
+// NextTask 为用户领取队列中的下一个任务：标注员领取 created 任务，审核员领取 processed 任务。
+// 按优先级从高到低、截止时间从早到晚排序，跳过用户技能标签不满足的包。
+// 领取通过带条件的 UPDATE 完成，并发领取同一任务时只有一个用户成功，其余用户继续尝试下一个；
+// 领取事务中锁定用户行后再统计进行中的任务数，同一用户并发领取时不会超过上限
+func (ts *TaskService) NextTask(userID int64, userRole string) (*models.TaskResponse, error) {
+	var from, to models.TaskStatus
+	var assigneeCol string
+	switch userRole {
+	case models.RoleAnnotator:
+		from, to, assigneeCol = models.TaskStatusCreated, models.TaskStatusProcessing, "annotator"
+	case models.RoleReviewer:
+		from, to, assigneeCol = models.TaskStatusProcessed, models.TaskStatusReviewing, "reviewer"
+	default:
+		return nil, errors.New("只有标注员和审核员可以领取任务")
+	}
+
+	// 预检查不在事务中，使用普通读；领取事务中会锁定后再次检查
+	session := config.DB.NewSession()
+	defer session.Close()
+	lookup := &dbTransitionLookup{session: session}
+
+	user, err := lookup.User(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	// 达到上限时不必扫描队列，领取时会再次检查
+	active, err := lookup.ActiveTasks(userID, assigneeCol, to)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkConcurrentTasks(user, active); err != nil {
+		return nil, err
+	}
+
+	skills := userSkills(user)
+	eligible := make(map[int64]bool) // 包ID -> 用户是否具备所需技能
+
+	// 按排序键分页，前面的任务被领取后不会跳过后面的任务
+	var last *models.Task
+	for {
+		query := config.DB.Where("status = ? AND "+assigneeCol+" = 0", from)
+		if last != nil {
+			query = afterQueuePosition(query, last)
+		}
+		var tasks []models.Task
+		err := query.OrderBy("priority DESC, due_at IS NULL, due_at ASC, id ASC").
+			Limit(nextTaskBatchSize).Find(&tasks)
+		if err != nil {
+			return nil, err
+		}
+		if len(tasks) > 0 {
+			tail := tasks[len(tasks)-1]
+			last = &tail
+		}
+
+		for i := range tasks {
+			task := &tasks[i]
+			ok, checked := eligible[task.PackageID]
+			if !checked {
+				required, err := lookup.PackageSkillTags(task.PackageID)
+				if err != nil {
+					return nil, err
+				}
+				ok = hasSkillTags(required, skills)
+				eligible[task.PackageID] = ok
+			}
+			if !ok {
+				continue
+			}
+			// 被其他用户抢先领取或已领取同一分片的重叠任务时尝试下一个，其他错误（如达到上限）直接返回
+			if _, err = ts.fireTransition([]string{taskActionClaim}, task, to, userID, userRole, userID); err != nil {
+				if isClaimConflict(err) {
+					continue
+				}
+				return nil, err
+			}
+			return toTaskResponse(task), nil
+		}
+
+		if len(tasks) < nextTaskBatchSize {
+			return nil, ErrNoTaskAvailable
+		}
+	}
+}
+
+// afterQueuePosition 筛选排在 last 之后的任务，排序为优先级降序、没有截止时间的在后、截止时间升序、ID 升序
+func afterQueuePosition(query *xorm.Session, last *models.Task) *xorm.Session {
+	if last.DueAt == nil {
+		return query.And("(priority < ? OR (priority = ? AND due_at IS NULL AND id > ?))",
+			last.Priority, last.Priority, last.ID)
+	}
+	return query.And("(priority < ? OR (priority = ? AND (due_at IS NULL OR due_at > ? OR (due_at = ? AND id > ?))))",
+		last.Priority, last.Priority, *last.DueAt, *last.DueAt, last.ID)
+}
+
+// isClaimConflict 是否为与其他领取冲突、只影响当前任务的错误
+func isClaimConflict(err error) bool {
+	return errors.Is(err, ErrTaskStateChanged) || errors.Is(err, errAnnotatorClaimed) ||
+		errors.Is(err, errReviewerClaimed) || errors.Is(err, errReplicaClaimed)
+}
+
+// checkConcurrentTasks 检查用户进行中的任务数 active 是否已达到上限
+func checkConcurrentTasks(user *models.User, active int64) error {
+	limit := user.MaxTasks
+	if limit == 0 {
+		limit = config.TaskMaxConcurrent
+	}
+	if limit <= 0 {
+		return nil
+	}
+	if active >= int64(limit) {
+		return errConcurrentTasksMax
+	}
+	return nil
+}
+
+// userSkills 用户的技能标签集合
+func userSkills(user *models.User) map[string]bool {
+	skills := make(map[string]bool, len(user.SkillTags))
+	for _, tag := range user.SkillTags {
+		skills[tag] = true
+	}
+	return skills
+}
+
+// hasSkillTags 用户是否具备包要求的全部技能标签
+func hasSkillTags(required []string, skills map[string]bool) bool {
+	for _, tag := range required {
+		if !skills[tag] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,693 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"luma-ai-backend/config"
+	"luma-ai-backend/models"
+
+	"xorm.io/xorm"
+)
+
+// 触发任务状态变更的操作
+const (
+	taskActionClaim      = "claim"       // 标注员或审核员领取
+	taskActionAssign     = "assign"      // 管理员分配
+	taskActionSubmit     = "submit"      // 标注员提交
+	taskActionReview     = "review"      // 审核员给出结论
+	taskActionRework     = "rework"      // 标注员返工
+	taskActionAutoReject = "auto_reject" // 金标准准确率过低自动驳回
+	taskActionOverride   = "override"    // 管理员直接修改状态
+	taskActionRelease    = "release"     // 领取租约过期自动释放
+	taskActionUnassign   = "unassign"    // 管理员取消分配
+)
+
+// statusUpdateActions 通过更新任务状态接口可以触发的操作
+var statusUpdateActions = []string{taskActionSubmit, taskActionReview, taskActionRework, taskActionOverride}
+
+// roleSystem 由系统而不是用户触发的状态变更
+const roleSystem = "system"
+
+// anyTaskStatus 转换规则中匹配任意状态
+const anyTaskStatus models.TaskStatus = "*"
+
+// 领取时与其他用户冲突的错误，自动领取时遇到这些错误继续尝试下一个任务
+var (
+	ErrTaskStateChanged   = errors.New("任务状态已被其他操作修改，请刷新后重试")
+	errAnnotatorClaimed   = errors.New("任务已经被标注员领取")
+	errReviewerClaimed    = errors.New("任务已经被审核员领取")
+	errReplicaClaimed     = errors.New("该标注员已领取同一批条目的另一份重叠任务")
+	errConcurrentTasksMax = errors.New("进行中的任务已达到上限，请先完成手头的任务")
+)
+
+// taskTransition 任务状态机的一条转换规则
+type taskTransition struct {
+	Action string
+	From   models.TaskStatus
+	To     models.TaskStatus
+	Roles  []string
+	Guard  func(tc *transitionContext) error   // 前置条件，不满足时拒绝转换
+	Apply  func(tc *transitionContext) error   // 在事务中修改任务及关联数据，可以把 tc.to 改为其他目标
+	After  []func(tc *transitionContext) error // 提交后的副作用，失败不影响状态变更
+	Notify []func(tc *transitionContext) error // 提交后发送的通知，批量操作时由调用方合并发送
+}
+
+// transitionContext 一次状态变更的上下文
+type transitionContext struct {
+	session     *xorm.Session
+	lookup      transitionLookup // 前置条件读取数据的方式
+	task        *models.Task
+	from        models.TaskStatus
+	to          models.TaskStatus
+	userID      int64 // 操作人
+	role        string
+	assignee    int64 // 领取或分配的目标用户
+	goldScore   *models.GoldScore
+	reworkItems []models.ReworkItem
+	released    int64 // 被释放或取消分配的用户
+}
+
+// taskTransitions 任务状态机，按顺序匹配第一条满足操作、状态和角色的规则
+var taskTransitions = []taskTransition{
+	{
+		Action: taskActionClaim, From: models.TaskStatusCreated, To: models.TaskStatusProcessing,
+		Roles: []string{models.RoleAnnotator},
+		Guard: guardClaimAnnotator, Apply: applyAnnotator,
+	},
+	{
+		Action: taskActionClaim, From: models.TaskStatusProcessed, To: models.TaskStatusReviewing,
+		Roles: []string{models.RoleReviewer},
+		Guard: guardClaimReviewer, Apply: applyReviewer,
+	},
+	{
+		Action: taskActionAssign, From: anyTaskStatus, To: models.TaskStatusProcessing,
+		Roles: []string{models.RoleAdmin},
+		Guard: guardReplicaAnnotator, Apply: applyAnnotator,
+		Notify: []func(tc *transitionContext) error{notifyAssigned},
+	},
+	{
+		Action: taskActionAssign, From: anyTaskStatus, To: models.TaskStatusReviewing,
+		Roles:  []string{models.RoleAdmin},
+		Apply:  applyReviewer,
+		Notify: []func(tc *transitionContext) error{notifyAssigned},
+	},
+	{
+		Action: taskActionSubmit, From: models.TaskStatusProcessing, To: models.TaskStatusProcessed,
+		Roles: []string{models.RoleAnnotator},
+		Guard: guardSubmit, Apply: applySubmit,
+		After:  []func(tc *transitionContext) error{computeConsensus},
+		Notify: []func(tc *transitionContext) error{notifyStatusChange, notifyGoldScore},
+	},
+	{
+		Action: taskActionAutoReject, From: models.TaskStatusProcessing, To: models.TaskStatusRejected,
+		Roles:  []string{roleSystem},
+		Notify: []func(tc *transitionContext) error{notifyGoldScore},
+	},
+	{
+		Action: taskActionReview, From: models.TaskStatusReviewing, To: models.TaskStatusApproved,
+		Roles: []string{models.RoleReviewer},
+		Guard: guardTaskReviewer, Apply: applyReviewRound,
+		Notify: []func(tc *transitionContext) error{notifyStatusChange},
+	},
+	{
+		Action: taskActionReview, From: models.TaskStatusReviewing, To: models.TaskStatusRejected,
+		Roles: []string{models.RoleReviewer},
+		Guard: guardTaskReviewer, Apply: applyReviewRound,
+		Notify: []func(tc *transitionContext) error{notifyStatusChange},
+	},
+	{
+		Action: taskActionRework, From: models.TaskStatusRejected, To: models.TaskStatusProcessing,
+		Roles: []string{models.RoleAnnotator},
+		Guard: guardTaskAnnotator, Apply: applyRework,
+	},
+	{
+		Action: taskActionRelease, From: models.TaskStatusProcessing, To: models.TaskStatusCreated,
+		Roles: []string{roleSystem},
+		Guard: guardLeaseExpired, Apply: applyReleaseAnnotator,
+		Notify: []func(tc *transitionContext) error{notifyReleased},
+	},
+	{
+		Action: taskActionRelease, From: models.TaskStatusReviewing, To: models.TaskStatusProcessed,
+		Roles: []string{roleSystem},
+		Guard: guardLeaseExpired, Apply: applyReleaseReviewer,
+		Notify: []func(tc *transitionContext) error{notifyReleased},
+	},
+	{
+		Action: taskActionUnassign, From: models.TaskStatusProcessing, To: models.TaskStatusCreated,
+		Roles:  []string{models.RoleAdmin},
+		Apply:  applyReleaseAnnotator,
+		Notify: []func(tc *transitionContext) error{notifyUnassigned},
+	},
+	{
+		Action: taskActionUnassign, From: models.TaskStatusReviewing, To: models.TaskStatusProcessed,
+		Roles:  []string{models.RoleAdmin},
+		Apply:  applyReleaseReviewer,
+		Notify: []func(tc *transitionContext) error{notifyUnassigned},
+	},
+	{
+		Action: taskActionOverride, From: anyTaskStatus, To: anyTaskStatus,
+		Roles: []string{models.RoleAdmin},
+		Guard: guardOverride, Apply: applyOverride,
+		After:  []func(tc *transitionContext) error{computeConsensus},
+		Notify: []func(tc *transitionContext) error{notifyStatusChange},
+	},
+}
+
+// findTaskTransition 查找允许的转换规则，只依赖规则表
+func findTaskTransition(actions []string, from, to models.TaskStatus, role string) (*taskTransition, error) {
+	matched := false
+	for i := range taskTransitions {
+		t := &taskTransitions[i]
+		if !containsString(actions, t.Action) {
+			continue
+		}
+		if (t.From != anyTaskStatus && t.From != from) || (t.To != anyTaskStatus && t.To != to) {
+			continue
+		}
+		matched = true
+		if containsString(t.Roles, role) {
+			return t, nil
+		}
+	}
+	if matched {
+		return nil, fmt.Errorf("没有权限将任务从 %s 变为 %s", from, to)
+	}
+	return nil, fmt.Errorf("不允许将任务从 %s 变为 %s", from, to)
+}
+
+// containsString 判断列表中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fireTransition 执行一次状态变更：检查规则和前置条件，在事务中修改任务，提交后执行副作用和通知。
+// 任务在读取后被其他请求修改了状态、标注员或审核员时拒绝变更，并发领取同一任务时只有一个成功
+func (ts *TaskService) fireTransition(actions []string, task *models.Task, to models.TaskStatus, userID int64, role string, assignee int64) (*transitionContext, error) {
+	session := config.DB.NewSession()
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return nil, err
+	}
+
+	tc, t, err := ts.applyTransition(session, actions, task, to, userID, role, assignee)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	if err = session.Commit(); err != nil {
+		return nil, err
+	}
+	tc.session = nil
+
+	runTransitionHooks(tc, t.After)
+	runTransitionHooks(tc, t.Notify)
+	return tc, nil
+}
+
+// checkTransition 查找允许的转换规则并检查前置条件，不修改任务。前置条件只通过 tc.lookup 读取数据
+func checkTransition(tc *transitionContext, actions []string) (*taskTransition, error) {
+	t, err := findTaskTransition(actions, tc.from, tc.to, tc.role)
+	if err != nil {
+		return nil, err
+	}
+	// 只有重新分配可以保持状态不变
+	if tc.from == tc.to && t.Action != taskActionAssign {
+		return nil, fmt.Errorf("任务已经是 %s 状态", tc.to)
+	}
+	if t.Guard != nil {
+		if err = t.Guard(tc); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// applyTransition 在调用方的事务中执行状态变更，不提交事务，返回匹配的规则供调用方在提交后执行副作用
+func (ts *TaskService) applyTransition(session *xorm.Session, actions []string, task *models.Task, to models.TaskStatus, userID int64, role string, assignee int64) (*transitionContext, *taskTransition, error) {
+	tc := &transitionContext{
+		session:  session,
+		lookup:   &dbTransitionLookup{session: session, lock: true},
+		task:     task,
+		from:     task.Status,
+		to:       to,
+		userID:   userID,
+		role:     role,
+		assignee: assignee,
+	}
+	t, err := checkTransition(tc, actions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oldAnnotator, oldReviewer := task.Annotator, task.Reviewer
+	if t.Apply != nil {
+		if err = t.Apply(tc); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Apply 改变了目标状态时，由系统规则接管后续处理
+	if tc.to != to {
+		next, err := findTaskTransition([]string{taskActionAutoReject}, tc.from, tc.to, roleSystem)
+		if err != nil {
+			return nil, nil, err
+		}
+		if next.Apply != nil {
+			if err = next.Apply(tc); err != nil {
+				return nil, nil, err
+			}
+		}
+		t = next
+	}
+
+	// 记录通过审核的时间，管理员改回其他状态时清除
+	if tc.to == models.TaskStatusApproved {
+		now := time.Now()
+		task.CompletedAt = &now
+	} else {
+		task.CompletedAt = nil
+	}
+
+	// 进入标注或审核时重新开始租约，离开时清除
+	if tc.to == models.TaskStatusProcessing || tc.to == models.TaskStatusReviewing {
+		task.LeaseExpiresAt = leaseDeadline()
+	} else {
+		task.LeaseExpiresAt = nil
+	}
+
+	if err = recordTransitionEvents(tc, t.Action, oldAnnotator, oldReviewer); err != nil {
+		return nil, nil, err
+	}
+
+	task.Status = tc.to
+	update := session.ID(task.ID).
+		Where("status = ? AND annotator = ? AND reviewer = ?", tc.from, oldAnnotator, oldReviewer)
+	// 读取任务后租约可能被续期，释放时以数据库中的租约为准
+	if t.Action == taskActionRelease {
+		update = update.And("lease_expires_at < ?", time.Now())
+	}
+	affected, err := update.
+		Cols("status", "annotator", "reviewer", "wip_idx", "flagged", "review_round", "lease_expires_at", "completed_at").Update(task)
+	if err != nil {
+		return nil, nil, err
+	}
+	if affected == 0 {
+		return nil, nil, ErrTaskStateChanged
+	}
+	return tc, t, nil
+}
+
+// runTransitionHooks 执行提交后的副作用，失败不影响状态变更
+func runTransitionHooks(tc *transitionContext, hooks []func(tc *transitionContext) error) {
+	for _, hook := range hooks {
+		_ = hook(tc)
+	}
+}
+
+// guardTaskAnnotator 只有任务的标注员可以操作
+func guardTaskAnnotator(tc *transitionContext) error {
+	if tc.task.Annotator != tc.userID {
+		return errors.New("只有任务分配的标注员可以更新任务状态")
+	}
+	return nil
+}
+
+// guardTaskReviewer 只有任务的审核员可以操作
+func guardTaskReviewer(tc *transitionContext) error {
+	if tc.task.Reviewer != tc.userID {
+		return errors.New("只有任务分配的审核员可以更新任务状态")
+	}
+	return nil
+}
+
+// guardClaimAnnotator 标注员只能领取还没有标注员的任务，且需要具备包要求的技能、进行中的任务不超过上限
+func guardClaimAnnotator(tc *transitionContext) error {
+	if tc.task.Annotator > 0 {
+		return errAnnotatorClaimed
+	}
+	if err := guardClaimant(tc, "annotator"); err != nil {
+		return err
+	}
+	return guardReplicaAnnotator(tc)
+}
+
+// guardClaimReviewer 审核员只能领取还没有审核员的任务，且需要具备包要求的技能、进行中的任务不超过上限
+func guardClaimReviewer(tc *transitionContext) error {
+	if tc.task.Reviewer > 0 {
+		return errReviewerClaimed
+	}
+	return guardClaimant(tc, "reviewer")
+}
+
+// guardClaimant 检查领取人的技能标签和进行中的任务数
+func guardClaimant(tc *transitionContext, assigneeCol string) error {
+	user, err := tc.lookup.User(tc.assignee)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	required, err := tc.lookup.PackageSkillTags(tc.task.PackageID)
+	if err != nil {
+		return err
+	}
+	if !hasSkillTags(required, userSkills(user)) {
+		return errors.New("不具备该任务所需的技能标签")
+	}
+	active, err := tc.lookup.ActiveTasks(user.ID, assigneeCol, tc.to)
+	if err != nil {
+		return err
+	}
+	return checkConcurrentTasks(user, active)
+}
+
+// guardReplicaAnnotator 同一分片的重叠任务必须由不同标注员完成
+func guardReplicaAnnotator(tc *transitionContext) error {
+	if tc.task.Replica == 0 {
+		return nil
+	}
+	claimed, err := tc.lookup.ReplicaClaimed(tc.task, tc.assignee)
+	if err != nil {
+		return err
+	}
+	if claimed {
+		return errReplicaClaimed
+	}
+	return nil
+}
+
+// guardSubmit 标注员提交前必须完成所有条目（含混入的金标准条目）且没有待返工的条目
+func guardSubmit(tc *transitionContext) error {
+	if err := guardTaskAnnotator(tc); err != nil {
+		return err
+	}
+	return guardAnnotationsComplete(tc)
+}
+
+// guardAnnotationsComplete 任务的每个条目都有标注，且没有待返工的条目
+// 按条目逐个检查，金标准调整后不再属于任务的旧标注不影响判断
+func guardAnnotationsComplete(tc *transitionContext) error {
+	items, err := tc.lookup.TaskItems(tc.task)
+	if err != nil {
+		return err
+	}
+	keys, err := tc.lookup.SavedKeys(tc.task.ID)
+	if err != nil {
+		return err
+	}
+	saved := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		saved[key] = true
+	}
+	missing := 0
+	for _, key := range items {
+		if !saved[key] {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("未完成所有标注，还有 %d 个条目未标注", missing)
+	}
+	reworkCount, err := tc.lookup.ReworkCount(tc.task.ID)
+	if err != nil {
+		return err
+	}
+	if reworkCount > 0 {
+		return fmt.Errorf("还有 %d 个条目需要返工", reworkCount)
+	}
+	return nil
+}
+
+// guardOverride 管理员可以任意修改状态，但变为 processed 时仍需完成所有标注
+func guardOverride(tc *transitionContext) error {
+	if tc.to == models.TaskStatusProcessed {
+		return guardAnnotationsComplete(tc)
+	}
+	return nil
+}
+
+// guardLeaseExpired 租约在读取任务后被续期时不释放
+func guardLeaseExpired(tc *transitionContext) error {
+	if tc.task.LeaseExpiresAt == nil || tc.task.LeaseExpiresAt.After(time.Now()) {
+		return errors.New("任务租约未过期")
+	}
+	return nil
+}
+
+// applyAnnotator 设置任务的标注员
+func applyAnnotator(tc *transitionContext) error {
+	tc.task.Annotator = tc.assignee
+	return nil
+}
+
+// applyReviewer 设置任务的审核员
+func applyReviewer(tc *transitionContext) error {
+	tc.task.Reviewer = tc.assignee
+	return nil
+}
+
+// applySubmit 清除审核员，并为混入的金标准条目评分，准确率过低时标记任务或转为自动驳回
+func applySubmit(tc *transitionContext) error {
+	tc.task.Reviewer = 0
+
+	pkg, _, err := NewTaskService().resolveTaskItems(tc.task)
+	if err != nil {
+		return err
+	}
+	score, err := NewGoldService().ScoreTask(tc.session, tc.task, pkg)
+	if err != nil {
+		return err
+	}
+	tc.goldScore = score
+	tc.task.Flagged = false
+	if score != nil {
+		switch score.Action {
+		case models.GoldActionFlag:
+			tc.task.Flagged = true
+		case models.GoldActionReject:
+			tc.to = models.TaskStatusRejected
+		}
+	}
+	return nil
+}
+
+// applyReviewRound 审核员给出结论即完成一轮审核
+func applyReviewRound(tc *transitionContext) error {
+	tc.task.ReviewRound++
+	return nil
+}
+
+// applyRework 标记需要返工的条目：审核分数低于及格线的条目，没有不及格条目时（整体驳回）为所有条目
+func applyRework(tc *transitionContext) error {
+	var annotations []models.SavedAnnotation
+	if err := tc.session.Where("task_id = ?", tc.task.ID).Asc("id").Find(&annotations); err != nil {
+		return err
+	}
+	var failed []models.SavedAnnotation
+	for _, annotation := range annotations {
+		if annotation.Review != nil && annotation.Review.Score < models.ReviewPassScore {
+			failed = append(failed, annotation)
+		}
+	}
+	if len(failed) == 0 {
+		failed = annotations
+	}
+
+	ids := make([]int64, len(failed))
+	tc.reworkItems = make([]models.ReworkItem, len(failed))
+	for i, annotation := range failed {
+		ids[i] = annotation.ID
+		tc.reworkItems[i] = models.ReworkItem{Key: annotation.Key, Review: annotation.Review}
+	}
+	if len(ids) > 0 {
+		_, err := tc.session.Table(new(models.SavedAnnotation)).In("id", ids).
+			Update(map[string]interface{}{"needs_rework": true})
+		if err != nil {
+			return err
+		}
+	}
+
+	tc.task.WipIdx = 0
+	return nil
+}
+
+// applyReleaseAnnotator 释放标注员，已保存的标注保留给下一个领取的人
+func applyReleaseAnnotator(tc *transitionContext) error {
+	tc.released = tc.task.Annotator
+	tc.task.Annotator = 0
+	return nil
+}
+
+// applyReleaseReviewer 释放审核员，任务回到待审核
+func applyReleaseReviewer(tc *transitionContext) error {
+	tc.released = tc.task.Reviewer
+	tc.task.Reviewer = 0
+	return nil
+}
+
+// applyOverride 管理员把任务改为 processed 时清除审核员，等待重新领取审核
+func applyOverride(tc *transitionContext) error {
+	if tc.to == models.TaskStatusProcessed {
+		tc.task.Reviewer = 0
+	}
+	return nil
+}
+
+// notifyStatusChange 按状态变更发送系统消息
+func notifyStatusChange(tc *transitionContext) error {
+	return NewTaskService().generateStatusChangeMessages(tc.task, tc.from, tc.to, tc.userID)
+}
+
+// notifyAssigned 通知被分配任务的用户
+func notifyAssigned(tc *transitionContext) error {
+	message := &models.SysMsgCreateRequest{
+		Title:   "任务分配通知",
+		Content: fmt.Sprintf("您已被分配了任务: %s [任务ID: %d]", tc.task.Name, tc.task.ID),
+		UserID:  tc.assignee,
+	}
+	_, err := NewSysMsgService().CreateSysMsg(message)
+	return err
+}
+
+// notifyGoldScore 金标准准确率过低时发送通知
+func notifyGoldScore(tc *transitionContext) error {
+	if tc.goldScore == nil {
+		return nil
+	}
+	return NewGoldService().notifyGoldScore(tc.task, tc.goldScore)
+}
+
+// computeConsensus 重叠标注的最后一个副本提交后合并该分片，管理员可以手动重新计算
+func computeConsensus(tc *transitionContext) error {
+	if tc.to != models.TaskStatusProcessed || tc.task.Replica == 0 {
+		return nil
+	}
+	_, _, err := NewConsensusService().ComputeChunk(tc.task.PackageID, tc.task.ChunkIdx)
+	return err
+}
+
+// notifyUnassigned 通知被取消分配的用户
+func notifyUnassigned(tc *transitionContext) error {
+	if tc.released == 0 {
+		return nil
+	}
+	_, err := NewSysMsgService().CreateSysMsg(&models.SysMsgCreateRequest{
+		Title:   "任务取消分配通知",
+		Content: fmt.Sprintf("管理员取消了您的任务: %s [任务ID: %d]", tc.task.Name, tc.task.ID),
+		UserID:  tc.released,
+	})
+	return err
+}
+
+// notifyReleased 通知被释放的用户，并通知管理员和审核员
+func notifyReleased(tc *transitionContext) error {
+	sysMsgService := NewSysMsgService()
+	if tc.released > 0 {
+		_, err := sysMsgService.CreateSysMsg(&models.SysMsgCreateRequest{
+			Title:   "任务领取已过期",
+			Content: fmt.Sprintf("您领取的任务 %s 长时间没有操作，已被释放，如需继续请重新领取 [任务ID: %d]", tc.task.Name, tc.task.ID),
+			UserID:  tc.released,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err := sysMsgService.CreateSysMsgForRoles("任务租约过期",
+		fmt.Sprintf("任务 %s 的领取租约已过期，已从用户 %d 释放回 %s 状态 [任务ID: %d]", tc.task.Name, tc.released, tc.to, tc.task.ID),
+		models.RoleAdmin, models.RoleReviewer)
+	return err
+}
+
+// transitionLookup 前置条件需要读取的数据，状态变更时在事务中查询数据库，测试时可以替换
+type transitionLookup interface {
+	// TaskItems 任务负责的条目，含混入的金标准条目
+	TaskItems(task *models.Task) ([]string, error)
+	// SavedKeys 任务已保存标注的条目
+	SavedKeys(taskID int64) ([]string, error)
+	// ReworkCount 任务中待返工的条目数
+	ReworkCount(taskID int64) (int64, error)
+	// User 获取用户，不存在时返回 nil
+	User(userID int64) (*models.User, error)
+	// PackageSkillTags 包要求的技能标签
+	PackageSkillTags(packageID int64) ([]string, error)
+	// ActiveTasks 用户作为 assigneeCol（annotator 或 reviewer）处于 status 状态的任务数
+	ActiveTasks(userID int64, assigneeCol string, status models.TaskStatus) (int64, error)
+	// ReplicaClaimed 用户是否已领取同一分片的其他重叠任务
+	ReplicaClaimed(task *models.Task, userID int64) (bool, error)
+}
+
+// dbTransitionLookup 通过数据库会话读取前置条件需要的数据，
+// lock 为 true 时用户和进行中的任务使用锁定读，只能在事务中使用
+type dbTransitionLookup struct {
+	session *xorm.Session
+	lock    bool
+}
+
+func (l *dbTransitionLookup) TaskItems(task *models.Task) ([]string, error) {
+	_, items, err := NewTaskService().resolveTaskItems(task)
+	if err != nil {
+		return nil, err
+	}
+	return mixGoldItems(task, items)
+}
+
+func (l *dbTransitionLookup) SavedKeys(taskID int64) ([]string, error) {
+	var keys []string
+	err := l.session.Table(new(models.SavedAnnotation)).Where("task_id = ?", taskID).Cols("key").Find(&keys)
+	return keys, err
+}
+
+func (l *dbTransitionLookup) ReworkCount(taskID int64) (int64, error) {
+	return l.session.Where("task_id = ? AND needs_rework = ?", taskID, true).Count(&models.SavedAnnotation{})
+}
+
+// User lock 为 true 时锁定用户行，同一用户的并发领取按顺序检查进行中的任务数
+func (l *dbTransitionLookup) User(userID int64) (*models.User, error) {
+	query := l.session.ID(userID)
+	if l.lock {
+		query = query.ForUpdate()
+	}
+	user := &models.User{}
+	has, err := query.Get(user)
+	if err != nil || !has {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (l *dbTransitionLookup) PackageSkillTags(packageID int64) ([]string, error) {
+	pkg := &models.Package{}
+	has, err := l.session.ID(packageID).Cols("skill_tags").Get(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("关联的包不存在")
+	}
+	return pkg.SkillTags, nil
+}
+
+// ActiveTasks lock 为 true 时使用锁定读，读到其他事务刚提交的领取
+func (l *dbTransitionLookup) ActiveTasks(userID int64, assigneeCol string, status models.TaskStatus) (int64, error) {
+	query := "SELECT COUNT(*) FROM task WHERE " + assigneeCol + " = ? AND status = ?"
+	if l.lock {
+		query += " FOR UPDATE"
+	}
+	var count int64
+	_, err := l.session.SQL(query, userID, status).Get(&count)
+	return count, err
+}
+
+func (l *dbTransitionLookup) ReplicaClaimed(task *models.Task, userID int64) (bool, error) {
+	count, err := l.session.Where("package_id = ? AND chunk_idx = ? AND id != ? AND annotator = ?",
+		task.PackageID, task.ChunkIdx, task.ID, userID).Count(new(models.Task))
+	return count > 0, err
+}
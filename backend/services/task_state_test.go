@@ -0,0 +1,285 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"luma-ai-backend/models"
+)
+
+// fakeTransitionLookup 前置条件使用的内存数据
+type fakeTransitionLookup struct {
+	items     []string
+	saved     []string
+	rework    int64
+	users     map[int64]*models.User
+	skillTags []string
+	active    int64
+	replica   bool
+}
+
+func (l *fakeTransitionLookup) TaskItems(task *models.Task) ([]string, error) {
+	return l.items, nil
+}
+
+func (l *fakeTransitionLookup) SavedKeys(taskID int64) ([]string, error) {
+	return l.saved, nil
+}
+
+func (l *fakeTransitionLookup) ReworkCount(taskID int64) (int64, error) {
+	return l.rework, nil
+}
+
+func (l *fakeTransitionLookup) User(userID int64) (*models.User, error) {
+	return l.users[userID], nil
+}
+
+func (l *fakeTransitionLookup) PackageSkillTags(packageID int64) ([]string, error) {
+	return l.skillTags, nil
+}
+
+func (l *fakeTransitionLookup) ActiveTasks(userID int64, assigneeCol string, status models.TaskStatus) (int64, error) {
+	return l.active, nil
+}
+
+func (l *fakeTransitionLookup) ReplicaClaimed(task *models.Task, userID int64) (bool, error) {
+	return l.replica, nil
+}
+
+const (
+	testAdmin     int64 = 1
+	testAnnotator int64 = 2
+	testReviewer  int64 = 3
+	testOther     int64 = 4
+)
+
+func newFakeTransitionLookup() *fakeTransitionLookup {
+	return &fakeTransitionLookup{
+		items: []string{"a.jpg", "b.jpg"},
+		saved: []string{"a.jpg", "b.jpg"},
+		users: map[int64]*models.User{
+			testAnnotator: {ID: testAnnotator, SkillTags: []string{"medical"}, MaxTasks: 2},
+			testReviewer:  {ID: testReviewer, MaxTasks: 2},
+			testOther:     {ID: testOther, MaxTasks: 2},
+		},
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	renewed := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		actions  []string
+		task     models.Task
+		to       models.TaskStatus
+		userID   int64
+		role     string
+		assignee int64
+		setup    func(l *fakeTransitionLookup)
+		action   string // 允许时匹配的规则，为空表示应被拒绝
+	}{
+		{
+			name: "标注员领取新任务", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusCreated}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator, assignee: testAnnotator, action: taskActionClaim,
+		},
+		{
+			name: "标注员领取已被领取的任务", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusCreated, Annotator: testOther}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator, assignee: testAnnotator,
+		},
+		{
+			name: "标注员缺少技能标签", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusCreated}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator, assignee: testAnnotator,
+			setup: func(l *fakeTransitionLookup) { l.skillTags = []string{"medical", "video"} },
+		},
+		{
+			name: "标注员进行中的任务达到上限", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusCreated}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator, assignee: testAnnotator,
+			setup: func(l *fakeTransitionLookup) { l.active = 2 },
+		},
+		{
+			name: "标注员已领取同一分片的重叠任务", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusCreated, Replica: 2}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator, assignee: testAnnotator,
+			setup: func(l *fakeTransitionLookup) { l.replica = true },
+		},
+		{
+			name: "审核员不能领取未提交的任务", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusCreated}, to: models.TaskStatusProcessing,
+			userID: testReviewer, role: models.RoleReviewer, assignee: testReviewer,
+		},
+		{
+			name: "审核员领取已提交的任务", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusProcessed, Annotator: testAnnotator}, to: models.TaskStatusReviewing,
+			userID: testReviewer, role: models.RoleReviewer, assignee: testReviewer, action: taskActionClaim,
+		},
+		{
+			name: "标注员不能领取审核", actions: []string{taskActionClaim},
+			task: models.Task{Status: models.TaskStatusProcessed}, to: models.TaskStatusReviewing,
+			userID: testAnnotator, role: models.RoleAnnotator, assignee: testAnnotator,
+		},
+		{
+			name: "标注员提交已完成的任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator}, to: models.TaskStatusProcessed,
+			userID: testAnnotator, role: models.RoleAnnotator, action: taskActionSubmit,
+		},
+		{
+			name: "标注员提交未完成的任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator}, to: models.TaskStatusProcessed,
+			userID: testAnnotator, role: models.RoleAnnotator,
+			setup: func(l *fakeTransitionLookup) { l.saved = []string{"a.jpg", "old.jpg"} },
+		},
+		{
+			name: "标注员提交有待返工条目的任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator}, to: models.TaskStatusProcessed,
+			userID: testAnnotator, role: models.RoleAnnotator,
+			setup: func(l *fakeTransitionLookup) { l.rework = 1 },
+		},
+		{
+			name: "其他标注员提交任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator}, to: models.TaskStatusProcessed,
+			userID: testOther, role: models.RoleAnnotator,
+		},
+		{
+			name: "审核员不能提交标注", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator}, to: models.TaskStatusProcessed,
+			userID: testReviewer, role: models.RoleReviewer,
+		},
+		{
+			name: "审核员通过任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusReviewing, Reviewer: testReviewer}, to: models.TaskStatusApproved,
+			userID: testReviewer, role: models.RoleReviewer, action: taskActionReview,
+		},
+		{
+			name: "审核员驳回任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusReviewing, Reviewer: testReviewer}, to: models.TaskStatusRejected,
+			userID: testReviewer, role: models.RoleReviewer, action: taskActionReview,
+		},
+		{
+			name: "其他审核员审核任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusReviewing, Reviewer: testReviewer}, to: models.TaskStatusApproved,
+			userID: testOther, role: models.RoleReviewer,
+		},
+		{
+			name: "审核员不能跳过审核", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusProcessed}, to: models.TaskStatusApproved,
+			userID: testReviewer, role: models.RoleReviewer,
+		},
+		{
+			name: "标注员返工被驳回的任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusRejected, Annotator: testAnnotator}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator, action: taskActionRework,
+		},
+		{
+			name: "标注员不能把已通过的任务改回标注中", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusApproved, Annotator: testAnnotator}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator,
+		},
+		{
+			name: "管理员直接通过任务", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusCreated}, to: models.TaskStatusApproved,
+			userID: testAdmin, role: models.RoleAdmin, action: taskActionOverride,
+		},
+		{
+			name: "管理员把未完成的任务改为已提交", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusProcessing}, to: models.TaskStatusProcessed,
+			userID: testAdmin, role: models.RoleAdmin,
+			setup: func(l *fakeTransitionLookup) { l.saved = nil },
+		},
+		{
+			name: "管理员修改为相同状态", actions: statusUpdateActions,
+			task: models.Task{Status: models.TaskStatusApproved}, to: models.TaskStatusApproved,
+			userID: testAdmin, role: models.RoleAdmin,
+		},
+		{
+			name: "管理员重新分配标注中的任务", actions: []string{taskActionAssign},
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testOther}, to: models.TaskStatusProcessing,
+			userID: testAdmin, role: models.RoleAdmin, assignee: testAnnotator, action: taskActionAssign,
+		},
+		{
+			name: "标注员不能分配任务", actions: []string{taskActionAssign},
+			task: models.Task{Status: models.TaskStatusCreated}, to: models.TaskStatusProcessing,
+			userID: testAnnotator, role: models.RoleAnnotator, assignee: testAnnotator,
+		},
+		{
+			name: "管理员取消分配审核中的任务", actions: []string{taskActionUnassign},
+			task: models.Task{Status: models.TaskStatusReviewing, Reviewer: testReviewer}, to: models.TaskStatusProcessed,
+			userID: testAdmin, role: models.RoleAdmin, action: taskActionUnassign,
+		},
+		{
+			name: "释放租约过期的任务", actions: []string{taskActionRelease},
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator, LeaseExpiresAt: &expired}, to: models.TaskStatusCreated,
+			role: roleSystem, action: taskActionRelease,
+		},
+		{
+			name: "不释放已续期的任务", actions: []string{taskActionRelease},
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator, LeaseExpiresAt: &renewed}, to: models.TaskStatusCreated,
+			role: roleSystem,
+		},
+		{
+			name: "管理员不能触发租约释放", actions: []string{taskActionRelease},
+			task: models.Task{Status: models.TaskStatusProcessing, Annotator: testAnnotator, LeaseExpiresAt: &expired}, to: models.TaskStatusCreated,
+			userID: testAdmin, role: models.RoleAdmin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := newFakeTransitionLookup()
+			if tt.setup != nil {
+				tt.setup(lookup)
+			}
+			task := tt.task
+			tc := &transitionContext{
+				lookup:   lookup,
+				task:     &task,
+				from:     task.Status,
+				to:       tt.to,
+				userID:   tt.userID,
+				role:     tt.role,
+				assignee: tt.assignee,
+			}
+			rule, err := checkTransition(tc, tt.actions)
+			if tt.action == "" {
+				if err == nil {
+					t.Fatalf("%s -> %s (%s) 应被拒绝，实际匹配了 %s", tt.task.Status, tt.to, tt.role, rule.Action)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s -> %s (%s) 应被允许: %v", tt.task.Status, tt.to, tt.role, err)
+			}
+			if rule.Action != tt.action {
+				t.Fatalf("匹配的规则为 %s，期望 %s", rule.Action, tt.action)
+			}
+		})
+	}
+}
+
+// TestTaskTransitionsReachable 每条规则都能被自身的操作和角色匹配到，不会被前面的规则遮蔽
+func TestTaskTransitionsReachable(t *testing.T) {
+	for i := range taskTransitions {
+		rule := &taskTransitions[i]
+		from, to := rule.From, rule.To
+		if from == anyTaskStatus {
+			from = models.TaskStatusCreated
+		}
+		if to == anyTaskStatus {
+			to = models.TaskStatusApproved
+		}
+		for _, role := range rule.Roles {
+			found, err := findTaskTransition([]string{rule.Action}, from, to, role)
+			if err != nil {
+				t.Fatalf("规则 %s %s -> %s (%s) 无法匹配: %v", rule.Action, rule.From, rule.To, role, err)
+			}
+			if found != rule {
+				t.Fatalf("规则 %s %s -> %s (%s) 被第 %d 条之前的规则遮蔽", rule.Action, rule.From, rule.To, role, i)
+			}
+		}
+	}
+}
@@ -188,6 +188,8 @@ func (us *UserService) GetUserByID(id int64, asDailyLogin bool) (*models.UserRes
 		Email:     user.Email,
 		Avatar:    user.Avatar,
 		Role:      user.Role,
+		SkillTags: user.SkillTags,
+		MaxTasks:  user.MaxTasks,
 		CreatedAt: user.CreatedAt,
 	}
 
@@ -221,11 +223,33 @@ func (us *UserService) UpdateProfile(userID int64, req *models.UserUpdateReq) (*
 		Email:     user.Email,
 		Avatar:    user.Avatar,
 		Role:      user.Role,
+		SkillTags: user.SkillTags,
+		MaxTasks:  user.MaxTasks,
 		CreatedAt: user.CreatedAt,
 	}
 	return response, nil
 }
 
+// UpdateUserSkills 管理员设置用户的技能标签和同时进行的任务上限
+func (us *UserService) UpdateUserSkills(userID int64, req *models.UserSkillsReq) (*models.UserResponse, error) {
+	user := &models.User{}
+	has, err := config.DB.ID(userID).Get(user)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.New("user not found")
+	}
+
+	user.SkillTags = req.SkillTags
+	user.MaxTasks = req.MaxTasks
+	_, err = config.DB.ID(userID).Cols("skill_tags", "max_tasks").Update(user)
+	if err != nil {
+		return nil, err
+	}
+	return us.GetUserByID(userID, false)
+}
+
 func (us *UserService) GetUserByIDByEmail(email string) (int64, error) {
 	var user models.User
 	_, err := config.DB.Where("email = ?", email).Cols("id").Get(&user)
@@ -369,6 +393,8 @@ func (us *UserService) GetUserList(page, pageSize int) (*models.UserListResponse
 			Email:     user.Email,
 			Avatar:    user.Avatar,
 			Role:      user.Role,
+			SkillTags: user.SkillTags,
+			MaxTasks:  user.MaxTasks,
 			CreatedAt: user.CreatedAt,
 		}
 	}
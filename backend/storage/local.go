@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本地存储每页默认返回的数量，与 S3 保持一致
+const localDefaultMaxKeys = 1000
+
+// Local 本地目录存储，可用于 NFS 挂载目录或测试用的临时目录
+type Local struct {
+	root      string
+	urlPrefix string
+	signKey   []byte
+}
+
+// NewLocal 创建本地存储，Endpoint 为根目录。根目录解析符号链接后必须位于 BaseDir 下
+func NewLocal(cfg Config) (*Local, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("本地存储需要指定根目录")
+	}
+	root, err := resolveDir(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("本地存储根目录不可用: %v", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("本地存储根目录不可用: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("本地存储根目录不是目录: %s", root)
+	}
+	if cfg.BaseDir != "" {
+		base, err := resolveDir(cfg.BaseDir)
+		if err != nil {
+			return nil, fmt.Errorf("本地存储允许的目录不可用: %v", err)
+		}
+		if !withinDir(base, root) {
+			return nil, fmt.Errorf("本地存储根目录必须位于 %s 下", base)
+		}
+	}
+	return &Local{
+		root:      root,
+		urlPrefix: strings.TrimRight(cfg.URLPrefix, "/"),
+		signKey:   cfg.SignKey,
+	}, nil
+}
+
+// Path 返回对象在本地的文件路径，不允许访问根目录之外的文件
+func (p *Local) Path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("无效的对象key: %s", key)
+	}
+	cleaned := path.Clean(key)
+	if cleaned != strings.TrimSuffix(key, "/") || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("无效的对象key: %s", key)
+	}
+	file := filepath.Join(p.root, filepath.FromSlash(cleaned))
+	if err := p.checkSymlinks(file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// checkSymlinks 解析路径中已存在部分的符号链接，确认仍位于根目录下；不存在的部分不会是符号链接
+func (p *Local) checkSymlinks(file string) error {
+	existing := file
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !withinDir(p.root, resolved) {
+				return fmt.Errorf("对象路径超出存储根目录: %s", file)
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return err
+		}
+		existing = parent
+	}
+}
+
+// resolveDir 返回目录解析符号链接后的绝对路径
+func resolveDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// withinDir file 是否为 dir 或位于 dir 下
+func withinDir(dir, file string) bool {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// localEntry 列举时的对象或子目录
+type localEntry struct {
+	key      string
+	isPrefix bool
+	object   Object
+}
+
+func (p *Local) List(ctx context.Context, in ListInput) (*ListResult, error) {
+	maxKeys := in.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = localDefaultMaxKeys
+	}
+
+	// 只需要从前缀所在的目录开始遍历
+	start := p.root
+	if i := strings.LastIndex(in.Prefix, "/"); i >= 0 {
+		dir, err := p.Path(in.Prefix[:i+1])
+		if err != nil {
+			return nil, err
+		}
+		start = dir
+	}
+	if _, err := os.Stat(start); err != nil {
+		if os.IsNotExist(err) {
+			return &ListResult{Objects: []Object{}}, nil
+		}
+		return nil, err
+	}
+
+	var entries []localEntry
+	prefixes := map[string]bool{}
+	addPrefix := func(prefix string) {
+		if !prefixes[prefix] {
+			prefixes[prefix] = true
+			entries = append(entries, localEntry{key: prefix, isPrefix: true})
+		}
+	}
+
+	err := filepath.WalkDir(start, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if file == start {
+			return nil
+		}
+		rel, err := filepath.Rel(p.root, file)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			dirKey := key + "/"
+			if !strings.HasPrefix(dirKey, in.Prefix) && !strings.HasPrefix(in.Prefix, dirKey) {
+				return filepath.SkipDir
+			}
+			// 按 "/" 分隔时子目录整体作为一个公共前缀返回，不再深入
+			if in.Delimiter == "/" && strings.HasPrefix(dirKey, in.Prefix) {
+				addPrefix(dirKey)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || !strings.HasPrefix(key, in.Prefix) {
+			return nil
+		}
+		if in.Delimiter != "" {
+			rest := key[len(in.Prefix):]
+			if i := strings.Index(rest, in.Delimiter); i >= 0 {
+				addPrefix(key[:len(in.Prefix)+i+len(in.Delimiter)])
+				return nil
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, localEntry{key: key, object: localObject(key, info)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 与 S3 一样按 key 的字典序返回，续传标记为上一页最后一个 key
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	result := &ListResult{Objects: []Object{}}
+	count := 0
+	for _, entry := range entries {
+		if in.ContinuationToken != "" && entry.key <= in.ContinuationToken {
+			continue
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		if entry.isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, entry.key)
+		} else {
+			result.Objects = append(result.Objects, entry.object)
+		}
+		result.NextContinuationToken = entry.key
+		count++
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+	return result, nil
+}
+
+func (p *Local) Head(ctx context.Context, key string) (*Object, error) {
+	file, err := p.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	object := localObject(key, info)
+	return &object, nil
+}
+
+// localObject 文件的元信息，ETag 由修改时间和大小生成
+func localObject(key string, info fs.FileInfo) Object {
+	return Object{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		ETag:         fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+	}
+}
+
+func (p *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if _, err := p.Head(ctx, key); err != nil {
+		return nil, err
+	}
+	file, _ := p.Path(key)
+	return os.Open(file)
+}
+
+func (p *Local) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	file, err := p.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func (p *Local) Delete(ctx context.Context, key string) error {
+	file, err := p.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// PresignGet 生成带过期时间和签名的下载地址，由后端校验签名后返回文件
+func (p *Local) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if p.urlPrefix == "" || len(p.signKey) == 0 {
+		return "", errors.New("本地存储未配置下载地址")
+	}
+	if _, err := p.Path(key); err != nil {
+		return "", err
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", p.sign(key, expiresAt))
+
+	var escaped []string
+	for _, part := range strings.Split(key, "/") {
+		escaped = append(escaped, url.PathEscape(part))
+	}
+	return p.urlPrefix + "/" + strings.Join(escaped, "/") + "?" + query.Encode(), nil
+}
+
+// Verify 校验 PresignGet 生成的下载地址
+func (p *Local) Verify(key, expires, signature string) error {
+	if len(p.signKey) == 0 {
+		return errors.New("本地存储未配置下载地址")
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("无效的过期时间")
+	}
+	if time.Now().Unix() > expiresAt {
+		return errors.New("下载地址已过期")
+	}
+	if !hmac.Equal([]byte(p.sign(key, expires)), []byte(signature)) {
+		return errors.New("签名无效")
+	}
+	return nil
+}
+
+// sign 计算对象 key 和过期时间的签名
+func (p *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, p.signKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestLocal 在临时目录下创建本地存储并写入文件
+func newTestLocal(t *testing.T, files ...string) *Local {
+	t.Helper()
+	root := t.TempDir()
+	for _, key := range files {
+		file := filepath.Join(root, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(key), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := NewLocal(Config{Endpoint: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLocalPath(t *testing.T) {
+	p := newTestLocal(t)
+	for _, key := range []string{"a.jpg", "dir/b.jpg", "dir/"} {
+		if _, err := p.Path(key); err != nil {
+			t.Errorf("Path(%q) 应被允许: %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "/etc/passwd", "../a.jpg", "dir/../../a.jpg", "..", "dir\\a.jpg", "dir/./a.jpg"} {
+		if _, err := p.Path(key); err == nil {
+			t.Errorf("Path(%q) 应被拒绝", key)
+		}
+	}
+}
+
+func TestLocalPathSymlinkEscape(t *testing.T) {
+	p := newTestLocal(t, "inside/a.jpg")
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(p.root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(p.root, "inside"), filepath.Join(p.root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := p.Get(ctx, "link/secret.txt"); err == nil {
+		t.Error("通过符号链接读取根目录之外的文件应被拒绝")
+	}
+	if err := p.Put(ctx, "link/new.txt", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Error("通过符号链接写入根目录之外应被拒绝")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); !os.IsNotExist(err) {
+		t.Error("根目录之外不应写入文件")
+	}
+	// 指向根目录内的符号链接仍然可以访问
+	if _, err := p.Head(ctx, "alias/a.jpg"); err != nil {
+		t.Errorf("根目录内的符号链接应被允许: %v", err)
+	}
+}
+
+func TestNewLocalBaseDir(t *testing.T) {
+	base := t.TempDir()
+	inside := filepath.Join(base, "bucket")
+	if err := os.Mkdir(inside, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	link := filepath.Join(base, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewLocal(Config{Endpoint: inside, BaseDir: base}); err != nil {
+		t.Errorf("允许目录下的根目录应被允许: %v", err)
+	}
+	if _, err := NewLocal(Config{Endpoint: outside, BaseDir: base}); err == nil {
+		t.Error("允许目录之外的根目录应被拒绝")
+	}
+	if _, err := NewLocal(Config{Endpoint: link, BaseDir: base}); err == nil {
+		t.Error("指向允许目录之外的符号链接应被拒绝")
+	}
+	if _, err := NewLocal(Config{Endpoint: filepath.Join(inside, ".."), BaseDir: inside}); err == nil {
+		t.Error("允许目录的上级目录应被拒绝")
+	}
+}
+
+func TestLocalListDelimiter(t *testing.T) {
+	p := newTestLocal(t, "a.jpg", "e.jpg", "dir1/b.jpg", "dir1/sub/c.jpg", "dir2/d.jpg")
+	ctx := context.Background()
+
+	tests := []struct {
+		prefix   string
+		objects  []string
+		prefixes []string
+	}{
+		{"", []string{"a.jpg", "e.jpg"}, []string{"dir1/", "dir2/"}},
+		{"dir1/", []string{"dir1/b.jpg"}, []string{"dir1/sub/"}},
+		{"dir1/sub/", []string{"dir1/sub/c.jpg"}, nil},
+		{"missing/", nil, nil},
+	}
+	for _, tt := range tests {
+		result, err := p.List(ctx, ListInput{Prefix: tt.prefix, Delimiter: "/"})
+		if err != nil {
+			t.Fatalf("List(%q): %v", tt.prefix, err)
+		}
+		if got := objectKeys(result.Objects); !reflect.DeepEqual(got, tt.objects) {
+			t.Errorf("List(%q) 对象为 %v，期望 %v", tt.prefix, got, tt.objects)
+		}
+		if !reflect.DeepEqual(result.CommonPrefixes, tt.prefixes) {
+			t.Errorf("List(%q) 子目录为 %v，期望 %v", tt.prefix, result.CommonPrefixes, tt.prefixes)
+		}
+	}
+}
+
+func TestLocalListContinuation(t *testing.T) {
+	files := []string{"a.jpg", "dir1/b.jpg", "dir1/sub/c.jpg", "dir2/d.jpg", "e.jpg"}
+	p := newTestLocal(t, files...)
+	ctx := context.Background()
+
+	var keys []string
+	token := ""
+	for page := 0; ; page++ {
+		if page > len(files) {
+			t.Fatal("分页没有结束")
+		}
+		result, err := p.List(ctx, ListInput{ContinuationToken: token, MaxKeys: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Objects) > 2 {
+			t.Fatalf("每页最多 2 个对象，实际 %d 个", len(result.Objects))
+		}
+		keys = append(keys, objectKeys(result.Objects)...)
+		if !result.IsTruncated {
+			if result.NextContinuationToken != "" {
+				t.Error("最后一页不应返回续传标记")
+			}
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if !reflect.DeepEqual(keys, files) {
+		t.Errorf("分页列举的对象为 %v，期望 %v", keys, files)
+	}
+
+	// 按目录分页时子目录同样占用名额
+	result, err := p.List(ctx, ListInput{Delimiter: "/", MaxKeys: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := objectKeys(result.Objects); !reflect.DeepEqual(got, []string{"a.jpg"}) || !reflect.DeepEqual(result.CommonPrefixes, []string{"dir1/"}) {
+		t.Fatalf("第一页为 %v %v", got, result.CommonPrefixes)
+	}
+	result, err = p.List(ctx, ListInput{Delimiter: "/", MaxKeys: 2, ContinuationToken: result.NextContinuationToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := objectKeys(result.Objects); !reflect.DeepEqual(got, []string{"e.jpg"}) || !reflect.DeepEqual(result.CommonPrefixes, []string{"dir2/"}) || result.IsTruncated {
+		t.Fatalf("第二页为 %v %v，truncated=%v", got, result.CommonPrefixes, result.IsTruncated)
+	}
+}
+
+func objectKeys(objects []Object) []string {
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	return keys
+}
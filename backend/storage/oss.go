@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// OSS 阿里云对象存储
+type OSS struct {
+	bucket *oss.Bucket
+}
+
+// NewOSS 创建 OSS 存储，未指定 Endpoint 时按 Region 使用公网地址
+func NewOSS(cfg Config) (*OSS, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://oss-%s.aliyuncs.com", cfg.Region)
+	}
+	client, err := oss.New(endpoint, cfg.AccessKey, cfg.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := client.Bucket(cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return &OSS{bucket: bucket}, nil
+}
+
+func (p *OSS) List(ctx context.Context, in ListInput) (*ListResult, error) {
+	options := []oss.Option{oss.Prefix(in.Prefix)}
+	if in.Delimiter != "" {
+		options = append(options, oss.Delimiter(in.Delimiter))
+	}
+	if in.ContinuationToken != "" {
+		options = append(options, oss.ContinuationToken(in.ContinuationToken))
+	}
+	if in.MaxKeys > 0 {
+		options = append(options, oss.MaxKeys(in.MaxKeys))
+	}
+
+	output, err := p.bucket.ListObjectsV2(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ListResult{
+		Objects:               make([]Object, 0, len(output.Objects)),
+		CommonPrefixes:        output.CommonPrefixes,
+		NextContinuationToken: output.NextContinuationToken,
+		IsTruncated:           output.IsTruncated,
+	}
+	for _, obj := range output.Objects {
+		result.Objects = append(result.Objects, Object{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			LastModified: obj.LastModified,
+			ETag:         strings.Trim(obj.ETag, `"`),
+		})
+	}
+	return result, nil
+}
+
+func (p *OSS) Head(ctx context.Context, key string) (*Object, error) {
+	header, err := p.bucket.GetObjectDetailedMeta(key)
+	if err != nil {
+		return nil, ossError(err)
+	}
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	modified, _ := http.ParseTime(header.Get("Last-Modified"))
+	return &Object{
+		Key:          key,
+		Size:         size,
+		LastModified: modified,
+		ETag:         strings.Trim(header.Get("ETag"), `"`),
+		ContentType:  header.Get("Content-Type"),
+	}, nil
+}
+
+func (p *OSS) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	body, err := p.bucket.GetObject(key)
+	if err != nil {
+		return nil, ossError(err)
+	}
+	return body, nil
+}
+
+func (p *OSS) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	var options []oss.Option
+	if contentType != "" {
+		options = append(options, oss.ContentType(contentType))
+	}
+	return p.bucket.PutObject(key, body, options...)
+}
+
+func (p *OSS) Delete(ctx context.Context, key string) error {
+	return p.bucket.DeleteObject(key)
+}
+
+func (p *OSS) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return p.bucket.SignURL(key, oss.HTTPGet, int64(expires/time.Second))
+}
+
+// ossError 把对象不存在的错误转换为 ErrNotFound
+func ossError(err error) error {
+	var serviceErr oss.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+// Package storage 对象存储的统一接口，存储桶按 provider 字段选择 S3（含 MinIO 等兼容服务）、阿里云 OSS 或本地目录
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// 存储类型
+const (
+	ProviderS3    = "s3"
+	ProviderOSS   = "oss"
+	ProviderLocal = "local"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Object 对象元信息
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string // 内容变化时改变，不保证是内容的 MD5
+	ContentType  string
+}
+
+// ListInput 列举对象的参数，Delimiter 为 "/" 时只列出当前目录，子目录放在 CommonPrefixes 中
+type ListInput struct {
+	Prefix            string
+	Delimiter         string
+	ContinuationToken string // 上一页返回的 NextContinuationToken
+	MaxKeys           int    // 为 0 时使用各实现的默认值
+}
+
+// ListResult 列举对象的结果
+type ListResult struct {
+	Objects               []Object
+	CommonPrefixes        []string
+	NextContinuationToken string
+	IsTruncated           bool
+}
+
+// Provider 对象存储
+type Provider interface {
+	// List 按字典序分页列举对象
+	List(ctx context.Context, in ListInput) (*ListResult, error)
+	// Head 获取对象元信息，对象不存在时返回 ErrNotFound
+	Head(ctx context.Context, key string) (*Object, error)
+	// Get 读取对象内容，调用方负责关闭，对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// PresignGet 生成限时有效的下载地址
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Config 创建存储的参数
+type Config struct {
+	Provider  string
+	Bucket    string // 存储桶名称，本地存储不使用
+	Region    string
+	Endpoint  string // S3 兼容服务或 OSS 的访问地址，本地存储为根目录
+	BaseDir   string // 本地存储的根目录必须位于此目录下，为空时不限制
+	PathMode  bool   // S3 使用路径模式访问
+	AccessKey string
+	SecretKey string
+
+	// 本地存储生成下载地址使用
+	URLPrefix string // 下载地址前缀，对象 key 拼接在其后
+	SignKey   []byte // 下载地址签名密钥
+}
+
+// New 按配置创建存储，Provider 为空时使用 S3
+func New(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "", ProviderS3:
+		return NewS3(cfg)
+	case ProviderOSS:
+		return NewOSS(cfg)
+	case ProviderLocal:
+		return NewLocal(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Provider)
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// S3 AWS S3 及 MinIO 等兼容服务
+type S3 struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3 创建 S3 存储，指定 Endpoint 时访问兼容服务
+func NewS3(cfg Config) (*S3, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(),
+		awsconfig.WithRegion(cfg.Region),
+		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			cfg.AccessKey,
+			cfg.SecretKey,
+			"", // session token
+		)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = cfg.PathMode
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	return &S3{client: client, bucket: cfg.Bucket}, nil
+}
+
+// HeadBucket 检查存储桶是否可以访问
+func (p *S3) HeadBucket(ctx context.Context) error {
+	_, err := p.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(p.bucket)})
+	return err
+}
+
+func (p *S3) List(ctx context.Context, in ListInput) (*ListResult, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(p.bucket),
+		Prefix: aws.String(in.Prefix),
+	}
+	if in.Delimiter != "" {
+		input.Delimiter = aws.String(in.Delimiter)
+	}
+	if in.ContinuationToken != "" {
+		input.ContinuationToken = aws.String(in.ContinuationToken)
+	}
+	if in.MaxKeys > 0 {
+		input.MaxKeys = aws.Int32(int32(in.MaxKeys))
+	}
+
+	output, err := p.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ListResult{
+		Objects:               make([]Object, 0, len(output.Contents)),
+		NextContinuationToken: aws.ToString(output.NextContinuationToken),
+		IsTruncated:           aws.ToBool(output.IsTruncated),
+	}
+	for _, obj := range output.Contents {
+		result.Objects = append(result.Objects, Object{
+			Key:          aws.ToString(obj.Key),
+			Size:         aws.ToInt64(obj.Size),
+			LastModified: aws.ToTime(obj.LastModified),
+			ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+		})
+	}
+	for _, prefix := range output.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, aws.ToString(prefix.Prefix))
+	}
+	return result, nil
+}
+
+func (p *S3) Head(ctx context.Context, key string) (*Object, error) {
+	output, err := p.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return &Object{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		LastModified: aws.ToTime(output.LastModified),
+		ETag:         strings.Trim(aws.ToString(output.ETag), `"`),
+		ContentType:  aws.ToString(output.ContentType),
+	}, nil
+}
+
+func (p *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	output, err := p.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return output.Body, nil
+}
+
+func (p *S3) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err := p.client.PutObject(ctx, input)
+	return err
+}
+
+func (p *S3) Delete(ctx context.Context, key string) error {
+	_, err := p.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (p *S3) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	request, err := s3.NewPresignClient(p.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return request.URL, nil
+}
+
+// s3Error 把对象不存在的错误转换为 ErrNotFound
+func s3Error(err error) error {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) {
+		return ErrNotFound
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotFound" {
+		return ErrNotFound
+	}
+	return err
+}
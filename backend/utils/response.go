@@ -3,6 +3,7 @@ package utils
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,6 +31,15 @@ func ResponseErr(c *gin.Context, err string, code int) {
 	})
 }
 
+// ResponseErrWithData 返回错误及附加数据，如字段级校验错误
+func ResponseErrWithData(c *gin.Context, err string, code int, data interface{}) {
+	c.JSON(code, Response{
+		Code:  code,
+		Data:  data,
+		Error: err,
+	})
+}
+
 func ResponseSuccess(c *gin.Context) {
 	c.JSON(200, Response{
 		Code: 200,
@@ -43,3 +53,14 @@ func ResponseSuccess(c *gin.Context) {
 func ParseInt64(str string) (int64, error) {
 	return strconv.ParseInt(str, 10, 64)
 }
+
+// FormatETag 将版本号格式化为 ETag
+func FormatETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ParseIfMatch 解析 If-Match 请求头中的版本号，兼容弱校验前缀 W/
+func ParseIfMatch(value string) (int, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	return strconv.Atoi(strings.Trim(value, `"`))
+}
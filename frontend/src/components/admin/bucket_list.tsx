@@ -1,10 +1,91 @@
 import { api } from "@/lib/api";
-import { Bucket } from "@/lib/types";
-import { DeleteOutlined } from "@ant-design/icons";
-import { useAntdTable } from "ahooks";
-import { Popconfirm } from "antd";
+import { Bucket, MissingObject } from "@/lib/types";
+import { DeleteOutlined, SyncOutlined } from "@ant-design/icons";
+import { useAntdTable, useRequest } from "ahooks";
+import { Button, message, Modal, Popconfirm, Space, Tag, Tooltip } from "antd";
 import Table from "antd/es/table/Table";
-import { forwardRef, useImperativeHandle } from "react";
+import { forwardRef, useImperativeHandle, useState } from "react";
+
+// 对象索引的同步状态，同步中时轮询
+function BucketSyncStatus({ bucket }: { bucket: Bucket }) {
+  const [missing, setMissing] = useState<MissingObject[]>();
+  const { data, refresh, loading } = useRequest(
+    () => api.bucket.getBucketSync(bucket.id),
+    {
+      pollingInterval: 5000,
+      pollingWhenHidden: false,
+    }
+  );
+
+  const resync = async () => {
+    await api.bucket.resyncBucket(bucket.id);
+    message.success("Sync started");
+    refresh();
+  };
+
+  const showMissing = async () => {
+    const res = await api.bucket.getMissingObjects(bucket.id);
+    setMissing(res.list);
+  };
+
+  const statusColor =
+    data?.status === "running"
+      ? "processing"
+      : data?.status === "failed"
+      ? "error"
+      : "default";
+
+  return (
+    <Space>
+      <Tooltip title={data?.error}>
+        <Tag color={statusColor}>{data?.status ?? "-"}</Tag>
+      </Tooltip>
+      <span>
+        {data?.lastSyncedAt
+          ? `${data.objects} objects, synced ${new Date(
+              data.lastSyncedAt
+            ).toLocaleString()}`
+          : "never synced"}
+      </span>
+      <Button
+        size="small"
+        icon={<SyncOutlined spin={data?.status === "running"} />}
+        disabled={loading || data?.status === "running"}
+        onClick={resync}
+      >
+        Resync
+      </Button>
+      {data?.lastSyncedAt && (
+        <Button size="small" type="link" onClick={showMissing}>
+          Missing objects
+        </Button>
+      )}
+      <Modal
+        title="Deleted objects still referenced by packages"
+        open={!!missing}
+        onCancel={() => setMissing(undefined)}
+        footer={null}
+        width={800}
+      >
+        <Table
+          size="small"
+          rowKey={(obj: MissingObject) => `${obj.packageId}:${obj.key}`}
+          dataSource={missing}
+          columns={[
+            { title: "Package", dataIndex: "packageName", key: "packageName" },
+            { title: "Key", dataIndex: "key", key: "key" },
+            {
+              title: "Deleted At",
+              dataIndex: "deletedAt",
+              key: "deletedAt",
+              render: (v?: string) => (v ? new Date(v).toLocaleString() : "-"),
+            },
+          ]}
+        />
+      </Modal>
+    </Space>
+  );
+}
 
 export const AdminBuckets = forwardRef((_, ref) => {
   const { tableProps, refresh } = useAntdTable(
@@ -30,11 +111,21 @@ export const AdminBuckets = forwardRef((_, ref) => {
       dataIndex: "name",
       key: "name",
     },
+    {
+      title: "Provider",
+      dataIndex: "provider",
+      key: "provider",
+    },
     {
       title: "Region",
       dataIndex: "region",
       key: "region",
     },
+    {
+      title: "Object Index",
+      key: "sync",
+      render: (record: Bucket) => <BucketSyncStatus bucket={record} />,
+    },
     {
       title: "Created At",
       dataIndex: "created_at",
@@ -1,7 +1,12 @@
 import { useState, useEffect, useMemo, useRef } from "react";
 import { useParams, useNavigate } from "react-router";
 import { api } from "@/lib/api";
-import { PackageStatus, ObjectInfo, BucketObjectListRes } from "@/lib/types";
+import {
+  PackageStatus,
+  ObjectInfo,
+  BucketObjectListRes,
+  ListObjectsFilter,
+} from "@/lib/types";
 import {
   Button,
   Tag,
@@ -15,6 +20,8 @@ import {
   Checkbox,
   Divider,
   Typography,
+  Input,
+  Select,
 } from "antd";
 import type { ColumnsType } from "antd/es/table";
 import VirtualList from "rc-virtual-list";
@@ -39,6 +46,9 @@ export default function PackageEdit() {
   const [hasMore, setHasMore] = useState<boolean>(false);
   const [objects, setObjects] = useState<ObjectInfo[]>([]);
   const [loadingObjects, setLoadingObjects] = useState<boolean>(false);
+  // 存储桶已同步对象索引时才支持搜索、筛选和排序
+  const [indexed, setIndexed] = useState<boolean>(false);
+  const [filter, setFilter] = useState<ListObjectsFilter>({});
 
   const handleBack = () => navigate(-1);
   const {
@@ -78,7 +88,7 @@ export default function PackageEdit() {
   const { data: bucketInfo } = useRequest(
     async () => {
       const bucket = await api.bucket.getBucket(packageDetail?.bucketId!);
-      const bucketFolders = await api.bucket.listDirectoryTree(bucket);
+      const bucketFolders = await api.bucket.listDirectoryTree(bucket.id);
       if (packageDetail?.items?.length) {
         const bucketFolderKey =
           packageDetail!.items[0].substring(
@@ -110,30 +120,30 @@ export default function PackageEdit() {
       setContinuationToken(undefined);
       loadObjects(false);
     }
-  }, [curDir]);
+  }, [curDir, filter]);
 
   // 加载对象列表 - 修复分页问题
   const loadObjects = async (append = true) => {
     if (!bucketInfo?.bucket) return;
     if (!curDir?.isLeaf) return;
+    if (append && !hasMore) return;
 
     try {
       setLoadingObjects(true);
 
-      // 对于 S3 分页，我们需要处理 ContinuationToken
-      // 但为了简化，我们每次重新请求第一页，忽略 ContinuationToken
-      // 这样分页控件就能正常工作
+      // 翻页时使用上一页返回的 ContinuationToken，切换目录时从第一页开始
       const res: BucketObjectListRes = await api.bucket.listObjects(
-        bucketInfo.bucket,
+        bucketInfo.bucket.id,
         curDir.key,
         PAGE_SIZE,
-        continuationToken // 使用 ContinuationToken
+        append ? continuationToken : undefined, // 切换目录时 state 尚未更新，不能使用旧的 token
+        filter
       );
 
       setObjects(append ? objects.concat(res.list) : res.list ?? []);
       setHasMore(res.hasMore);
-      // 不再存储 ContinuationToken，因为每次分页都重新从第一页开始
       setContinuationToken(res.nextContinuationToken);
+      setIndexed(res.indexed);
       append && message.success(`${res.list.length} more items loaded!`);
     } catch (error) {
       console.error("Error loading objects:", error);
@@ -389,6 +399,48 @@ export default function PackageEdit() {
               </div>
               <Button icon={<CheckOutlined />} onClick={handleSelectAll}>check all</Button>
             </div>
+            {indexed && (
+              <div className="flex gap-2 items-center p-2">
+                <Input.Search
+                  placeholder="Search file name in this folder"
+                  allowClear
+                  onSearch={(search) =>
+                    setFilter((prev) => ({ ...prev, search: search || undefined }))
+                  }
+                />
+                <Select
+                  placeholder="Type"
+                  allowClear
+                  style={{ width: 120 }}
+                  value={filter.media_type}
+                  onChange={(media_type) =>
+                    setFilter((prev) => ({ ...prev, media_type }))
+                  }
+                  options={[
+                    { label: "Image", value: "image" },
+                    { label: "Video", value: "video" },
+                  ]}
+                />
+                <Select
+                  style={{ width: 180 }}
+                  value={`${filter.sort_by ?? "key"}:${filter.order ?? "asc"}`}
+                  onChange={(value: string) => {
+                    const [sort_by, order] = value.split(":");
+                    setFilter((prev) => ({
+                      ...prev,
+                      sort_by: sort_by as ListObjectsFilter["sort_by"],
+                      order: order as ListObjectsFilter["order"],
+                    }));
+                  }}
+                  options={[
+                    { label: "Path", value: "key:asc" },
+                    { label: "Name", value: "name:asc" },
+                    { label: "Largest first", value: "size:desc" },
+                    { label: "Newest first", value: "last_modified:desc" },
+                  ]}
+                />
+              </div>
+            )}
 
             <List loading={loadingObjects} className="container-bg">
               <VirtualList
@@ -1,7 +1,29 @@
 import { api } from "@/lib/api";
-import { Role, Task, TaskStatus, User } from "@/lib/types";
+import {
+  PackageSLA,
+  Role,
+  Task,
+  TaskBatchRequest,
+  TaskEvent,
+  TaskListRequest,
+  TaskStatus,
+  User,
+} from "@/lib/types";
 import { useAntdTable } from "ahooks";
-import { Button, Table, message, Space, Modal, Select } from "antd";
+import {
+  Button,
+  Table,
+  message,
+  Space,
+  Modal,
+  Select,
+  Tag,
+  Timeline,
+  Tabs,
+  Input,
+  InputNumber,
+  Checkbox,
+} from "antd";
 import type { ColumnsType } from "antd/es/table";
 import { useState } from "react";
 import { getStatusTag } from "@/lib/util";
@@ -14,21 +36,149 @@ export default function AdminTasks() {
   const [assignUserId, setAssignUserId] = useState<number | null>(null);
   const [users, setUsers] = useState<any[]>([]);
   const [loadingUsers, setLoadingUsers] = useState(false);
+  const [timelineTask, setTimelineTask] = useState<Task | null>(null);
+  const [timeline, setTimeline] = useState<TaskEvent[]>([]);
+  const [view, setView] = useState("all");
+  const [priorityTask, setPriorityTask] = useState<Task | null>(null);
+  const [priority, setPriority] = useState<number | null>(0);
+  const [dueAt, setDueAt] = useState("");
+  const [clearDueAt, setClearDueAt] = useState(false);
+  const [selectedIds, setSelectedIds] = useState<number[]>([]);
+  const [batchMode, setBatchMode] = useState(false);
 
-  // 获取任务列表
+  // 批量操作，任一任务失败时整批回滚并列出失败原因
+  const runBatch = async (req: Omit<TaskBatchRequest, "task_ids">) => {
+    try {
+      const response = await api.task.batchUpdateTasks({ ...req, task_ids: selectedIds });
+      if (response.committed) {
+        message.success(`${response.results.length} tasks updated`);
+        setSelectedIds([]);
+        refreshTaskTable();
+        return true;
+      }
+      Modal.error({
+        title: "Batch operation rolled back",
+        content: (
+          <ul>
+            {response.results
+              .filter((result) => !result.success)
+              .map((result) => (
+                <li key={result.taskId}>
+                  #{result.taskId}: {result.error}
+                </li>
+              ))}
+          </ul>
+        ),
+      });
+    } catch (error) {
+      // 错误信息已由 http 提示
+    }
+    return false;
+  };
+
+  const handleBatchUnassign = () => {
+    Modal.confirm({
+      title: `Unassign ${selectedIds.length} tasks?`,
+      onOk: () => runBatch({ action: "unassign" }),
+    });
+  };
+
+  const handleBatchStatus = (status: TaskStatus) => {
+    Modal.confirm({
+      title: `Change ${selectedIds.length} tasks to ${status}?`,
+      onOk: () => runBatch({ action: "status", status }),
+    });
+  };
+
+  // 获取任务列表，逾期视图只显示已过截止时间且未完成的任务
   const { tableProps: taskTableProps, refresh: refreshTaskTable } = useAntdTable(
-    async ({ pageSize, current }) => {
+    async ({ pageSize, current, sorter }) => {
+      // 表头排序优先，逾期视图默认按截止时间排序
+      let sortBy: TaskListRequest["sort_by"] = view === "overdue" ? "due_at" : undefined;
+      if (sorter?.order && sorter.field === "priority") sortBy = "priority";
+      if (sorter?.order && sorter.field === "dueAt") sortBy = "due_at";
       const response = await api.task.getTaskList({
         page: current,
         page_size: pageSize,
+        overdue: view === "overdue" || undefined,
+        sort_by: sortBy,
+        order: sorter?.order === "descend" ? "desc" : undefined,
       });
       return {
         list: response.list,
         total: response.total,
       };
-    }
+    },
+    { refreshDeps: [view] }
   );
 
+  // 各包的 SLA 达成率
+  const [slaList, setSlaList] = useState<PackageSLA[]>([]);
+  const loadSLA = async () => {
+    try {
+      const response = await api.task.getPackageSLA();
+      setSlaList(response.list);
+    } catch (error) {
+      message.error("Failed to load SLA report");
+    }
+  };
+
+  const changeView = (key: string) => {
+    setView(key);
+    if (key === "sla") {
+      loadSLA();
+    }
+  };
+
+  // 打开优先级设置，datetime-local 使用本地时间，task 为空时批量设置选中的任务
+  const openPriority = (task: Task | null) => {
+    setBatchMode(!task);
+    setPriorityTask(task ?? ({ name: `${selectedIds.length} tasks` } as Task));
+    setPriority(task ? task.priority ?? 0 : null);
+    setClearDueAt(false);
+    if (task?.dueAt) {
+      const date = new Date(task.dueAt);
+      date.setMinutes(date.getMinutes() - date.getTimezoneOffset());
+      setDueAt(date.toISOString().slice(0, 16));
+    } else {
+      setDueAt("");
+    }
+  };
+
+  const handleSetPriority = async () => {
+    if (!priorityTask) return;
+    const due = dueAt ? new Date(dueAt).toISOString() : null;
+    if (batchMode) {
+      // 批量设置时优先级或截止时间为空表示保留各任务原有的值
+      if (priority === null && !due && !clearDueAt) {
+        message.warning("Please set a priority or due date");
+        return;
+      }
+      const req: Omit<TaskBatchRequest, "task_ids"> = {
+        action: "priority",
+        priority,
+        dueAt: clearDueAt ? null : due,
+        clearDueAt,
+      };
+      if (await runBatch(req)) {
+        setPriorityTask(null);
+      }
+      return;
+    }
+    try {
+      await api.task.setTaskPriority({
+        task_id: priorityTask.id,
+        priority: priority ?? 0,
+        dueAt: due,
+      });
+      message.success("Priority updated");
+      setPriorityTask(null);
+      refreshTaskTable();
+    } catch (error) {
+      // 错误信息已由 http 提示
+    }
+  };
+
   // 获取用户列表（用于分配任务）
   const loadUsers = async () => {
     setLoadingUsers(true);
@@ -42,9 +192,10 @@ export default function AdminTasks() {
     }
   };
 
-  // 打开分配任务模态框
-  const openAssignModal = (task: Task) => {
-    setSelectedTask(task);
+  // 打开分配任务模态框，task 为空时批量分配选中的任务
+  const openAssignModal = (task: Task | null) => {
+    setBatchMode(!task);
+    setSelectedTask(task ?? ({ name: `${selectedIds.length} tasks` } as Task));
     setAssignUserId(null);
     loadUsers();
     setAssignModalVisible(true);
@@ -56,6 +207,12 @@ export default function AdminTasks() {
       message.error("Please select a user");
       return;
     }
+    if (batchMode) {
+      if (await runBatch({ action: "assign", user_id: assignUserId })) {
+        setAssignModalVisible(false);
+      }
+      return;
+    }
 
     try {
       await api.task.assignTask({
@@ -70,6 +227,60 @@ export default function AdminTasks() {
     }
   };
 
+  // 打开任务时间线
+  const openTimeline = async (task: Task) => {
+    setTimelineTask(task);
+    setTimeline([]);
+    try {
+      const response = await api.task.getTaskTimeline(task.id);
+      setTimeline(response.list);
+    } catch (error) {
+      message.error("Failed to load timeline");
+    }
+  };
+
+  // 时间线中的一条事件，管理员分配与自行领取用不同颜色区分
+  const renderEvent = (event: TaskEvent) => {
+    const actor = `${event.actorName || event.actorId || "system"} (${event.actorRole})`;
+    const change = `${event.oldValue || "-"} → ${event.newValue || "-"}`;
+    let text = "";
+    switch (event.type) {
+      case "claim":
+        text = `claimed as ${event.field}`;
+        break;
+      case "assign":
+        text = `assigned ${event.field} ${change}`;
+        break;
+      case "priority":
+        text = `${event.field} ${change}`;
+        break;
+      case "release":
+        text = `lease expired, released ${event.field} ${change}`;
+        break;
+      case "status":
+        text = `status ${change}`;
+        break;
+      case "wip":
+        text = `progress ${change}`;
+        break;
+      case "annotation":
+        text = `${event.action} ${event.key} revision ${change}`;
+        break;
+      case "review":
+        text = `reviewed ${event.key} score ${change}`;
+        break;
+    }
+    return (
+      <div>
+        <Tag color={event.type === "assign" ? "orange" : "default"}>{event.type}</Tag>
+        {actor} {text}
+        <div className="text-gray-400 text-xs">
+          {new Date(event.created_at).toLocaleString()}
+        </div>
+      </div>
+    );
+  };
+
   const columns: ColumnsType<Task> = [
     {
       title: "ID",
@@ -109,6 +320,30 @@ export default function AdminTasks() {
       width: 120,
       render: (status: TaskStatus) => getStatusTag(status),
     },
+    {
+      title: "Priority",
+      dataIndex: "priority",
+      key: "priority",
+      width: 100,
+      sorter: true,
+    },
+    {
+      title: "Due At",
+      dataIndex: "dueAt",
+      key: "dueAt",
+      width: 180,
+      sorter: true,
+      render: (date: string | undefined, record: Task) => {
+        if (!date) return "-";
+        const overdue =
+          new Date(date) < new Date() && record.status !== TaskStatus.approved;
+        return (
+          <span className={overdue ? "text-red-500" : undefined}>
+            {new Date(date).toLocaleString()}
+          </span>
+        );
+      },
+    },
     {
       title: "Created At",
       dataIndex: "created_at",
@@ -119,7 +354,7 @@ export default function AdminTasks() {
     {
       title: "Actions",
       key: "actions",
-      width: 150,
+      width: 220,
       render: (_, record: Task) => (
         <Space size="small">
           <Button
@@ -129,20 +364,152 @@ export default function AdminTasks() {
           >
             Assign
           </Button>
+          <Button
+            type="link"
+            size="small"
+            onClick={() => openPriority(record)}
+          >
+            Priority
+          </Button>
+          <Button
+            type="link"
+            size="small"
+            onClick={() => openTimeline(record)}
+          >
+            Timeline
+          </Button>
         </Space>
       ),
     },
   ];
 
+  const slaColumns: ColumnsType<PackageSLA> = [
+    { title: "Package", key: "package", render: (_, r) => r.packageName || r.packageId },
+    { title: "Tasks", dataIndex: "tasks", key: "tasks" },
+    { title: "Completed", dataIndex: "completed", key: "completed" },
+    { title: "On Time", dataIndex: "completedOnTime", key: "completedOnTime" },
+    { title: "Overdue", dataIndex: "overdue", key: "overdue" },
+    { title: "Pending", dataIndex: "pending", key: "pending" },
+    {
+      title: "Compliance",
+      dataIndex: "compliance",
+      key: "compliance",
+      render: (value: number) => `${(value * 100).toFixed(1)}%`,
+    },
+  ];
+
   return (
     <div className="p-4">
       <h1 className="text-2xl! text-green-900 mb-4">Task Management</h1>
-      <Table
-        columns={columns}
-        {...taskTableProps}
-        rowKey="id"
-        scroll={{ x: 800 }}
+      <Tabs
+        activeKey={view}
+        onChange={changeView}
+        items={[
+          { key: "all", label: "All Tasks" },
+          { key: "overdue", label: "Overdue" },
+          { key: "sla", label: "SLA" },
+        ]}
       />
+      {view === "sla" ? (
+        <Table
+          columns={slaColumns}
+          dataSource={slaList}
+          rowKey="packageId"
+          pagination={false}
+        />
+      ) : (
+        <>
+          <Space className="mb-4">
+            <span>{selectedIds.length} selected</span>
+            <Button disabled={!selectedIds.length} onClick={() => openAssignModal(null)}>
+              Assign
+            </Button>
+            <Button disabled={!selectedIds.length} onClick={handleBatchUnassign}>
+              Unassign
+            </Button>
+            <Select
+              placeholder="Set status"
+              disabled={!selectedIds.length}
+              style={{ width: 140 }}
+              value={null}
+              onChange={handleBatchStatus}
+              options={Object.values(TaskStatus).map((status) => ({
+                label: status,
+                value: status,
+              }))}
+            />
+            <Button disabled={!selectedIds.length} onClick={() => openPriority(null)}>
+              Set Priority
+            </Button>
+          </Space>
+          <Table
+            columns={columns}
+            {...taskTableProps}
+            rowKey="id"
+            scroll={{ x: 800 }}
+            rowSelection={{
+              selectedRowKeys: selectedIds,
+              onChange: (keys) => setSelectedIds(keys as number[]),
+            }}
+          />
+        </>
+      )}
+
+      {/* 设置优先级和截止时间 */}
+      <Modal
+        title={`Priority: ${priorityTask?.name ?? ""}`}
+        open={!!priorityTask}
+        onOk={handleSetPriority}
+        onCancel={() => setPriorityTask(null)}
+      >
+        <div className="space-y-4">
+          <div>
+            <label className="block mb-2">
+              {batchMode ? "Priority (higher first, empty to keep):" : "Priority (higher first):"}
+            </label>
+            <InputNumber
+              value={priority}
+              onChange={(value) => setPriority(value ?? (batchMode ? null : 0))}
+            />
+          </div>
+          <div>
+            <label className="block mb-2">
+              {batchMode ? "Due at (empty to keep):" : "Due at (empty to clear):"}
+            </label>
+            <Input
+              type="datetime-local"
+              value={dueAt}
+              disabled={batchMode && clearDueAt}
+              onChange={(e) => setDueAt(e.target.value)}
+            />
+            {batchMode && (
+              <Checkbox
+                className="mt-2"
+                checked={clearDueAt}
+                onChange={(e) => setClearDueAt(e.target.checked)}
+              >
+                Clear due date
+              </Checkbox>
+            )}
+          </div>
+        </div>
+      </Modal>
+
+      {/* 任务时间线 */}
+      <Modal
+        title={`Timeline: ${timelineTask?.name ?? ""}`}
+        open={!!timelineTask}
+        onCancel={() => setTimelineTask(null)}
+        footer={null}
+      >
+        <Timeline
+          className="mt-4"
+          items={timeline.map((event) => ({
+            color: event.actorRole === Role.ADMIN ? "red" : "blue",
+            children: renderEvent(event),
+          }))}
+        />
+      </Modal>
 
       {/* 分配任务模态框 */}
       <Modal
@@ -160,7 +527,7 @@ export default function AdminTasks() {
             </p>
             <p>
               <strong>Current Status:</strong>{" "}
-              {selectedTask && getStatusTag(selectedTask.status)}
+              {selectedTask?.status && getStatusTag(selectedTask.status)}
             </p>
           </div>
           <div>
@@ -4,7 +4,8 @@ import { User } from "@/lib/types";
 import { useUserStore } from "@/store/user_store";
 import { MoreOutlined } from "@ant-design/icons";
 import { useAntdTable } from "ahooks";
-import { Avatar, Dropdown } from "antd";
+import { Avatar, Dropdown, InputNumber, Modal, Select, Tag, message } from "antd";
+import { useState } from "react";
 import Table, { ColumnsType } from "antd/es/table";
 import { UpdateProfileModal, useUpdateProfileModal } from "../update_profile_modal";
 
@@ -16,6 +17,29 @@ export default function AdminUsers() {
     });
 
   const updateProfileProps = useUpdateProfileModal();
+  const [skillsUser, setSkillsUser] = useState<User | null>(null);
+  const [skillTags, setSkillTags] = useState<string[]>([]);
+  const [maxTasks, setMaxTasks] = useState(0);
+
+  // 打开技能设置
+  const openSkills = (user: User) => {
+    setSkillsUser(user);
+    setSkillTags(user.skillTags ?? []);
+    setMaxTasks(user.maxTasks ?? 0);
+  };
+
+  const handleSaveSkills = async () => {
+    if (!skillsUser) return;
+    try {
+      await api.user.updateUserSkills(skillsUser.id, { skillTags, maxTasks });
+      message.success("Skills updated");
+      setSkillsUser(null);
+      refreshUserTable();
+    } catch (error) {
+      // 错误信息已由 http 提示
+    }
+  };
+
   const columns: ColumnsType<User> = [
     {
       title: "ID",
@@ -37,6 +61,18 @@ export default function AdminUsers() {
       dataIndex: "role",
       key: "role",
     },
+    {
+      title: "Skills",
+      key: "skills",
+      render: (item: User) => (
+        <span>
+          {(item.skillTags ?? []).map((tag) => (
+            <Tag key={tag}>{tag}</Tag>
+          ))}
+          {item.maxTasks ? `max ${item.maxTasks}` : null}
+        </span>
+      ),
+    },
     {
       title: "Action",
       key: "action",
@@ -55,6 +91,11 @@ export default function AdminUsers() {
                     updateProfileProps.open(item).then(refreshUserTable);
                   },
                 },
+                {
+                  key: "skills",
+                  label: "Edit skills",
+                  onClick: () => openSkills(item),
+                },
               ],
             }}
             trigger={["click"]}
@@ -72,6 +113,33 @@ export default function AdminUsers() {
       </h1>
       <Table columns={columns} {...userTableProps} />
       <UpdateProfileModal {...updateProfileProps} />
+      <Modal
+        title={`Skills: ${skillsUser?.username ?? ""}`}
+        open={!!skillsUser}
+        onOk={handleSaveSkills}
+        onCancel={() => setSkillsUser(null)}
+      >
+        <div className="space-y-4">
+          <div>
+            <label className="block mb-2">Skill tags:</label>
+            <Select
+              mode="tags"
+              style={{ width: "100%" }}
+              value={skillTags}
+              onChange={setSkillTags}
+            />
+          </div>
+          <div>
+            <label className="block mb-2">Max concurrent tasks (0 = default):</label>
+            <InputNumber
+              min={0}
+              max={100}
+              value={maxTasks}
+              onChange={(value) => setMaxTasks(value ?? 0)}
+            />
+          </div>
+        </div>
+      </Modal>
     </div>
   );
 }
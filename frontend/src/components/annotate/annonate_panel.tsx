@@ -1,5 +1,4 @@
 import { api } from "@/lib/api";
-import { useRequest } from "ahooks";
 import { Button, message, Card, Tag, Rate, Checkbox } from "antd";
 import { useState, useEffect, useRef, useMemo, cache } from "react";
 import { LeftOutlined, RightOutlined, SaveOutlined } from "@ant-design/icons";
@@ -56,39 +55,28 @@ export const AnnonatePanel = ({
   const annotateImgRef = useRef<any>(null);
   const annotateVideoRef = useRef<any>(null);
 
-  const { data: bucketInfo } = useRequest(
-    async () => {
-      const res = await api.bucket.getBucket(bucketId);
-      return res;
-    },
-    {
-      ready: bucketId > 0,
-      refreshDeps: [bucketId],
-    }
-  );
-
   // 加载当前图片和标注数据
   useEffect(() => {
-    if (bucketInfo && list.length > 0 && currentIndex < list.length) {
+    if (taskId && list.length > 0 && currentIndex < list.length) {
       loadCurrentSource();
       loadSavedAnnotation();
     }
-  }, [bucketInfo, currentIndex, list, testImg]);
+  }, [taskId, currentIndex, list, testImg]);
 
   // 预加载下一张图片
   useEffect(() => {
-    if (bucketInfo && list.length > 0 && currentIndex < list.length - 1) {
+    if (taskId && list.length > 0 && currentIndex < list.length - 1) {
       preloadNextSource();
     }
-  }, [bucketInfo, currentIndex, list]);
+  }, [taskId, currentIndex, list]);
 
   const loadCurrentSource = async () => {
-    if (!bucketInfo || list.length === 0) return;
+    if (!taskId || list.length === 0) return;
 
     try {
       const currentKey = list[currentIndex];
       setCurrentIsImg(getFileType(currentKey) === "image");
-      const imageUrl = await getS3ObjUrl(currentKey);
+      const imageUrl = await getS3ObjUrl(currentIndex);
       setCurrentUrl(imageUrl);
     } catch (error) {
       console.error("Error loading s3 object url:", error);
@@ -127,29 +115,33 @@ export const AnnonatePanel = ({
   };
 
   const preloadNextSource = async () => {
-    if (!bucketInfo || currentIndex >= list.length - 1) return;
+    if (!taskId || currentIndex >= list.length - 1) return;
     try {
-      const nextKey = list[currentIndex + 1];
-      const imageUrl = await getS3ObjUrl(nextKey);
+      const imageUrl = await getS3ObjUrl(currentIndex + 1);
       setNextUrl(imageUrl);
     } catch (error) {
       console.error("预加载图片失败:", error);
     }
   };
 
-  const getS3ObjUrl = async (key: string): Promise<string> => {
+  // 由后端校验任务分配后生成限时地址，缓存到过期前一分钟
+  const getS3ObjUrl = async (idx: number): Promise<string> => {
+    const key = list[idx];
     const fileType = getFileType(key);
     if (testImg) {
       return fileType === "image"
         ? "https://gips1.baidu.com/it/u=1647344915,1746921568&fm=3028&app=3028&f=JPEG&fmt=auto?w=720&h=1280"
         : "https://vjs.zencdn.net/v/oceans.mp4";
     } else {
-      if (!bucketInfo) throw new Error("Bucket 信息未加载");
-      const cachedUrl = cacheTool.get(key);
-      if(cachedUrl) return cachedUrl;
-      const url = await api.bucket.getObjectUrl(bucketInfo, key);
-      cacheTool.set(key, url);
-      return url;
+      if (!taskId) throw new Error("任务信息未加载");
+      const cacheKey = `${taskId}:${idx}`;
+      const cached = cacheTool.get(cacheKey);
+      if (cached && new Date(cached.expiresAt).getTime() - Date.now() > 60000) {
+        return cached.url;
+      }
+      const res = await api.task.getItemUrl(taskId, idx);
+      cacheTool.set(cacheKey, res);
+      return res.url;
     }
   };
 
@@ -197,6 +189,7 @@ export const AnnonatePanel = ({
     const annotation: SavedAnnotation = {
       key: currentKey,
       taskId: taskId!,
+      version: curAnnotation?.key === currentKey ? curAnnotation.version : undefined,
       meta: currentIsImg
         ? annotateImgRef.current.getAnnotationMeta(bucketId)
         : annotateVideoRef.current.getAnnotationMeta(bucketId),
@@ -15,6 +15,7 @@ import { useUserStore } from "@/store/user_store";
 import {
   CheckOutlined,
   EditOutlined,
+  RedoOutlined,
 } from "@ant-design/icons";
 import { useNavigate } from "react-router";
 import { routr_annotate } from "@/lib/consts";
@@ -69,10 +70,33 @@ export default function AnnotatorTasks() {
     }
   };
 
+  // 领取队列中的下一个任务并直接开始
+  const handleNextTask = async () => {
+    try {
+      const task = await api.task.nextTask();
+      navigate(routr_annotate.replace(":id", task.id.toString()));
+    } catch (error) {
+      // 没有可领取的任务或已达到上限，错误信息已由 http 提示
+    }
+  };
+
   const handleProceedTask = async (task: Task) => {
     navigate(routr_annotate.replace(":id", task.id.toString()));
   };
 
+  // 被驳回的任务返工
+  const handleRework = async (task: Task) => {
+    try {
+      const res = await api.task.reworkTask({
+        task_id: task.id,
+      });
+      message.success(`${res.items.length} item(s) need rework`);
+      refreshMyTasks();
+    } catch (error) {
+      message.error("Failed to start rework");
+    }
+  };
+
   // 确认完成任务
   const handleConfirmComplete = async (record: Task) => {
     try {
@@ -200,6 +224,14 @@ export default function AnnotatorTasks() {
               danger
             />
           </Popconfirm>
+          {record.status === TaskStatus.rejected && (
+            <Button
+              icon={<RedoOutlined />}
+              className="ml-4"
+              size="small"
+              onClick={() => handleRework(record)}
+            />
+          )}
         </span>
       ),
     },
@@ -212,6 +244,11 @@ export default function AnnotatorTasks() {
       </h1>
       <Tabs
         defaultActiveKey="1"
+        tabBarExtraContent={
+          <Button type="primary" onClick={handleNextTask}>
+            Next Task
+          </Button>
+        }
         items={[
           {
             label: "My Tasks",
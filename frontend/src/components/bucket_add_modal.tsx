@@ -1,6 +1,6 @@
 import { api } from "@/lib/api";
 import { useRequest } from "ahooks";
-import { Form, Input, message, Modal } from "antd";
+import { Form, Input, message, Modal, Select } from "antd";
 import { useState } from "react";
 
 export function useBucketAddModal() {
@@ -45,6 +45,7 @@ export function BucketAddModal({
   close: () => void;
 }) {
   const [form] = Form.useForm();
+  const provider = Form.useWatch("provider", form) ?? "s3";
   const { loading, runAsync } = useRequest(
     async (values: any) => {
       try {
@@ -77,19 +78,49 @@ export function BucketAddModal({
       loading={loading}
       centered
     >
-      <Form form={form} onFinish={runAsync} layout="vertical">
+      <Form
+        form={form}
+        onFinish={runAsync}
+        layout="vertical"
+        initialValues={{ provider: "s3" }}
+      >
         <Form.Item label="Bucket Name" name="name">
           <Input />
         </Form.Item>
-        <Form.Item label="region" name="region">
-          <Input />
-        </Form.Item>
-        <Form.Item label="Access Key" name={["access", "key"]}>
-          <Input />
+        <Form.Item label="Provider" name="provider">
+          <Select
+            options={[
+              { label: "S3 / MinIO", value: "s3" },
+              { label: "Aliyun OSS", value: "oss" },
+              { label: "Local directory", value: "local" },
+            ]}
+          />
         </Form.Item>
-        <Form.Item label="Access Secret" name={["access", "secret"]}>
-          <Input />
+        {provider !== "local" && (
+          <Form.Item label="region" name="region">
+            <Input />
+          </Form.Item>
+        )}
+        <Form.Item
+          label={provider === "local" ? "Root directory" : "Endpoint (optional)"}
+          name="endpoint"
+        >
+          <Input
+            placeholder={
+              provider === "local" ? "/mnt/nfs/datasets" : "http://minio:9000"
+            }
+          />
         </Form.Item>
+        {provider !== "local" && (
+          <>
+            <Form.Item label="Access Key" name={["access", "key"]}>
+              <Input />
+            </Form.Item>
+            <Form.Item label="Access Secret" name={["access", "secret"]}>
+              <Input />
+            </Form.Item>
+          </>
+        )}
       </Form>
     </Modal>
   );
@@ -68,6 +68,16 @@ export default function ReviewerTasks() {
     }
   };
 
+  // 领取队列中的下一个任务并直接开始
+  const handleNextTask = async () => {
+    try {
+      const task = await api.task.nextTask();
+      navigate(router_review.replace(":id", task.id.toString()));
+    } catch (error) {
+      // 没有可领取的任务或已达到上限，错误信息已由 http 提示
+    }
+  };
+
   const navigate = useNavigate();
   const handleEdit = (task: Task) =>
     navigate(router_review.replace(":id", task.id.toString()));
@@ -238,6 +248,11 @@ export default function ReviewerTasks() {
       </h1>
       <Tabs
         defaultActiveKey="1"
+        tabBarExtraContent={
+          <Button type="primary" onClick={handleNextTask}>
+            Next Task
+          </Button>
+        }
         items={[
           {
             label: "My Tasks",
@@ -1,23 +1,20 @@
 import { message } from "antd";
 import { http } from "../http";
-import { getFileType } from "../util";
 import {
   Bucket,
   BucketAccess,
   BucketObjectListRes,
   BucketReq,
+  BucketSync,
   ListBucketRes,
+  ListObjectsFilter,
+  MissingObjectRes,
   ObjectInfo,
 } from "../types";
-import {
-  S3Client,
-  ListObjectsV2Command,
-  ListObjectsV2CommandInput,
-  HeadBucketCommand,
-  _Object,
-  GetObjectCommand,
-} from "@aws-sdk/client-s3";
-import { getSignedUrl } from "@aws-sdk/s3-request-presigner";
+
+// 后端返回的对象大小为字节数
+type BucketObjectRes = Omit<ObjectInfo, "size"> & { size: number };
+import { S3Client, HeadBucketCommand } from "@aws-sdk/client-s3";
 
 export type DirectoryNode = {
   key: string; // full prefix
@@ -35,11 +32,13 @@ export const bucket = {
   async addBucket(bucketReq: BucketReq) {
     // 使用后端验证bucket
     bucketReq.path_mode = true; // 默认使用path模式
-    // todo, 先验证bucket是否存在
-    const valid = await checkBucketAccess(bucketReq);
-    if (!valid) {
-      message.error("Bucket does not exist");
-      return;
+    // todo, 先验证bucket是否存在；OSS、本地存储及自定义 endpoint 由后端校验
+    if ((bucketReq.provider ?? "s3") === "s3" && !bucketReq.endpoint) {
+      const valid = await checkBucketAccess(bucketReq);
+      if (!valid) {
+        message.error("Bucket does not exist");
+        return;
+      }
     }
     return http<Bucket>("/bucket/add", {
       data: bucketReq,
@@ -56,92 +55,67 @@ export const bucket = {
       method: "DELETE",
     });
   },
+  // 由后端按目录列出子目录和一页文件，翻页时传入上一页的 nextContinuationToken
   async listObjects(
-    bucketInfo: Bucket,
+    bucketId: number,
     prefix?: string,
     pageSize: number = 100,
-    continuationToken?: string
+    continuationToken?: string,
+    filter?: ListObjectsFilter
   ): Promise<BucketObjectListRes> {
     try {
-      // 创建S3客户端
-      const s3 = getS3(bucketInfo.region, bucketInfo.access);
-
-      let allFilteredObjects: ObjectInfo[] = [];
-      let totalEstimated = 0;
-      let hasMore = false;
-      let nextContinuationToken: string | undefined = undefined;
-
-      const firstPageCommand = new ListObjectsV2Command({
-        Bucket: bucketInfo.name,
-        Prefix: prefix || "",
-        MaxKeys: pageSize,
-        Delimiter: "/",
-        ContinuationToken: continuationToken,
-      });
-      const response = await s3.send(firstPageCommand);
-
-      // UI层做了限制，这里不处理子目录
-
-      // 处理第一页的文件对象
-      if (response.Contents) {
-        for (const obj of response.Contents) {
-          if (!obj.Key) {
-            continue;
-          }
-
-          const key = obj.Key;
-
-          // 跳过目录本身（以/结尾的通常是目录）
-          if (key === (prefix || "") || key.endsWith("/")) {
-            continue;
-          }
-
-          const name = key.split("/").pop() || key;
-          // 检查文件类型
-          let fileType = getFileType(name);
-          if (fileType === "unknown") {
-            continue; // 跳过非图片/视频文件
-          }
-
-          allFilteredObjects.push({
-            key,
-            name,
-            type: fileType,
-            size: ((obj.Size || 0) / 1024 / 1024).toFixed(1) + "MB",
-            last_modified: obj.LastModified
-              ? obj.LastModified.toISOString()
-              : new Date().toISOString(),
-          });
+      const res = await http<BucketObjectListRes & { list: BucketObjectRes[] }>(
+        "/bucket/objects",
+        {
+          method: "GET",
+          params: {
+            bucket_id: bucketId,
+            prefix: prefix || "",
+            page_size: pageSize,
+            continuation_token: continuationToken,
+            ...filter,
+          },
         }
-      }
-
-      // 更新分页状态
-      hasMore = response.IsTruncated || false;
-      nextContinuationToken = response.NextContinuationToken;
-      totalEstimated = response.KeyCount || allFilteredObjects.length;
-
+      );
       return {
-        list: allFilteredObjects,
-        total: totalEstimated,
-        nextContinuationToken,
-        hasMore,
-      } as any;
+        ...res,
+        list: res.list.map((obj) => ({
+          ...obj,
+          size: (obj.size / 1024 / 1024).toFixed(1) + "MB",
+        })),
+      };
     } catch (error) {
-      console.error("Error listing S3 objects:", error);
+      console.error("Error listing bucket objects:", error);
       message.error("Failed to get file list");
       throw error;
     }
   },
 
-  // 新方法：获取目录树结构 - 从根目录开始获取真实目录
+  // 立即在后台同步对象索引
+  async resyncBucket(id: number) {
+    return http(`/bucket/${id}/sync`, {
+      method: "POST",
+    });
+  },
+  async getBucketSync(id: number) {
+    return http<BucketSync>(`/bucket/${id}/sync`, {
+      method: "GET",
+    });
+  },
+  // 已从存储中删除但仍被包引用的对象
+  async getMissingObjects(id: number) {
+    return http<MissingObjectRes>(`/bucket/${id}/missing`, {
+      method: "GET",
+    });
+  },
+
+  // 获取目录树结构 - 从根目录开始获取真实目录
   async listDirectoryTree(
-    bucketInfo: Bucket,
+    bucketId: number,
     basePrefix: string = "",
     depth: number = 10 // 增加深度以获取更多层级
   ): Promise<DirectoryNode[]> {
     try {
-      const s3 = getS3(bucketInfo.region, bucketInfo.access);
-
       const result: DirectoryNode[] = [];
 
       // 递归获取目录结构
@@ -152,67 +126,26 @@ export const bucket = {
       ) => {
         if (currentDepth > depth) return;
 
-        const params: ListObjectsV2CommandInput = {
-          Bucket: bucketInfo.name,
-          Prefix: currentPrefix,
-          Delimiter: "/",
-          MaxKeys: 100,
-        };
-
-        const command = new ListObjectsV2Command(params);
-        const response = await s3.send(command);
+        const response = await bucket.listObjects(bucketId, currentPrefix, 100);
 
         // 处理子目录
-        if (response.CommonPrefixes) {
-          for (const prefixObj of response.CommonPrefixes) {
-            if (prefixObj.Prefix) {
-              const dirPrefix = prefixObj.Prefix;
-              const dirName = dirPrefix
-                .replace(currentPrefix, "")
-                .replace("/", "");
-
-              if (dirName) {
-                // 检查这个目录是否有子目录
-                let hasChildren = false;
-                if (currentDepth < depth) {
-                  // 检查下一层是否有子目录
-                  const checkParams: ListObjectsV2CommandInput = {
-                    Bucket: bucketInfo.name,
-                    Prefix: dirPrefix,
-                    Delimiter: "/",
-                    MaxKeys: 100,
-                  };
-
-                  try {
-                    const checkCommand = new ListObjectsV2Command(checkParams);
-                    const checkResponse = await s3.send(checkCommand);
-                    hasChildren = !!(
-                      checkResponse.CommonPrefixes &&
-                      checkResponse.CommonPrefixes.length > 0
-                    );
-                  } catch (e) {
-                    console.error("Error checking subdirectories:", e);
-                  }
-                }
-                const node: DirectoryNode = {
-                  key: dirPrefix,
-                  title: dirName,
-                  isLeaf: !hasChildren,
-                };
-                currentResult.push(node);
-
-                // 递归探索子目录
-                if (hasChildren) {
-                  node.children ||= [];
-                  await exploreDirectory(
-                    dirPrefix,
-                    currentDepth + 1,
-                    node.children
-                  );
-                }
-              }
-            }
+        for (const dirPrefix of response.subDirectories ?? []) {
+          const dirName = dirPrefix.replace(currentPrefix, "").replace("/", "");
+          if (!dirName) continue;
+
+          // 检查这个目录是否有子目录
+          let hasChildren = false;
+          let children: DirectoryNode[] = [];
+          if (currentDepth < depth) {
+            await exploreDirectory(dirPrefix, currentDepth + 1, children);
+            hasChildren = children.length > 0;
           }
+          currentResult.push({
+            key: dirPrefix,
+            title: dirName,
+            isLeaf: !hasChildren,
+            children: hasChildren ? children : undefined,
+          });
         }
       };
 
@@ -223,27 +156,6 @@ export const bucket = {
       return [];
     }
   },
-
-  // 获取 S3 对象的 URL（用于图片加载）
-  async getObjectUrl(bucketInfo: Bucket, key: string): Promise<string> {
-    try {
-      const s3 = getS3(bucketInfo.region, bucketInfo.access);
-
-      // 由于 bucket 不是 public，需要生成预签名 URL
-      const command = new GetObjectCommand({
-        Bucket: bucketInfo.name,
-        Key: key,
-      });
-
-      // 生成1小时有效的预签名 URL
-      const signedUrl = await getSignedUrl(s3, command, { expiresIn: 3600 });
-      return signedUrl;
-    } catch (error) {
-      console.error("Error getting S3 object URL:", error);
-      message.error("Failed to get image URL");
-      throw error;
-    }
-  },
 };
 
 function getS3(region: string, access: BucketAccess) {
@@ -265,6 +177,7 @@ function getS3(region: string, access: BucketAccess) {
 }
 
 async function checkBucketAccess(bucket: BucketReq) {
+  if (!bucket.access) return false;
   const s3 = getS3(bucket.region, bucket.access);
   const command = new HeadBucketCommand({
     Bucket: bucket.name,
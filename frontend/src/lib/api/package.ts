@@ -5,8 +5,9 @@ export const packages = {
   savePackage(req: PackageReq) {
     return http<Package>("/package", { data: req, method: "POST" });
   },
-  publishPackage(package_id: number) {
-    return http<Package>(`/package/publish/${package_id}`, { method: "POST" });
+  // chunkSize / taskCount 可选，用于将包拆分为多个任务
+  publishPackage(package_id: number, data?: { chunkSize?: number; taskCount?: number }) {
+    return http<Package>(`/package/publish/${package_id}`, { method: "POST", data });
   },
   getPackageList(page: number, page_size: number) {
     return http<PackageListResponse>("/package/list", {
@@ -10,13 +10,20 @@ import {
   Task,
   TaskWipUpdateRequest,
   SavedAnnotation,
-  ReviewAnnotationReq
+  ReviewAnnotationReq,
+  TaskReworkResponse,
+  TaskTimelineResponse,
+  TaskPriorityRequest,
+  PackageSLA,
+  TaskBatchRequest,
+  TaskBatchResponse,
+  TaskItemUrl
 } from "../types"
 
 export const task = {
   /**
    * 获取任务列表
-   * @param params 查询参数：user_id, status, page, page_size
+   * @param params 查询参数：user_id, status, package_id, min_priority, overdue, sort_by, order, page, page_size
    */
   getTaskList(params: TaskListRequest) {
     return http<TaskListResponse>('/task/list', {
@@ -35,6 +42,27 @@ export const task = {
     })
   },
 
+  /**
+   * 获取任务事件时间线
+   * @param taskId 任务ID
+   */
+  getTaskTimeline(taskId: number) {
+    return http<TaskTimelineResponse>(`/task/${taskId}/timeline`, {
+      method: 'GET'
+    })
+  },
+
+  /**
+   * 获取任务条目的限时下载地址
+   * @param taskId 任务ID
+   * @param idx 条目在任务详情 items 中的下标
+   */
+  getItemUrl(taskId: number, idx: number) {
+    return http<TaskItemUrl>(`/task/${taskId}/items/${idx}/url`, {
+      method: 'GET'
+    })
+  },
+
   /**
    * 领取任务
    * @param data 任务领取请求
@@ -46,6 +74,59 @@ export const task = {
     })
   },
 
+  /**
+   * 设置任务的优先级和截止时间（管理员）
+   * @param data 任务优先级请求
+   */
+  setTaskPriority(data: TaskPriorityRequest) {
+    return http<Task>('/task/priority', {
+      method: 'PUT',
+      data
+    })
+  },
+
+  /**
+   * 批量分配、取消分配、修改状态或优先级（管理员），任一任务失败时整批回滚
+   * @param data 批量操作请求
+   */
+  batchUpdateTasks(data: TaskBatchRequest) {
+    return http<TaskBatchResponse>('/task/batch', {
+      method: 'POST',
+      data
+    })
+  },
+
+  /**
+   * 各包的 SLA 达成率（管理员）
+   * @param packageId 不指定时统计所有设置了截止时间的包
+   */
+  getPackageSLA(packageId?: number) {
+    return http<{ list: PackageSLA[] }>('/package/sla', {
+      method: 'GET',
+      params: { package_id: packageId }
+    })
+  },
+
+  /**
+   * 领取队列中优先级最高的可领取任务，没有可领取的任务时返回错误
+   */
+  nextTask() {
+    return http<Task>('/task/next', {
+      method: 'POST'
+    })
+  },
+
+  /**
+   * 被驳回的任务返工，回到 processing 状态
+   * @param data 任务ID
+   */
+  reworkTask(data: TaskClaimRequest) {
+    return http<TaskReworkResponse>('/task/rework', {
+      method: 'POST',
+      data
+    })
+  },
+
   /**
    * 更新任务状态
    * @param data 任务状态更新请求
@@ -79,9 +160,11 @@ export const task = {
   },
 
   saveAnnotation(data: SavedAnnotation){
+    // 携带读取时的版本号，服务端版本不一致时返回 409
     return http<SavedAnnotation>('/task/annotation', {
       method: 'POST',
-      data
+      data,
+      headers: data.version ? { 'If-Match': `"${data.version}"` } : undefined
     })
   },
   getAnnotation(task_id: number, key: string){
@@ -1,7 +1,7 @@
 import { http } from "../http";
 import md5 from "md5";
 
-import { CommonRes, User, UserCreateReq, UserListRes, UserLoginRes, UserUpdateReq } from "../types";
+import { CommonRes, User, UserCreateReq, UserListRes, UserLoginRes, UserSkillsReq, UserUpdateReq } from "../types";
 
 export const user = {
   sendVerifyCode(email: string, for_register = false): Promise<CommonRes> {
@@ -63,5 +63,13 @@ export const user = {
       method: "GET",
       params: { page, page_size },
     });
-  }
+  },
+
+  // 管理员设置用户的技能标签和同时进行的任务上限
+  updateUserSkills(userId: number, data: UserSkillsReq) {
+    return http<User>(`/user/${userId}/skills`, {
+      method: "PUT",
+      data,
+    });
+  },
 };
@@ -37,9 +37,8 @@ export const http = async <T>(
         : JSON.stringify(options.data)
       : undefined,
     headers: {
-      ...(options.headers || {
-        Authorization: `Bearer ${token}`,
-      }),
+      Authorization: `Bearer ${token}`,
+      ...(options.headers || {}),
       ...(isFormData ? {} : { "Content-Type": "application/json" }),
     },
   });
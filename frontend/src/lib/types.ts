@@ -10,23 +10,32 @@ export type UserCreateReq = {
   role: Role;
   password: string;
 };
-export type User = Omit<UserCreateReq,"password"> & { id: number, avatar?: string };
+export type User = Omit<UserCreateReq,"password"> & {
+  id: number,
+  avatar?: string,
+  skillTags?: string[], // 技能标签，只能领取所需标签都具备的任务
+  maxTasks?: number, // 同时进行的任务上限，0 表示使用默认值
+};
+export type UserSkillsReq = { skillTags: string[], maxTasks: number };
 export type UserUpdateReq = User;
 export type UserLoginRes = {user: User, token: string };
 export type BucketAccess = {
   key: string;
   secret: string;
 };
+export type BucketProvider = "s3" | "oss" | "local";
 export type BucketReq = {
   id: number;
   name: string;
+  provider?: BucketProvider; // 默认为 s3
   region: string;
+  endpoint?: string; // S3 兼容服务或 OSS 的访问地址，本地存储为根目录
   path_mode: boolean;
-  access: BucketAccess;
+  access?: BucketAccess; // 只在创建、更新时提交，本地存储和更新时不填写沿用原有凭证
 };
 
 export type ListBucketRes = {
-  list: Omit<Bucket, 'access'>[];
+  list: Bucket[];
   total: number;
 }
 export enum PackageStatus {
@@ -41,6 +50,7 @@ export type Package = {
   name: string;
   items: string[];// list of s3 object keys
   status: PackageStatus;
+  skillTags?: string[]; // 领取任务需要具备的技能标签
 };
 
 export enum TaskStatus {
@@ -59,16 +69,64 @@ export type Task = {
   reviewer: number;  // 审核员 user id
   status: TaskStatus;
   wipIdx: number; // 当前标注到的 item 索引
+  reviewRound?: number; // 已完成的审核轮数
+  priority?: number; // 数值越大越先被领取
+  dueAt?: string; // 截止时间
+  completedAt?: string; // 通过审核的时间
+  leaseExpiresAt?: string; // 领取租约到期时间，期间无操作会被自动释放
   created_at: string;
 };
 
+export type ReworkItem = {
+  key: string;
+  review?: SavedAnnotation["review"];
+};
+
 export type TaskDetail = Task & {
   items: string[];
+  reworkItems?: ReworkItem[]; // 返工时需要重新标注的条目
+};
+
+// 任务条目的限时下载地址
+export type TaskItemUrl = {
+  key: string;
+  url: string;
+  expiresAt: string;
+};
+
+export type TaskEvent = {
+  id: number;
+  taskId: number;
+  type: "claim" | "assign" | "status" | "wip" | "annotation" | "review";
+  action?: string;
+  actorId: number;
+  actorRole: string;
+  actorName: string;
+  key?: string;
+  field: string;
+  oldValue: string;
+  newValue: string;
+  created_at: string;
+};
+
+export type TaskTimelineResponse = {
+  list: TaskEvent[];
+  total: number;
+};
+
+export type TaskReworkResponse = {
+  task: Task;
+  items: ReworkItem[];
 };
 
 export type TaskListRequest = {
   user_id?: number;
   status?: TaskStatus;
+  package_id?: number;
+  min_priority?: number;
+  overdue?: boolean; // 只返回已过截止时间且未完成的任务
+  sort_by?: "id" | "priority" | "due_at" | "created_at";
+  order?: "asc" | "desc";
   page: number;
   page_size: number;
 };
@@ -95,7 +153,48 @@ export type TaskAssignRequest = {
   task_id: number;
   user_id: number;
 };
-export type Bucket = BucketReq & { id: number };
+export type TaskPriorityRequest = {
+  task_id: number;
+  priority: number;
+  dueAt?: string | null; // 为空时清除截止时间
+};
+export type TaskBatchRequest = {
+  action: "assign" | "unassign" | "status" | "priority";
+  task_ids?: number[];
+  filter?: { packageId?: number; status?: TaskStatus; assignee?: number }; // 与 task_ids 同时指定时取交集
+  user_id?: number;
+  status?: TaskStatus;
+  priority?: number | null; // 为空时保留任务原有的优先级
+  dueAt?: string | null; // 为空时保留任务原有的截止时间
+  clearDueAt?: boolean; // 清除截止时间，不能与 dueAt 同时指定
+};
+export type TaskBatchResult = {
+  taskId: number;
+  success: boolean;
+  error?: string;
+  task?: Task;
+};
+// 任一任务失败时整批回滚，committed 为 false
+export type TaskBatchResponse = {
+  committed: boolean;
+  results: TaskBatchResult[];
+};
+export type PackageSLA = {
+  packageId: number;
+  packageName: string;
+  tasks: number;
+  completed: number;
+  completedOnTime: number;
+  overdue: number;
+  pending: number;
+  compliance: number; // 按时完成数 / (已完成数 + 逾期未完成数)
+};
+// 详情不返回凭证，只返回 access key 的掩码
+export type Bucket = Omit<BucketReq, "access"> & {
+  id: number;
+  accessKeyHint?: string;
+  keyVersion?: number; // 主密钥版本，0 表示凭证未加密
+};
 
 export type FileUploadRes = {
   url: string;
@@ -143,6 +242,8 @@ export type ObjectInfo = {
   type: string; // "image" or "video"
   size: string;
   last_modified: string;
+  width?: number; // 仅索引中的图片有宽高
+  height?: number;
 }
 
 export type BucketObjectListRes = {
@@ -151,6 +252,40 @@ export type BucketObjectListRes = {
   nextContinuationToken?: string;
   hasMore: boolean;
   subDirectories?: string[];
+  indexed: boolean; // 来自对象索引时支持搜索、筛选和排序
+}
+
+// 搜索、筛选和排序需要存储桶已完成同步
+export type ListObjectsFilter = {
+  search?: string;
+  media_type?: "image" | "video";
+  sort_by?: "key" | "name" | "size" | "last_modified";
+  order?: "asc" | "desc";
+}
+
+export type BucketSync = {
+  id: number;
+  bucketId: number;
+  status: "idle" | "running" | "failed";
+  scanStartedAt?: string; // 未完成的扫描开始时间
+  lastSyncedAt?: string;
+  objects: number;
+  added: number;
+  updated: number;
+  removed: number;
+  error?: string;
+}
+
+export type MissingObject = {
+  packageId: number;
+  packageName: string;
+  key: string;
+  deletedAt?: string;
+}
+
+export type MissingObjectRes = {
+  list: MissingObject[];
+  total: number;
 }
 
 
@@ -167,6 +302,8 @@ export type SavedAnnotation = {
   id?:number;
   taskId: number;
   key: string;
+  version?: number;
+  needsRework?: boolean;
   meta: {
     bucketId: number;
     marks: (MarkData| VideoMarkData)[];
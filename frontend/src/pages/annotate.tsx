@@ -41,7 +41,7 @@ export default function Annotate() {
       ) : (
         <AnnonatePanel
           bucketId={pageData!.package.bucketId}
-          list={pageData!.package.items}
+          list={pageData!.task.items}
           current={pageData?.task.wipIdx}
           taskId={pageData?.task.id}
           onCompleteAnnotate={onCompleteTask}
@@ -38,7 +38,7 @@ export default function Review() {
         ) : (
           <AnnonatePanel
             bucketId={pageData!.package.bucketId}
-            list={pageData!.package.items}
+            list={pageData!.task.items}
             current={pageData?.task.wipIdx}
             taskId={pageData?.task.id}
             viewMode